@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formats of the client-config subcommand.
+const (
+	clientFormatClaude  = "claude"
+	clientFormatVSCode  = "vscode"
+	clientFormatGeneric = "generic"
+
+	clientConfigCommand = "client-config"
+)
+
+// ClientConfigCmd prints the snippet registering this server in an MCP client.
+type ClientConfigCmd struct {
+	Format  string            `arg:"--format" default:"generic" help:"Client to generate the configuration for (claude|vscode|generic)"`
+	Headers map[string]string `arg:"--header,separate" help:"HTTP header the client sends, as Name=value, e.g. for a proxy in front of the server (repeatable, http only)"`
+}
+
+// clientServer describes how a client reaches the server: either the command
+// line it starts for stdio, or the URL it connects to for HTTP.
+type clientServer struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// printClientConfig writes the client configuration for the server configured
+// by cfg. args are the command line arguments the server was invoked with,
+// which a stdio client passes on when starting it.
+func printClientConfig(w io.Writer, cfg *Config, args []string) error {
+	var server clientServer
+	switch cfg.TransportType {
+	case transportStdio:
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to determine executable: %w", err)
+		}
+		server = clientServer{Command: executable, Args: serverArgs(args), Env: mcpEnv()}
+	case transportHTTP:
+		server = clientServer{URL: clientURL(cfg), Headers: cfg.ClientConfig.Headers}
+	default:
+		return fmt.Errorf("MCP clients cannot connect to the %s transport (must be '%s' or '%s')", cfg.TransportType, transportStdio, transportHTTP)
+	}
+
+	name := clientServerName(cfg.ServerName)
+	transportType := cfg.TransportType
+
+	var snippet any
+	switch cfg.ClientConfig.Format {
+	case clientFormatClaude:
+		entry := map[string]any{"command": server.Command, "args": server.Args, "env": server.Env}
+		if transportType == transportHTTP {
+			entry = map[string]any{"type": transportType, "url": server.URL, "headers": server.Headers}
+		}
+		snippet = map[string]any{"mcpServers": map[string]any{name: omitEmpty(entry)}}
+	case clientFormatVSCode:
+		entry := map[string]any{"type": transportType, "command": server.Command, "args": server.Args, "env": server.Env}
+		if transportType == transportHTTP {
+			entry = map[string]any{"type": transportType, "url": server.URL, "headers": server.Headers}
+		}
+		snippet = map[string]any{"servers": map[string]any{name: omitEmpty(entry)}}
+	case clientFormatGeneric:
+		snippet = struct {
+			Name      string `json:"name"`
+			Transport string `json:"transport"`
+			clientServer
+		}{name, transportType, server}
+	default:
+		return fmt.Errorf("invalid client config format: %s (must be '%s', '%s' or '%s')", cfg.ClientConfig.Format, clientFormatClaude, clientFormatVSCode, clientFormatGeneric)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snippet)
+}
+
+// omitEmpty drops the entries without a value, since clients reject e.g. a null env.
+func omitEmpty(entry map[string]any) map[string]any {
+	for key, value := range entry {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				delete(entry, key)
+			}
+		case []string:
+			if len(v) == 0 {
+				delete(entry, key)
+			}
+		case map[string]string:
+			if len(v) == 0 {
+				delete(entry, key)
+			}
+		}
+	}
+	return entry
+}
+
+// serverArgs returns the server's command line arguments without the
+// client-config subcommand and its options.
+func serverArgs(args []string) []string {
+	var filtered []string
+	subcommand := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case !subcommand && arg == clientConfigCommand:
+			subcommand = true
+		case subcommand && (arg == "--format" || arg == "--header"):
+			i++ // skip the value
+		case subcommand && (strings.HasPrefix(arg, "--format=") || strings.HasPrefix(arg, "--header=")):
+		default:
+			filtered = append(filtered, arg)
+		}
+	}
+	return filtered
+}
+
+// mcpEnv returns the MCP_* environment variables configuring the server, so
+// a client starting it applies the same configuration.
+func mcpEnv() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, "MCP_") {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// clientURL returns the URL of the MCP endpoint under the base path: the
+// first ACME domain over HTTPS, otherwise the first listen address or the
+// bind address, with localhost standing in for all interfaces.
+func clientURL(cfg *Config) string {
+	scheme, host, port := "http", "localhost", strconv.Itoa(cfg.HTTPPort)
+	bind := cfg.BindAddr
+	if len(cfg.Listen) > 0 {
+		if h, p, err := net.SplitHostPort(cfg.Listen[0]); err == nil {
+			bind, port = h, p
+		}
+	}
+	if ip := net.ParseIP(bind); bind != "" && (ip == nil || !ip.IsUnspecified()) {
+		host = bind
+	}
+	if len(cfg.ACMEDomains) > 0 {
+		scheme, host = "https", cfg.ACMEDomains[0]
+	}
+
+	basePath := strings.TrimSuffix(cfg.BasePath, "/")
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return fmt.Sprintf("%s://%s%s/mcp", scheme, host, basePath)
+	}
+	return fmt.Sprintf("%s://%s%s/mcp", scheme, net.JoinHostPort(host, port), basePath)
+}
+
+// clientServerName turns the server name into a key for client
+// configurations, e.g. "MCP Server" into "mcp-server".
+func clientServerName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "mcp-server"
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintClientConfig(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	command, _ := json.Marshal(executable)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "claude stdio",
+			args: []string{"--name", "Tea Server", "client-config", "--format", "claude"},
+			want: `{"mcpServers":{"tea-server":{"command":$COMMAND,"args":["--name","Tea Server"]}}}`,
+		},
+		{
+			name: "claude stdio with token",
+			args: []string{"--ordered-responses", "client-config", "--format=claude"},
+			env:  map[string]string{"MCP_ADMIN_TOKEN": "secret-admin-token"},
+			want: `{"mcpServers":{"mcp-server":{"command":$COMMAND,"args":["--ordered-responses"],"env":{"MCP_ADMIN_TOKEN":"secret-admin-token"}}}}`,
+		},
+		{
+			name: "claude http",
+			args: []string{"--transport", "http", "--port", "3000", "client-config", "--format", "claude"},
+			want: `{"mcpServers":{"mcp-server":{"type":"http","url":"http://127.0.0.1:3000/mcp"}}}`,
+		},
+		{
+			name: "claude http with headers",
+			args: []string{"--transport", "http", "client-config", "--format", "claude", "--header", "Authorization=Bearer secret", "--header=X-Api-Key=key"},
+			want: `{"mcpServers":{"mcp-server":{"type":"http","url":"http://127.0.0.1:8080/mcp","headers":{"Authorization":"Bearer secret","X-Api-Key":"key"}}}}`,
+		},
+		{
+			name: "vscode stdio",
+			args: []string{"client-config", "--format", "vscode"},
+			env:  map[string]string{"MCP_LOG_LEVEL": "debug"},
+			want: `{"servers":{"mcp-server":{"type":"stdio","command":$COMMAND,"env":{"MCP_LOG_LEVEL":"debug"}}}}`,
+		},
+		{
+			name: "vscode http with headers",
+			args: []string{"--transport", "http", "--bind", "0.0.0.0", "--base-path", "/api/ai/", "client-config", "--format", "vscode", "--header", "Authorization=Bearer secret"},
+			want: `{"servers":{"mcp-server":{"type":"http","url":"http://localhost:8080/api/ai/mcp","headers":{"Authorization":"Bearer secret"}}}}`,
+		},
+		{
+			name: "generic stdio",
+			args: []string{"--request-timeout", "10s", "client-config"},
+			want: `{"name":"mcp-server","transport":"stdio","command":$COMMAND,"args":["--request-timeout","10s"]}`,
+		},
+		{
+			name: "generic http with headers",
+			args: []string{"--transport", "http", "--listen", "[::1]:9000", "client-config", "--format", "generic", "--header", "Authorization=Bearer secret"},
+			want: `{"name":"mcp-server","transport":"http","url":"http://[::1]:9000/mcp","headers":{"Authorization":"Bearer secret"}}`,
+		},
+		{
+			name: "generic https",
+			args: []string{"--transport", "http", "--port", "443", "--acme-domain", "mcp.example.com", "client-config"},
+			want: `{"name":"mcp-server","transport":"http","url":"https://mcp.example.com/mcp"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := printClientConfig(&out, cfg, tt.args); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode %s: %v", out.String(), err)
+			}
+			if err := json.Unmarshal([]byte(strings.ReplaceAll(tt.want, "$COMMAND", string(command))), &want); err != nil {
+				t.Fatalf("Failed to decode the expected config: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %s, got %s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestPrintClientConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown format", []string{"client-config", "--format", "cursor"}},
+		{"tcp transport", []string{"--transport", "tcp", "client-config"}},
+		{"grpc transport", []string{"--transport", "grpc", "client-config"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := printClientConfig(&bytes.Buffer{}, cfg, tt.args); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestClientServerName(t *testing.T) {
+	tests := map[string]string{
+		"MCP Server":       "mcp-server",
+		"  Tea & Coffee! ": "tea-coffee",
+		"Grüner Tee 2":     "grüner-tee-2",
+		"---":              "mcp-server",
+	}
+	for name, want := range tests {
+		if got := clientServerName(name); got != want {
+			t.Errorf("clientServerName(%q): expected %q, got %q", name, want, got)
+		}
+	}
+}
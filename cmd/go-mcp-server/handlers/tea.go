@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/cbrgm/go-mcp-server/mcp"
 )
@@ -24,6 +26,10 @@ const (
 	toolGetTeasByType = "getTeasByType"
 )
 
+// TeaInstructions is the usage guidance the tea server sends on initialize.
+const TeaInstructions = "Use getTeaInfo directly when the tea is known, it is faster than listing all teas with getTeaNames first. " +
+	"Use getTeasByType to narrow down by type, and the tea_recommendation prompt when the user is unsure what to drink."
+
 type TeaHandler struct{}
 
 type Tea struct {
@@ -133,14 +139,27 @@ func (h *TeaHandler) ListTools(ctx context.Context) ([]mcp.Tool, error) {
 	return []mcp.Tool{
 		{
 			Name:        toolGetTeaNames,
+			Title:       "Get Tea Names",
 			Description: "Get a list of all available tea names in our collection",
 			InputSchema: mcp.InputSchema{
 				Type:       "object",
 				Properties: map[string]interface{}{},
 			},
+			OutputSchema: &mcp.OutputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"names": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The names of all teas in the collection",
+					},
+				},
+				Required: []string{"names"},
+			},
 		},
 		{
 			Name:        toolGetTeaInfo,
+			Title:       "Get Tea Info",
 			Description: "Get detailed information about a specific tea including brewing instructions",
 			InputSchema: mcp.InputSchema{
 				Type: "object",
@@ -155,6 +174,7 @@ func (h *TeaHandler) ListTools(ctx context.Context) ([]mcp.Tool, error) {
 		},
 		{
 			Name:        toolGetTeasByType,
+			Title:       "Get Teas by Type",
 			Description: "Get all teas of a specific type (Green Tea, Black Tea, Oolong Tea, White Tea)",
 			InputSchema: mcp.InputSchema{
 				Type: "object",
@@ -190,17 +210,18 @@ func (h *TeaHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (m
 					Text: string(result),
 				},
 			},
+			StructuredContent: map[string]any{"names": names},
 		}, nil
 
 	case "getTeaInfo":
 		nameInterface, ok := params.Arguments["name"]
 		if !ok {
-			return mcp.ToolResponse{}, fmt.Errorf("name parameter is required")
+			return mcp.ToolResponse{}, fmt.Errorf("%w: name parameter is required", mcp.ErrInvalidParams)
 		}
 
 		name, ok := nameInterface.(string)
 		if !ok {
-			return mcp.ToolResponse{}, fmt.Errorf("name parameter must be a string")
+			return mcp.ToolResponse{}, fmt.Errorf("%w: name parameter must be a string", mcp.ErrInvalidParams)
 		}
 
 		tea, exists := teaMenu[name]
@@ -232,12 +253,12 @@ func (h *TeaHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (m
 	case "getTeasByType":
 		typeInterface, ok := params.Arguments["type"]
 		if !ok {
-			return mcp.ToolResponse{}, fmt.Errorf("type parameter is required")
+			return mcp.ToolResponse{}, fmt.Errorf("%w: type parameter is required", mcp.ErrInvalidParams)
 		}
 
 		teaType, ok := typeInterface.(string)
 		if !ok {
-			return mcp.ToolResponse{}, fmt.Errorf("type parameter must be a string")
+			return mcp.ToolResponse{}, fmt.Errorf("%w: type parameter must be a string", mcp.ErrInvalidParams)
 		}
 
 		var matchingTeas []Tea
@@ -273,15 +294,23 @@ func (h *TeaHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (m
 		}, nil
 
 	default:
-		return mcp.ToolResponse{}, fmt.Errorf("unknown tool: %s", params.Name)
+		return mcp.ToolResponse{}, fmt.Errorf("%w: %s", mcp.ErrToolNotFound, params.Name)
 	}
 }
 
 func (h *TeaHandler) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	menuData, err := json.MarshalIndent(teaMenu, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tea menu: %w", err)
+	}
+
 	return []mcp.Resource{
 		{
-			URI:  "menu://tea",
-			Name: "Tea Menu",
+			URI:         "menu://tea",
+			Name:        "Tea Menu",
+			Description: "The complete tea collection with prices, origins and brewing details",
+			MimeType:    "application/json",
+			Size:        int64(len(menuData)),
 		},
 	}, nil
 }
@@ -296,14 +325,11 @@ func (h *TeaHandler) ReadResource(ctx context.Context, params mcp.ResourceParams
 
 		return mcp.ResourceResponse{
 			Contents: []mcp.ResourceContent{
-				{
-					URI:  params.URI,
-					Text: string(menuData),
-				},
+				mcp.NewTextResourceContent(params.URI, "application/json", string(menuData)),
 			},
 		}, nil
 	default:
-		return mcp.ResourceResponse{}, fmt.Errorf("unknown resource URI: %s", params.URI)
+		return mcp.ResourceResponse{}, mcp.NewResourceNotFoundError(params.URI)
 	}
 }
 
@@ -315,6 +341,7 @@ func (h *TeaHandler) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
 	return []mcp.Prompt{
 		{
 			Name:        "tea_recommendation",
+			Title:       "Tea Recommendation",
 			Description: "Get personalized tea recommendations based on preferences",
 			Arguments: []mcp.PromptArgument{
 				{
@@ -336,6 +363,7 @@ func (h *TeaHandler) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
 		},
 		{
 			Name:        "brewing_guide",
+			Title:       "Brewing Guide",
 			Description: "Get detailed brewing instructions for a specific tea",
 			Arguments: []mcp.PromptArgument{
 				{
@@ -347,6 +375,7 @@ func (h *TeaHandler) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
 		},
 		{
 			Name:        "tea_pairing",
+			Title:       "Tea Pairing",
 			Description: "Get food pairing suggestions for a specific tea",
 			Arguments: []mcp.PromptArgument{
 				{
@@ -370,7 +399,7 @@ func (h *TeaHandler) GetPrompt(ctx context.Context, params mcp.PromptParams) (mc
 	case "tea_pairing":
 		return h.generateTeaPairing(arguments)
 	default:
-		return mcp.PromptResponse{}, fmt.Errorf("unknown prompt: %s", params.Name)
+		return mcp.PromptResponse{}, fmt.Errorf("%w: %s", mcp.ErrPromptNotFound, params.Name)
 	}
 }
 
@@ -452,7 +481,7 @@ func (h *TeaHandler) getFlavorRecommendations(flavorProfile string) string {
 func (h *TeaHandler) generateBrewingGuide(arguments map[string]string) (mcp.PromptResponse, error) {
 	teaName := arguments["tea_name"]
 	if teaName == "" {
-		return mcp.PromptResponse{}, fmt.Errorf("tea_name is required for brewing guide")
+		return mcp.PromptResponse{}, fmt.Errorf("%w: tea_name is required for brewing guide", mcp.ErrInvalidParams)
 	}
 
 	tea, exists := teaMenu[teaName]
@@ -486,7 +515,7 @@ Enjoy your perfectly brewed %s!`,
 func (h *TeaHandler) generateTeaPairing(arguments map[string]string) (mcp.PromptResponse, error) {
 	teaName := arguments["tea_name"]
 	if teaName == "" {
-		return mcp.PromptResponse{}, fmt.Errorf("tea_name is required for pairing suggestions")
+		return mcp.PromptResponse{}, fmt.Errorf("%w: tea_name is required for pairing suggestions", mcp.ErrInvalidParams)
 	}
 
 	tea, exists := teaMenu[teaName]
@@ -544,3 +573,43 @@ func (h *TeaHandler) createPromptResponse(text string) mcp.PromptResponse {
 		},
 	}
 }
+
+func (h *TeaHandler) Complete(ctx context.Context, params mcp.CompleteParams) (mcp.CompleteResponse, error) {
+	if params.Ref.Type != mcp.CompletionRefPrompt {
+		return mcp.CompleteResponse{}, fmt.Errorf("%w: %s", mcp.ErrResourceNotFound, params.Ref.URI)
+	}
+
+	var candidates []string
+	switch params.Ref.Name {
+	case "tea_recommendation":
+		switch params.Argument.Name {
+		case "mood":
+			candidates = []string{"energizing", "relaxing", "focus"}
+		case "caffeine_preference":
+			candidates = []string{"high", "medium", "low", "none"}
+		case "flavor_profile":
+			candidates = []string{"floral", "robust", "delicate", "complex"}
+		}
+	case "brewing_guide", "tea_pairing":
+		if params.Argument.Name == "tea_name" {
+			for name := range teaMenu {
+				candidates = append(candidates, name)
+			}
+			sort.Strings(candidates)
+		}
+	default:
+		return mcp.CompleteResponse{}, fmt.Errorf("%w: %s", mcp.ErrPromptNotFound, params.Ref.Name)
+	}
+
+	prefix := strings.ToLower(params.Argument.Value)
+	values := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			values = append(values, candidate)
+		}
+	}
+
+	return mcp.CompleteResponse{
+		Completion: mcp.Completion{Values: values, Total: len(values)},
+	}, nil
+}
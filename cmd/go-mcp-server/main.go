@@ -1,10 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -18,23 +24,110 @@ import (
 const (
 	transportStdio = "stdio"
 	transportHTTP  = "http"
+	transportTCP   = "tcp"
+	transportPipe  = "pipe"
+	transportGRPC  = "grpc"
+
+	sessionLimitReject    = "reject"
+	sessionLimitEvictIdle = "evict-idle"
 
 	minPort = 1
 	maxPort = 65535
 )
 
+// Exit codes, so supervisors can tell failures worth a restart from ones that need a fix.
+const (
+	exitFailure    = 1 // unexpected failure while serving
+	exitConfig     = 2 // invalid flags or environment
+	exitBind       = 3 // transport could not bind its address
+	exitServerInit = 4 // server or handlers failed to initialize
+)
+
+// exitError is an error that terminates the program with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// exitCode returns the exit code for an error returned by run.
+func exitCode(err error) int {
+	var exitErr *exitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.Is(err, transport.ErrListen):
+		return exitBind
+	default:
+		return exitFailure
+	}
+}
+
 type Config struct {
-	TransportType   string        `arg:"--transport,env:MCP_TRANSPORT" default:"stdio" help:"Transport type (stdio|http)"`
-	HTTPPort        int           `arg:"--port,env:MCP_PORT" default:"8080" help:"HTTP port"`
-	ServerName      string        `arg:"--name,env:MCP_SERVER_NAME" default:"MCP Server" help:"Server name"`
-	ServerVersion   string        `arg:"--version,env:MCP_SERVER_VERSION" default:"1.0.0" help:"Server version"`
-	RequestTimeout  time.Duration `arg:"--request-timeout,env:MCP_REQUEST_TIMEOUT" default:"30s" help:"Request timeout"`
-	ShutdownTimeout time.Duration `arg:"--shutdown-timeout,env:MCP_SHUTDOWN_TIMEOUT" default:"5s" help:"Shutdown timeout"`
-	ReadTimeout     time.Duration `arg:"--read-timeout,env:MCP_READ_TIMEOUT" default:"30s" help:"HTTP read timeout"`
-	WriteTimeout    time.Duration `arg:"--write-timeout,env:MCP_WRITE_TIMEOUT" default:"30s" help:"HTTP write timeout"`
-	IdleTimeout     time.Duration `arg:"--idle-timeout,env:MCP_IDLE_TIMEOUT" default:"120s" help:"HTTP idle timeout"`
-	LogLevel        string        `arg:"--log-level,env:MCP_LOG_LEVEL" default:"info" help:"Log level (debug|info|warn|error)"`
-	LogJSON         bool          `arg:"--log-json,env:MCP_LOG_JSON" help:"Output logs in JSON format"`
+	TransportType   string            `arg:"--transport,env:MCP_TRANSPORT" default:"stdio" help:"Transport type (stdio|http|tcp|pipe|grpc, grpc is experimental)"`
+	Profile         string            `arg:"--profile,env:MCP_PROFILE" help:"Preset defaults for an environment (dev|staging|prod), explicit flags and environment variables take precedence"`
+	HTTPPort        int               `arg:"--port,env:MCP_PORT" default:"8080" help:"HTTP port"`
+	BindAddr        string            `arg:"--bind,env:MCP_BIND_ADDR" default:"127.0.0.1" help:"Address --port and --admin-port bind to, 0.0.0.0 for all interfaces (http only)"`
+	TCPAddr         string            `arg:"--tcp-addr,env:MCP_TCP_ADDR" default:"localhost:9090" help:"Address to listen on (tcp only)"`
+	PipeName        string            `arg:"--pipe-name,env:MCP_PIPE_NAME" default:"\\\\.\\pipe\\go-mcp-server" help:"Windows named pipe to listen on (pipe only)"`
+	GRPCAddr        string            `arg:"--grpc-addr,env:MCP_GRPC_ADDR" default:"localhost:50051" help:"Address to listen on (grpc only)"`
+	ServerName      string            `arg:"--name,env:MCP_SERVER_NAME" default:"MCP Server" help:"Server name"`
+	ServerTitle     string            `arg:"--title,env:MCP_SERVER_TITLE" help:"Server display name"`
+	Instructions    string            `arg:"--instructions,env:MCP_INSTRUCTIONS" help:"Usage guidance for the model returned on initialize (defaults to the tea server's)"`
+	ServerVersion   string            `arg:"--version,env:MCP_SERVER_VERSION" default:"1.0.0" help:"Server version"`
+	RequestTimeout  time.Duration     `arg:"--request-timeout,env:MCP_REQUEST_TIMEOUT" default:"30s" help:"Request timeout"`
+	MaxRequestBytes int               `arg:"--max-request-bytes,env:MCP_MAX_REQUEST_BYTES" default:"4194304" help:"Maximum size of a single inbound message in bytes"`
+	ShutdownTimeout time.Duration     `arg:"--shutdown-timeout,env:MCP_SHUTDOWN_TIMEOUT" default:"5s" help:"Shutdown timeout"`
+	ReadTimeout     time.Duration     `arg:"--read-timeout,env:MCP_READ_TIMEOUT" default:"30s" help:"HTTP read timeout"`
+	WriteTimeout    time.Duration     `arg:"--write-timeout,env:MCP_WRITE_TIMEOUT" default:"30s" help:"HTTP, TCP and pipe write timeout"`
+	IdleTimeout     time.Duration     `arg:"--idle-timeout,env:MCP_IDLE_TIMEOUT" default:"120s" help:"HTTP idle timeout, and how long a TCP or pipe connection may stay silent"`
+	LongPollTimeout time.Duration     `arg:"--long-poll-timeout,env:MCP_LONG_POLL_TIMEOUT" default:"20s" help:"How long a long-poll waits for server messages (http only)"`
+	SSEKeepAlive    time.Duration     `arg:"--sse-keepalive,env:MCP_SSE_KEEPALIVE" default:"15s" help:"Send a keepalive comment on event streams idle this long, 0 disables (http only)"`
+	LogLevel        string            `arg:"--log-level,env:MCP_LOG_LEVEL" default:"info" help:"Log level (debug|info|warn|error)"`
+	LogJSON         bool              `arg:"--log-json,env:MCP_LOG_JSON" help:"Output logs in JSON format"`
+	ACMEDomains     []string          `arg:"--acme-domain,separate,env:MCP_ACME_DOMAINS" help:"Domain to obtain a TLS certificate for via ACME (repeatable, http only)"`
+	ACMECacheDir    string            `arg:"--acme-cache-dir,env:MCP_ACME_CACHE_DIR" default:"acme-cache" help:"Directory to persist ACME certificates in"`
+	ACMEEmail       string            `arg:"--acme-email,env:MCP_ACME_EMAIL" help:"Contact email passed to the ACME certificate authority"`
+	ACMEChallenge   string            `arg:"--acme-challenge-addr,env:MCP_ACME_CHALLENGE_ADDR" default:":80" help:"Address of the plain HTTP listener answering ACME HTTP-01 challenges and redirecting to HTTPS; empty leaves TLS-ALPN-01 on the TLS port only"`
+	AllowedHosts    []string          `arg:"--allowed-host,separate,env:MCP_ALLOWED_HOSTS" help:"Accepted Host header value for DNS rebinding protection (repeatable, http only)"`
+	TrustedProxies  []string          `arg:"--trusted-proxy,separate,env:MCP_TRUSTED_PROXIES" help:"IP or CIDR range of a reverse proxy whose X-Forwarded-For, -Proto and -Host headers are trusted (repeatable, http only)"`
+	BrowserOrigins  []string          `arg:"--browser-origin,separate,env:MCP_BROWSER_ORIGINS" help:"Origin allowed to obtain short-lived tokens from /mcp/token, enables token checks for browser requests (repeatable, http only)"`
+	CORSOrigins     []string          `arg:"--cors-origin,separate,env:MCP_CORS_ORIGINS" help:"Origin allowed to call /mcp from browsers, * for any, defaults to loopback origins (repeatable, http only)"`
+	CORSHeaders     []string          `arg:"--cors-header,separate,env:MCP_CORS_HEADERS" help:"Additional request header browsers may send to /mcp, e.g. X-Api-Key (repeatable, http only)"`
+	CORSMethods     []string          `arg:"--cors-method,separate,env:MCP_CORS_METHODS" help:"Method browsers may call /mcp with, replacing the default GET, POST, DELETE and OPTIONS (repeatable, http only)"`
+	CORSCredentials bool              `arg:"--cors-credentials,env:MCP_CORS_CREDENTIALS" help:"Allow browsers to send cookies and HTTP authentication to /mcp (requires --cors-origin, http only)"`
+	BrowserTokenTTL time.Duration     `arg:"--browser-token-ttl,env:MCP_BROWSER_TOKEN_TTL" default:"5m" help:"Lifetime of browser session tokens"`
+	ResponseHeaders map[string]string `arg:"--response-header,separate,env:MCP_RESPONSE_HEADERS" help:"Extra HTTP response header as Name=value, empty value removes a default (repeatable, http only)"`
+	AdminPort       int               `arg:"--admin-port,env:MCP_ADMIN_PORT" help:"Serve status, health and debug endpoints on this port only, 0 disables (http only)"`
+	AdminToken      string            `arg:"--admin-token,env:MCP_ADMIN_TOKEN" help:"Bearer token required on /admin endpoints, which are only served with it (http only)"`
+	Listen          []string          `arg:"--listen,separate,env:MCP_LISTEN" help:"Address to listen on instead of --port, e.g. [::1]:8080 (repeatable, http only)"`
+	BasePath        string            `arg:"--base-path,env:MCP_BASE_PATH" help:"Serve all endpoints under this path, e.g. /api/ai for /api/ai/mcp behind a gateway (http only)"`
+	NoStatusPage    bool              `arg:"--no-status-page,env:MCP_NO_STATUS_PAGE" help:"Do not serve the HTML status page at the root path (http only)"`
+	MaxSessions     int               `arg:"--max-sessions,env:MCP_MAX_SESSIONS" help:"Maximum concurrent sessions, 0 is unlimited (http only)"`
+	SessionsPerIP   int               `arg:"--max-sessions-per-ip,env:MCP_MAX_SESSIONS_PER_IP" help:"Maximum concurrent sessions per client IP, 0 is unlimited (http only)"`
+	SessionIdle     time.Duration     `arg:"--session-idle-timeout,env:MCP_SESSION_IDLE_TIMEOUT" default:"30m" help:"End sessions without requests or open event stream for this long, 0 keeps them (http only)"`
+	SessionStore    int               `arg:"--session-store-limit,env:MCP_SESSION_STORE_LIMIT" help:"Maximum sessions kept, including those without open event stream, evicting the least recently active idle one; 0 is unlimited (http only)"`
+	OnSessionLimit  string            `arg:"--session-limit-action,env:MCP_SESSION_LIMIT_ACTION" default:"reject" help:"Action when a session limit is reached (reject|evict-idle)"`
+	OrderResponses  bool              `arg:"--ordered-responses,env:MCP_ORDERED_RESPONSES" help:"Write responses in request order (stdio only)"`
+	SessionInfo     bool              `arg:"--session-info-tool,env:MCP_SESSION_INFO_TOOL" help:"Expose the mcp.sessionInfo diagnostic tool"`
+	SanitizeInput   bool              `arg:"--sanitize-input,env:MCP_SANITIZE_INPUT" help:"Strip control characters from request parameters"`
+	StrictValidate  bool              `arg:"--strict-validation,env:MCP_STRICT_VALIDATION" help:"Reject messages with unknown fields, null IDs or non-object params"`
+	LogSampleEvery  int               `arg:"--log-sample-every,env:MCP_LOG_SAMPLE_EVERY" help:"Log every Nth request at debug level, 0 disables"`
+	SettingsFile    string            `arg:"--settings-file,env:MCP_SETTINGS_FILE" help:"File persisting runtime settings changed through /admin/settings, applied on startup"`
+	LogMethodLevels map[string]string `arg:"--log-method-level,separate,env:MCP_LOG_METHOD_LEVELS" help:"Log level for requests of a method as method=level, e.g. tools/call=debug (repeatable)"`
+	ToolDocs        bool              `arg:"--tool-docs,env:MCP_TOOL_DOCS" help:"Expose a doc://tools/{name} markdown resource per tool"`
+	Provenance      bool              `arg:"--provenance,env:MCP_PROVENANCE" help:"Add server, tool, timestamp and duration to the _meta of tool results"`
+	Telemetry       bool              `arg:"--telemetry,env:MCP_TELEMETRY" help:"Opt in to anonymous usage reports (requires --telemetry-url)"`
+	Transcripts     bool              `arg:"--transcripts,env:MCP_TRANSCRIPTS" help:"Record session transcripts with redacted arguments, exported via /admin/transcripts/ on the admin port with --admin-token"`
+	TranscriptFile  string            `arg:"--transcript-file,env:MCP_TRANSCRIPT_FILE" help:"Record session transcripts and write them to this file on exit, as Markdown if it ends in .md, otherwise as JSON"`
+	MemoryThreshold int               `arg:"--memory-threshold,env:MCP_MEMORY_THRESHOLD" help:"Reject tool calls and resource reads while memory in use exceeds this many MiB, 0 disables"`
+	MemLimitRatio   float64           `arg:"--memory-limit-ratio,env:MCP_MEMORY_LIMIT_RATIO" help:"Reject tool calls and resource reads once memory in use reaches this fraction of GOMEMLIMIT, e.g. 0.9, 0 disables"`
+	TelemetryURL    string            `arg:"--telemetry-url,env:MCP_TELEMETRY_URL" help:"Endpoint anonymous usage reports are sent to"`
+	PrintOpenAPI    bool              `arg:"--print-openapi" help:"Print an OpenAPI document describing the hosted tools and exit"`
+
+	ClientConfig *ClientConfigCmd `arg:"subcommand:client-config" help:"Print the JSON snippet registering this server in an MCP client and exit"`
 }
 
 func (Config) Description() string {
@@ -42,7 +135,7 @@ func (Config) Description() string {
 
 This application provides a sample MCP server implementation that demonstrates
 tools, resources, and prompts through the Model Context Protocol (MCP). 
-It supports both stdio and HTTP transports for integration with various MCP clients.
+It supports stdio, HTTP, raw TCP and Windows named pipe transports for integration with various MCP clients.
 
 Configuration can be provided via command line arguments or environment variables.
 Environment variables use the prefix "MCP_" followed by the uppercase field name.
@@ -54,6 +147,18 @@ Examples:
   # Run with HTTP transport on port 3000
   go-mcp-server --transport http --port 3000
 
+  # Run as a daemon accepting newline-delimited JSON-RPC over TCP
+  go-mcp-server --transport tcp --tcp-addr 0.0.0.0:9090
+
+  # Serve editor extensions on Windows over a named pipe
+  go-mcp-server --transport pipe --pipe-name \\.\pipe\go-mcp-server
+
+  # Run with HTTPS using an automatically provisioned certificate
+  go-mcp-server --transport http --bind 0.0.0.0 --port 443 --acme-domain mcp.example.com
+
+  # Run in production with JSON logs, strict validation, session limits and an admin token
+  MCP_ADMIN_TOKEN=... go-mcp-server --profile prod --transport http --bind 0.0.0.0
+
   # Set server name via environment variable
   MCP_SERVER_NAME="My MCP Server" go-mcp-server`
 }
@@ -64,19 +169,40 @@ func (Config) Version() string {
 
 func (c *Config) Validate() error {
 	switch c.TransportType {
-	case transportStdio, transportHTTP:
+	case transportStdio, transportHTTP, transportTCP, transportPipe, transportGRPC:
 	default:
-		return fmt.Errorf("invalid transport type: %s (must be '%s' or '%s')", c.TransportType, transportStdio, transportHTTP)
+		return fmt.Errorf("invalid transport type: %s (must be '%s', '%s', '%s', '%s' or '%s')", c.TransportType, transportStdio, transportHTTP, transportTCP, transportPipe, transportGRPC)
+	}
+
+	if c.TransportType == transportPipe && runtime.GOOS != "windows" {
+		return fmt.Errorf("the %s transport is only supported on Windows", transportPipe)
 	}
 
 	if c.HTTPPort < minPort || c.HTTPPort > maxPort {
 		return fmt.Errorf("invalid port: %d (must be %d-%d)", c.HTTPPort, minPort, maxPort)
 	}
 
+	if c.AdminPort != 0 {
+		if c.AdminPort < minPort || c.AdminPort > maxPort {
+			return fmt.Errorf("invalid admin port: %d (must be %d-%d)", c.AdminPort, minPort, maxPort)
+		}
+		if c.AdminPort == c.HTTPPort && len(c.Listen) == 0 {
+			return fmt.Errorf("invalid admin port: %d (must differ from port)", c.AdminPort)
+		}
+	}
+
+	if c.Profile == profileProd && c.TransportType == transportHTTP && c.AdminToken == "" {
+		return fmt.Errorf("the %s profile requires --admin-token (or MCP_ADMIN_TOKEN) to protect the /admin endpoints", profileProd)
+	}
+
 	if c.RequestTimeout <= 0 {
 		return fmt.Errorf("invalid request timeout: %v (must be positive)", c.RequestTimeout)
 	}
 
+	if c.MaxRequestBytes <= 0 {
+		return fmt.Errorf("invalid max request bytes: %d (must be positive)", c.MaxRequestBytes)
+	}
+
 	if c.ShutdownTimeout <= 0 {
 		return fmt.Errorf("invalid shutdown timeout: %v (must be positive)", c.ShutdownTimeout)
 	}
@@ -93,16 +219,65 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid idle timeout: %v (must be positive)", c.IdleTimeout)
 	}
 
+	if c.LongPollTimeout <= 0 {
+		return fmt.Errorf("invalid long-poll timeout: %v (must be positive)", c.LongPollTimeout)
+	}
+
+	if c.SSEKeepAlive < 0 {
+		return fmt.Errorf("invalid SSE keepalive interval: %v (must not be negative)", c.SSEKeepAlive)
+	}
+
+	if len(c.ACMEDomains) > 0 {
+		if c.TransportType != transportHTTP {
+			return fmt.Errorf("ACME requires the '%s' transport", transportHTTP)
+		}
+		if c.ACMECacheDir == "" {
+			return fmt.Errorf("invalid ACME cache directory: must not be empty")
+		}
+	}
+
+	if c.CORSCredentials && (len(c.CORSOrigins) == 0 || slices.Contains(c.CORSOrigins, "*")) {
+		return fmt.Errorf("CORS credentials require --cors-origin to list the allowed origins")
+	}
+
+	if c.BrowserTokenTTL <= 0 {
+		return fmt.Errorf("invalid browser token TTL: %v (must be positive)", c.BrowserTokenTTL)
+	}
+
 	switch c.LogLevel {
 	case "debug", "info", "warn", "error":
 	default:
 		return fmt.Errorf("invalid log level: %s (must be 'debug', 'info', 'warn', or 'error')", c.LogLevel)
 	}
 
+	if c.MaxSessions < 0 || c.SessionsPerIP < 0 || c.SessionStore < 0 {
+		return fmt.Errorf("invalid session limit: must not be negative")
+	}
+
+	if c.SessionIdle < 0 {
+		return fmt.Errorf("invalid session idle timeout: %v (must not be negative)", c.SessionIdle)
+	}
+
+	switch c.OnSessionLimit {
+	case sessionLimitReject, sessionLimitEvictIdle:
+	default:
+		return fmt.Errorf("invalid session limit action: %s (must be '%s' or '%s')", c.OnSessionLimit, sessionLimitReject, sessionLimitEvictIdle)
+	}
+
+	if c.Telemetry && c.TelemetryURL == "" {
+		return fmt.Errorf("telemetry requires a telemetry URL")
+	}
+
+	if c.LogSampleEvery < 0 {
+		return fmt.Errorf("invalid log sampling rate: %d (must not be negative)", c.LogSampleEvery)
+	}
+
 	return nil
 }
 
-func parseArgs() (*Config, error) {
+// parseArgs parses the command line arguments, without the program name, and
+// the MCP_* environment variables into a validated configuration.
+func parseArgs(args []string) (*Config, error) {
 	var cfg Config
 
 	parser, err := arg.NewParser(arg.Config{
@@ -112,11 +287,26 @@ func parseArgs() (*Config, error) {
 		return nil, fmt.Errorf("failed to create argument parser: %w", err)
 	}
 
-	err = parser.Parse(os.Args[1:])
+	err = parser.Parse(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
+	// Parse again on top of the profile's presets, keeping the presets of
+	// everything not set explicitly
+	if cfg.Profile != "" {
+		if cfg, err = profileDefaults(cfg.Profile); err != nil {
+			return nil, fmt.Errorf("configuration validation failed: %w", err)
+		}
+		parser, err = arg.NewParser(arg.Config{Program: "go-mcp-server", IgnoreDefault: true}, &cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create argument parser: %w", err)
+		}
+		if err := parser.Parse(args); err != nil {
+			return nil, fmt.Errorf("failed to parse arguments: %w", err)
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
@@ -125,25 +315,32 @@ func parseArgs() (*Config, error) {
 }
 
 func main() {
-	cfg, err := parseArgs()
+	cfg, err := parseArgs(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitConfig)
+	}
+
+	if cfg.ClientConfig != nil {
+		if err := printClientConfig(os.Stdout, cfg, os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		return
 	}
 
 	if err := run(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 func run(cfg *Config) error {
 	teaHandler := &handlers.TeaHandler{}
 
-	mcpServer, err := server.NewMCPServer(
-		cfg.ServerName,
-		cfg.ServerVersion,
-		teaHandler, teaHandler, teaHandler,
+	opts := []server.Option{
+		server.WithServerTitle(cfg.ServerTitle),
+		server.WithInstructions(cmp.Or(cfg.Instructions, handlers.TeaInstructions)),
 		server.WithRequestTimeout(cfg.RequestTimeout),
 		server.WithShutdownTimeout(cfg.ShutdownTimeout),
 		server.WithReadTimeout(cfg.ReadTimeout),
@@ -151,14 +348,50 @@ func run(cfg *Config) error {
 		server.WithIdleTimeout(cfg.IdleTimeout),
 		server.WithLogLevel(cfg.LogLevel),
 		server.WithLogJSON(cfg.LogJSON),
-	)
+		server.WithInputSanitization(cfg.SanitizeInput),
+		server.WithSessionInfoTool(cfg.SessionInfo),
+		server.WithToolDocs(cfg.ToolDocs),
+		server.WithProvenance(cfg.Provenance),
+		server.WithLogPolicy(server.LogPolicy{SampleEvery: cfg.LogSampleEvery, MethodLevels: cfg.LogMethodLevels}),
+		server.WithCompletionHandler(teaHandler),
+	}
+	if cfg.StrictValidate {
+		opts = append(opts, server.WithStrictValidation())
+	}
+	if cfg.SettingsFile != "" {
+		opts = append(opts, server.WithSettingsFile(cfg.SettingsFile))
+	}
+	if cfg.Telemetry {
+		opts = append(opts, server.WithTelemetry(server.Telemetry{Endpoint: cfg.TelemetryURL}))
+	}
+	if cfg.Transcripts || cfg.TranscriptFile != "" {
+		opts = append(opts, server.WithTranscripts(server.Transcripts{}))
+	}
+	if cfg.MemoryThreshold > 0 || cfg.MemLimitRatio != 0 {
+		opts = append(opts, server.WithMemoryPressure(server.MemoryPressure{
+			Threshold:  uint64(max(cfg.MemoryThreshold, 0)) << 20,
+			LimitRatio: cfg.MemLimitRatio,
+		}))
+	}
+
+	mcpServer, err := server.NewMCPServer(cfg.ServerName, cfg.ServerVersion, teaHandler, teaHandler, teaHandler, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to create server: %w", err)
+		return &exitError{code: exitServerInit, err: fmt.Errorf("failed to create server: %w", err)}
+	}
+
+	if cfg.PrintOpenAPI {
+		doc, err := mcpServer.OpenAPI(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to generate OpenAPI document: %w", err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
 	}
 
 	transport, err := createTransport(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create transport: %w", err)
+		return &exitError{code: exitConfig, err: fmt.Errorf("failed to create transport: %w", err)}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -168,23 +401,164 @@ func run(cfg *Config) error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
+		// Drain in-flight requests while the transport can still deliver their responses
+		drainCtx, stop := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer stop()
+		mcpServer.Shutdown(drainCtx)
 		cancel()
 	}()
 
+	mcpServer.StartTelemetry(ctx, cfg.TransportType)
+
 	if err := transport.Start(ctx, mcpServer); err != nil {
 		return fmt.Errorf("transport start failed: %w", err)
 	}
 
+	if cfg.TranscriptFile != "" {
+		if err := writeTranscripts(mcpServer, cfg.TranscriptFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write transcripts: %v\n", err)
+		}
+	}
+	if err := mcpServer.AnnounceShutdown(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write shutdown report: %v\n", err)
+	}
 	return nil
 }
 
+// writeTranscripts writes the transcripts of all recorded sessions to path,
+// as Markdown if it ends in .md and as a JSON array otherwise.
+func writeTranscripts(srv *server.Server, path string) error {
+	transcripts := []server.Transcript{}
+	for _, id := range srv.TranscriptSessions() {
+		if transcript, ok := srv.Transcript(id); ok {
+			transcripts = append(transcripts, transcript)
+		}
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		var b strings.Builder
+		for i, transcript := range transcripts {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(transcript.Markdown())
+		}
+		data = []byte(b.String())
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(transcripts, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	// Transcripts may contain user data even with arguments redacted
+	return os.WriteFile(path, data, 0o600)
+}
+
 func createTransport(cfg *Config) (transport.Transport, error) {
 	switch strings.ToLower(cfg.TransportType) {
 	case transportStdio:
-		return transport.NewStdio(), nil
+		return transport.NewStdio(
+			transport.WithStdioRequestTimeout(cfg.RequestTimeout),
+			transport.WithStdioMaxMessageSize(cfg.MaxRequestBytes),
+			transport.WithOrderedResponses(cfg.OrderResponses),
+		)
 	case transportHTTP:
-		return transport.NewHTTP(cfg.HTTPPort, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout, cfg.ShutdownTimeout, cfg.RequestTimeout), nil
+		opts := []transport.HTTPOption{
+			transport.WithPort(cfg.HTTPPort),
+			transport.WithBindAddr(cfg.BindAddr),
+			transport.WithBasePath(cfg.BasePath),
+			transport.WithStatusPage(!cfg.NoStatusPage),
+			transport.WithReadTimeout(cfg.ReadTimeout),
+			transport.WithWriteTimeout(cfg.WriteTimeout),
+			transport.WithIdleTimeout(cfg.IdleTimeout),
+			transport.WithShutdownTimeout(cfg.ShutdownTimeout),
+			transport.WithRequestTimeout(cfg.RequestTimeout),
+			transport.WithMaxMessageSize(cfg.MaxRequestBytes),
+			transport.WithSessionIdleTimeout(cfg.SessionIdle),
+			transport.WithSessionLimit(cfg.SessionStore),
+			transport.WithLongPollTimeout(cfg.LongPollTimeout),
+			transport.WithSSEKeepAlive(cfg.SSEKeepAlive),
+		}
+		if cfg.AdminPort != 0 {
+			opts = append(opts, transport.WithAdminPort(cfg.AdminPort))
+		}
+		if cfg.AdminToken != "" {
+			opts = append(opts, transport.WithAdminToken(cfg.AdminToken))
+		}
+		for _, addr := range cfg.Listen {
+			opts = append(opts, transport.WithListener(transport.Listener{Addr: addr}))
+		}
+		if len(cfg.ACMEDomains) > 0 {
+			opts = append(opts, transport.WithACME(cfg.ACMEDomains, cfg.ACMECacheDir, cfg.ACMEEmail))
+			opts = append(opts, transport.WithACMEChallengeAddr(cfg.ACMEChallenge))
+		}
+		if len(cfg.AllowedHosts) > 0 {
+			opts = append(opts, transport.WithAllowedHosts(cfg.AllowedHosts...))
+		}
+		if len(cfg.TrustedProxies) > 0 {
+			opts = append(opts, transport.WithTrustedProxies(cfg.TrustedProxies...))
+		}
+		if cfg.MaxSessions > 0 || cfg.SessionsPerIP > 0 {
+			admission := transport.SessionAdmission{MaxSessions: cfg.MaxSessions, MaxSessionsPerIP: cfg.SessionsPerIP}
+			if cfg.OnSessionLimit == sessionLimitEvictIdle {
+				admission.OnLimit = transport.AdmissionEvictIdle
+			}
+			opts = append(opts, transport.WithSessionAdmission(admission))
+		}
+		if len(cfg.BrowserOrigins) > 0 {
+			opts = append(opts, transport.WithBrowserTokens(transport.BrowserTokens{Origins: cfg.BrowserOrigins, TTL: cfg.BrowserTokenTTL}))
+		}
+		if len(cfg.ResponseHeaders) > 0 {
+			opts = append(opts, transport.WithResponseHeaders(cfg.ResponseHeaders))
+		}
+		if len(cfg.CORSOrigins) > 0 {
+			opts = append(opts, transport.WithAllowedOrigins(cfg.CORSOrigins...))
+		}
+		// The prod profile denies browsers unless their origins are listed
+		if len(cfg.CORSOrigins) > 0 || len(cfg.CORSHeaders) > 0 || len(cfg.CORSMethods) > 0 || cfg.CORSCredentials || cfg.Profile == profileProd {
+			rules := transport.DefaultCORSRules()
+			for i := range rules {
+				if rules[i].PathPrefix != "/mcp" {
+					continue
+				}
+				if len(cfg.CORSOrigins) > 0 || cfg.Profile == profileProd {
+					rules[i].AllowedOrigins = cfg.CORSOrigins
+				}
+				rules[i].AllowedHeaders = append(rules[i].AllowedHeaders, cfg.CORSHeaders...)
+				if len(cfg.CORSMethods) > 0 {
+					rules[i].AllowedMethods = cfg.CORSMethods
+				}
+				rules[i].AllowCredentials = cfg.CORSCredentials
+			}
+			opts = append(opts, transport.WithCORSRules(rules...))
+		}
+		return transport.NewHTTP(opts...)
+	case transportTCP:
+		return transport.NewTCP(
+			transport.WithTCPAddr(cfg.TCPAddr),
+			transport.WithTCPReadTimeout(cfg.IdleTimeout),
+			transport.WithTCPWriteTimeout(cfg.WriteTimeout),
+			transport.WithTCPRequestTimeout(cfg.RequestTimeout),
+			transport.WithTCPMaxMessageSize(cfg.MaxRequestBytes),
+		)
+	case transportPipe:
+		return transport.NewPipe(
+			transport.WithPipeName(cfg.PipeName),
+			transport.WithPipeReadTimeout(cfg.IdleTimeout),
+			transport.WithPipeWriteTimeout(cfg.WriteTimeout),
+			transport.WithPipeRequestTimeout(cfg.RequestTimeout),
+			transport.WithPipeMaxMessageSize(cfg.MaxRequestBytes),
+		)
+	case transportGRPC:
+		return transport.NewGRPC(
+			transport.WithGRPCAddr(cfg.GRPCAddr),
+			transport.WithGRPCWriteTimeout(cfg.WriteTimeout),
+			transport.WithGRPCRequestTimeout(cfg.RequestTimeout),
+			transport.WithGRPCMaxMessageSize(cfg.MaxRequestBytes),
+		)
 	default:
-		return nil, fmt.Errorf("invalid transport type: %s (must be '%s' or '%s')", cfg.TransportType, transportStdio, transportHTTP)
+		return nil, fmt.Errorf("invalid transport type: %s (must be '%s', '%s', '%s', '%s' or '%s')", cfg.TransportType, transportStdio, transportHTTP, transportTCP, transportPipe, transportGRPC)
 	}
 }
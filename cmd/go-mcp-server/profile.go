@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alexflint/go-arg"
+)
+
+// Profiles selectable with --profile.
+const (
+	profileDev     = "dev"
+	profileStaging = "staging"
+	profileProd    = "prod"
+)
+
+// Session limits of the prod profile.
+const (
+	prodMaxSessions      = 1000
+	prodMaxSessionsPerIP = 20
+	prodMemLimitRatio    = 0.9
+)
+
+// profileDefaults returns the defaults of a profile: the defaults of the flags
+// with the profile's presets applied on top.
+//
+// Only scalar settings are preset, since repeatable flags append to preset
+// values instead of replacing them. The prod profile's strict CORS policy is
+// applied in createTransport instead.
+func profileDefaults(profile string) (Config, error) {
+	var cfg Config
+	parser, err := arg.NewParser(arg.Config{Program: "go-mcp-server", IgnoreEnv: true}, &cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to create argument parser: %w", err)
+	}
+	if err := parser.Parse(nil); err != nil {
+		return Config{}, fmt.Errorf("failed to read flag defaults: %w", err)
+	}
+
+	switch profile {
+	case profileDev:
+		// Verbose, human-readable logs and diagnostics for trying out clients
+		cfg.LogLevel = "debug"
+		cfg.SessionInfo = true
+		cfg.ToolDocs = true
+	case profileStaging:
+		cfg.LogJSON = true
+		cfg.StrictValidate = true
+		cfg.SanitizeInput = true
+	case profileProd:
+		cfg.LogJSON = true
+		cfg.StrictValidate = true
+		cfg.SanitizeInput = true
+		cfg.NoStatusPage = true
+		cfg.MaxSessions = prodMaxSessions
+		cfg.SessionsPerIP = prodMaxSessionsPerIP
+		cfg.MemLimitRatio = prodMemLimitRatio
+	default:
+		return Config{}, fmt.Errorf("invalid profile: %s (must be '%s', '%s' or '%s')", profile, profileDev, profileStaging, profileProd)
+	}
+
+	cfg.Profile = profile
+	return cfg, nil
+}
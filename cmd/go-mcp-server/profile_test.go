@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestProfilePresets(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(*Config) bool
+	}{
+		{"no profile keeps the flag defaults", nil, func(c *Config) bool {
+			return c.Profile == "" && c.LogLevel == "info" && !c.LogJSON && !c.StrictValidate && c.MaxSessions == 0
+		}},
+		{"dev", []string{"--profile", "dev"}, func(c *Config) bool {
+			return c.LogLevel == "debug" && c.SessionInfo && c.ToolDocs && !c.LogJSON
+		}},
+		{"staging", []string{"--profile", "staging"}, func(c *Config) bool {
+			return c.LogJSON && c.StrictValidate && c.SanitizeInput && !c.NoStatusPage && c.MaxSessions == 0
+		}},
+		{"prod", []string{"--profile", "prod", "--transport", "http", "--admin-token", "test-admin-token-0123456789"}, func(c *Config) bool {
+			return c.LogJSON && c.StrictValidate && c.SanitizeInput && c.NoStatusPage &&
+				c.MaxSessions == prodMaxSessions && c.SessionsPerIP == prodMaxSessionsPerIP && c.MemLimitRatio == prodMemLimitRatio
+		}},
+		{"presets keep the other flag defaults", []string{"--profile", "prod"}, func(c *Config) bool {
+			return c.TransportType == transportStdio && c.HTTPPort == 8080 && c.ServerName == "MCP Server" && c.LogLevel == "info"
+		}},
+		{"explicit flags override presets", []string{"--profile", "prod", "--max-sessions", "5", "--memory-limit-ratio", "0.5"}, func(c *Config) bool {
+			return c.MaxSessions == 5 && c.MemLimitRatio == 0.5 && c.SessionsPerIP == prodMaxSessionsPerIP
+		}},
+		{"explicit flags override dev presets", []string{"--log-level", "warn", "--profile", "dev"}, func(c *Config) bool {
+			return c.LogLevel == "warn" && c.SessionInfo
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("Unexpected configuration %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestProfileEnvironmentOverridesPresets(t *testing.T) {
+	t.Setenv("MCP_PROFILE", "prod")
+	t.Setenv("MCP_MAX_SESSIONS_PER_IP", "3")
+
+	cfg, err := parseArgs(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Profile != profileProd || cfg.SessionsPerIP != 3 || cfg.MaxSessions != prodMaxSessions {
+		t.Errorf("Expected the environment to override the prod preset, got %+v", cfg)
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown profile", []string{"--profile", "qa"}},
+		{"prod http without admin token", []string{"--profile", "prod", "--transport", "http"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseArgs(tt.args); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package compat keeps the transport constructor signatures from before
+// functional options as thin adapters over the option-based constructors,
+// so that embedders can upgrade and migrate their call sites one at a time.
+//
+// Every function in this package is deprecated and will be removed in the
+// next major version.
+package compat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/transport"
+)
+
+// NewHTTP creates an HTTP transport listening on port with the given
+// timeouts. Like the earlier constructor, it binds all interfaces, and a
+// port that cannot be bound makes Start fail.
+//
+// A timeout that is not positive selects the transport's default, see the
+// DefaultHTTP*Timeout constants in the transport package.
+//
+// Deprecated: Use transport.NewHTTP with transport.WithPort,
+// transport.WithBindAddr and the timeout options instead.
+func NewHTTP(port int, readTimeout, writeTimeout, idleTimeout, shutdownTimeout, requestTimeout time.Duration) *transport.HTTPTransport {
+	opts := []transport.HTTPOption{
+		// The earlier constructor listened on ":port" without checking the port
+		transport.WithListener(transport.Listener{Addr: fmt.Sprintf(":%d", port)}),
+	}
+	timeouts := []struct {
+		value time.Duration
+		opt   func(time.Duration) transport.HTTPOption
+	}{
+		{readTimeout, transport.WithReadTimeout},
+		{writeTimeout, transport.WithWriteTimeout},
+		{idleTimeout, transport.WithIdleTimeout},
+		{shutdownTimeout, transport.WithShutdownTimeout},
+		{requestTimeout, transport.WithRequestTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value > 0 {
+			opts = append(opts, timeout.opt(timeout.value))
+		}
+	}
+
+	// Only valid values are passed on, so this cannot fail
+	t, _ := transport.NewHTTP(opts...)
+	return t
+}
+
+// NewStdio creates a stdio transport with the default settings.
+//
+// Deprecated: Use transport.NewStdio instead.
+func NewStdio() *transport.Stdio {
+	// The defaults are always valid, so this cannot fail
+	t, _ := transport.NewStdio()
+	return t
+}
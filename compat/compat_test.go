@@ -0,0 +1,61 @@
+package compat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/server"
+	"github.com/cbrgm/go-mcp-server/transport"
+)
+
+// The signatures of the constructors before they took options; a change to
+// any of them breaks the build.
+var (
+	_ func(port int, readTimeout, writeTimeout, idleTimeout, shutdownTimeout, requestTimeout time.Duration) *transport.HTTPTransport = NewHTTP
+	_ func() *transport.Stdio                                                                                                        = NewStdio
+)
+
+func TestNewHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts []time.Duration
+	}{
+		{"timeouts", []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second}},
+		{"zero timeouts select the defaults", []time.Duration{0, 0, 0, 0, 0}},
+		{"negative timeouts select the defaults", []time.Duration{-1, -1, -1, -1, -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.timeouts
+			if tr := NewHTTP(8080, d[0], d[1], d[2], d[3], d[4]); tr == nil {
+				t.Fatal("Expected a transport")
+			}
+		})
+	}
+}
+
+func TestNewHTTPInvalidPort(t *testing.T) {
+	// Like the earlier constructor, an invalid port fails on Start
+	tr := NewHTTP(-1, 0, 0, 0, 0, 0)
+	if tr == nil {
+		t.Fatal("Expected a transport")
+	}
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := tr.Start(context.Background(), srv); !errors.Is(err, transport.ErrListen) {
+		t.Errorf("Expected ErrListen, got %v", err)
+	}
+}
+
+func TestNewStdio(t *testing.T) {
+	if tr := NewStdio(); tr == nil {
+		t.Fatal("Expected a transport")
+	}
+}
@@ -2,6 +2,18 @@ module github.com/cbrgm/go-mcp-server
 
 go 1.24.4
 
-require github.com/alexflint/go-arg v1.6.1
+require (
+	github.com/Microsoft/go-winio v0.6.2
+	github.com/alexflint/go-arg v1.6.1
+	golang.org/x/crypto v0.45.0
+	golang.org/x/net v0.47.0
+	golang.org/x/sys v0.38.0
+	golang.org/x/text v0.31.0
+	google.golang.org/grpc v1.75.1
+	google.golang.org/protobuf v1.36.9
+)
 
-require github.com/alexflint/go-scalar v1.2.0 // indirect
+require (
+	github.com/alexflint/go-scalar v1.2.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+)
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+)
+
+// Roles of the audience of annotated content.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Annotations tell the client how to use or display a resource or content item.
+type Annotations struct {
+	// Audience lists who the content is intended for, RoleUser and/or RoleAssistant.
+	Audience []string `json:"audience,omitempty"`
+
+	// Priority is the importance of the content from 0 (least, entirely
+	// optional) to 1 (most, effectively required). Nil leaves it unspecified.
+	Priority *float64 `json:"priority,omitempty"`
+
+	// LastModified is the time the content was last modified as an ISO 8601
+	// timestamp, e.g. "2025-01-12T15:00:58Z".
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// NewAnnotations returns annotations with the given priority and audience.
+func NewAnnotations(priority float64, audience ...string) *Annotations {
+	return &Annotations{Audience: audience, Priority: &priority}
+}
+
+// WithLastModified sets LastModified to t and returns the annotations.
+func (a *Annotations) WithLastModified(t time.Time) *Annotations {
+	a.LastModified = t.UTC().Format(time.RFC3339)
+	return a
+}
+
+// Validate checks the audience roles, the priority range and the timestamp format.
+// Nil annotations are valid.
+func (a *Annotations) Validate() error {
+	if a == nil {
+		return nil
+	}
+	for _, role := range a.Audience {
+		if role != RoleUser && role != RoleAssistant {
+			return fmt.Errorf("%w: unknown audience role %q", ErrInvalidContent, role)
+		}
+	}
+	if a.Priority != nil && (*a.Priority < 0 || *a.Priority > 1) {
+		return fmt.Errorf("%w: priority %v is not between 0 and 1", ErrInvalidContent, *a.Priority)
+	}
+	if a.LastModified != "" {
+		if _, err := time.Parse(time.RFC3339, a.LastModified); err != nil {
+			return fmt.Errorf("%w: lastModified %q is not an ISO 8601 timestamp", ErrInvalidContent, a.LastModified)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import "context"
+
+const (
+	// MethodCompletionComplete is the method clients use to request argument completions.
+	MethodCompletionComplete = "completion/complete"
+
+	// CompletionRefPrompt references a prompt by name.
+	CompletionRefPrompt = "ref/prompt"
+
+	// CompletionRefResource references a resource template by URI.
+	CompletionRefResource = "ref/resource"
+
+	// MaxCompletionValues is the maximum number of values in a completion result.
+	MaxCompletionValues = 100
+)
+
+// CompletionReference identifies what is being completed.
+type CompletionReference struct {
+	// Type is either CompletionRefPrompt or CompletionRefResource.
+	Type string `json:"type"`
+
+	// Name is the prompt name when Type is CompletionRefPrompt.
+	Name string `json:"name,omitempty"`
+
+	// URI is the resource template URI when Type is CompletionRefResource.
+	URI string `json:"uri,omitempty"`
+}
+
+// CompletionArgument is the argument being completed and its partial value.
+type CompletionArgument struct {
+	// Name is the name of the prompt argument or URI template variable.
+	Name string `json:"name"`
+
+	// Value is the partial value typed so far.
+	Value string `json:"value"`
+}
+
+// CompletionContext carries additional information for context-aware completions.
+type CompletionContext struct {
+	// Arguments contains previously resolved arguments of the same prompt or template.
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// CompleteParams contains the parameters of a completion/complete request.
+type CompleteParams struct {
+	// Ref identifies the prompt or resource template.
+	Ref CompletionReference `json:"ref"`
+
+	// Argument is the argument being completed.
+	Argument CompletionArgument `json:"argument"`
+
+	// Context contains already resolved arguments, if any.
+	Context *CompletionContext `json:"context,omitempty"`
+}
+
+// Completion contains the suggested values for an argument.
+type Completion struct {
+	// Values are the suggestions, at most MaxCompletionValues.
+	Values []string `json:"values"`
+
+	// Total is the total number of available matches, if known.
+	Total int `json:"total,omitempty"`
+
+	// HasMore indicates that more matches exist than were returned.
+	HasMore bool `json:"hasMore,omitempty"`
+}
+
+// CompleteResponse is the result of a completion/complete request.
+type CompleteResponse struct {
+	// Completion contains the suggested values.
+	Completion Completion `json:"completion"`
+}
+
+// CompletionHandler defines the interface for argument autocompletion.
+//
+// Implementations suggest values for prompt arguments and resource template
+// variables as the user types, enabling IDE-like completion in clients.
+type CompletionHandler interface {
+	// Complete returns suggestions for the partial argument value in params.
+	// Unknown references should return an error wrapping ErrPromptNotFound or
+	// ErrResourceNotFound.
+	Complete(ctx context.Context, params CompleteParams) (CompleteResponse, error)
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Content types of ContentItem and MessageContent.
+const (
+	ContentTypeText     = "text"
+	ContentTypeImage    = "image"
+	ContentTypeAudio    = "audio"
+	ContentTypeResource = "resource"
+)
+
+// NewTextContent returns a text content item for a ToolResponse.
+func NewTextContent(text string) ContentItem {
+	return ContentItem{Type: ContentTypeText, Text: text}
+}
+
+// NewImageContent returns an image content item for a ToolResponse.
+//
+// The raw image data is base64-encoded as required on the wire. If mimeType
+// is empty, it is detected from the data.
+func NewImageContent(data []byte, mimeType string) ContentItem {
+	return ContentItem{
+		Type:     ContentTypeImage,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: detectMimeType(data, mimeType),
+	}
+}
+
+// NewAudioContent returns an audio content item for a ToolResponse.
+//
+// The raw audio data is base64-encoded as required on the wire. If mimeType
+// is empty, it is detected from the data.
+func NewAudioContent(data []byte, mimeType string) ContentItem {
+	return ContentItem{
+		Type:     ContentTypeAudio,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: detectMimeType(data, mimeType),
+	}
+}
+
+// NewTextMessageContent returns text content for a PromptMessage or SamplingMessage.
+func NewTextMessageContent(text string) MessageContent {
+	return MessageContent{Type: ContentTypeText, Text: text}
+}
+
+// NewImageMessageContent returns image content for a PromptMessage or SamplingMessage.
+//
+// The raw image data is base64-encoded as required on the wire. If mimeType
+// is empty, it is detected from the data.
+func NewImageMessageContent(data []byte, mimeType string) MessageContent {
+	return MessageContent{
+		Type:     ContentTypeImage,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: detectMimeType(data, mimeType),
+	}
+}
+
+// NewAudioMessageContent returns audio content for a PromptMessage or SamplingMessage.
+//
+// The raw audio data is base64-encoded as required on the wire. If mimeType
+// is empty, it is detected from the data.
+func NewAudioMessageContent(data []byte, mimeType string) MessageContent {
+	return MessageContent{
+		Type:     ContentTypeAudio,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: detectMimeType(data, mimeType),
+	}
+}
+
+// Validate checks that the content item is well-formed for its type.
+func (c ContentItem) Validate() error {
+	if err := c.Annotations.Validate(); err != nil {
+		return err
+	}
+
+	switch c.Type {
+	case ContentTypeImage, ContentTypeAudio:
+		return validateBinaryContent(c.Type, c.Data, c.MimeType)
+	case ContentTypeResource:
+		if c.Resource == nil || c.Resource.URI == "" {
+			return fmt.Errorf("%w: resource content requires a resource URI", ErrInvalidContent)
+		}
+		return nil
+	case ContentTypeText:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown content type %q", ErrInvalidContent, c.Type)
+	}
+}
+
+// DecodeData returns the decoded binary data of image or audio content.
+func (c ContentItem) DecodeData() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(c.Data)
+}
+
+// Validate checks that the message content is well-formed for its type.
+func (c MessageContent) Validate() error {
+	switch c.Type {
+	case ContentTypeImage, ContentTypeAudio:
+		return validateBinaryContent(c.Type, c.Data, c.MimeType)
+	case ContentTypeText:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown content type %q", ErrInvalidContent, c.Type)
+	}
+}
+
+// DecodeData returns the decoded binary data of image or audio content.
+func (c MessageContent) DecodeData() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(c.Data)
+}
+
+// NewTextResourceContent returns text content for a ResourceResponse.
+func NewTextResourceContent(uri, mimeType, text string) ResourceContent {
+	return ResourceContent{URI: uri, MimeType: mimeType, Text: text}
+}
+
+// NewBlobResourceContent returns binary content for a ResourceResponse.
+//
+// The raw data is base64-encoded as required on the wire. If mimeType is
+// empty, it is detected from the data.
+func NewBlobResourceContent(uri string, data []byte, mimeType string) ResourceContent {
+	return ResourceContent{
+		URI:      uri,
+		MimeType: detectMimeType(data, mimeType),
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// Validate checks that the resource content is well-formed.
+func (c ResourceContent) Validate() error {
+	if c.URI == "" {
+		return fmt.Errorf("%w: resource content requires a URI", ErrInvalidContent)
+	}
+	if err := c.Annotations.Validate(); err != nil {
+		return err
+	}
+	if c.Blob == "" {
+		return nil
+	}
+	if _, err := base64.StdEncoding.DecodeString(c.Blob); err != nil {
+		return fmt.Errorf("%w: resource blob is not valid base64: %w", ErrInvalidContent, err)
+	}
+	return nil
+}
+
+// DecodeBlob returns the decoded binary data of blob resource content.
+func (c ResourceContent) DecodeBlob() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(c.Blob)
+}
+
+func validateBinaryContent(contentType, data, mimeType string) error {
+	if data == "" {
+		return fmt.Errorf("%w: %s content requires data", ErrInvalidContent, contentType)
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return fmt.Errorf("%w: %s data is not valid base64: %w", ErrInvalidContent, contentType, err)
+	}
+	if !strings.HasPrefix(mimeType, contentType+"/") {
+		return fmt.Errorf("%w: %s content has MIME type %q", ErrInvalidContent, contentType, mimeType)
+	}
+	return nil
+}
+
+func detectMimeType(data []byte, mimeType string) string {
+	if mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(data)
+}
@@ -2,6 +2,9 @@ package mcp
 
 import "context"
 
+// MethodElicitationCreate is the method servers use to ask the user for input through the client.
+const MethodElicitationCreate = "elicitation/create"
+
 // ElicitationRequest represents a request from a server to gather additional information from the user.
 //
 // This allows servers to request structured data from users with JSON schemas to validate responses.
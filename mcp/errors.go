@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors shared by handlers, the server and transports.
+//
+// Handlers should wrap these with fmt.Errorf("...: %w", err) so that callers
+// can branch on errors.Is instead of matching error strings.
+var (
+	// ErrToolNotFound indicates that the requested tool does not exist.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrResourceNotFound indicates that the requested resource does not exist.
+	ErrResourceNotFound = errors.New("resource not found")
+
+	// ErrPromptNotFound indicates that the requested prompt does not exist.
+	ErrPromptNotFound = errors.New("prompt not found")
+
+	// ErrSessionNotFound indicates that no session is known for the given ID.
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrInvalidParams indicates that request parameters are missing or malformed.
+	ErrInvalidParams = errors.New("invalid params")
+
+	// ErrTimeout indicates that a request did not complete within its deadline.
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrOverloaded indicates that the server rejected the request to protect itself.
+	ErrOverloaded = errors.New("server overloaded")
+
+	// ErrMemoryPressure indicates that the server rejected the request because it
+	// is running low on memory.
+	ErrMemoryPressure = errors.New("server under memory pressure")
+
+	// ErrUnauthorized indicates that the caller is not allowed to perform the request.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrNotInitialized indicates that the session has not completed the initialization handshake.
+	ErrNotInitialized = errors.New("session not initialized")
+
+	// ErrSessionShutdown indicates that the session has ended and accepts no further requests.
+	ErrSessionShutdown = errors.New("session shut down")
+
+	// ErrServerShutdown indicates that the server is shutting down and accepts no further requests.
+	ErrServerShutdown = errors.New("server shutting down")
+
+	// ErrInvalidContent indicates a malformed content item, e.g. image data that is not base64.
+	ErrInvalidContent = errors.New("invalid content")
+
+	// ErrInvalidRequestID indicates a request ID that is neither a string nor a number.
+	ErrInvalidRequestID = errors.New("invalid request ID")
+
+	// ErrDuplicateRequestID indicates that a request reused the ID of a request still in flight.
+	ErrDuplicateRequestID = errors.New("duplicate request ID")
+
+	// ErrMissingResponseSender indicates that the request context carries no ResponseSender.
+	ErrMissingResponseSender = errors.New("missing response sender in context")
+)
+
+// Error is a JSON-RPC error carrying its own code, message and data.
+//
+// Handlers return it, possibly wrapped, to choose the error response sent to
+// the client. Errors of other types are reported with a generic code.
+type Error struct {
+	// Code is the JSON-RPC error code, e.g. ErrorCodeResourceNotFound.
+	Code int
+
+	// Message is a short description of the error.
+	Message string
+
+	// Data contains additional information about the error.
+	Data any
+}
+
+// NewError creates an Error with the given code, message and optional data.
+func NewError(code int, message string, data any) *Error {
+	return &Error{Code: code, Message: message, Data: data}
+}
+
+// NewResourceNotFoundError creates the error for a resource that does not exist.
+func NewResourceNotFoundError(uri string) *Error {
+	return NewError(ErrorCodeResourceNotFound, "Resource not found", map[string]string{"uri": uri})
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Is reports resource-not-found errors as ErrResourceNotFound, so callers can
+// keep using errors.Is regardless of how the error was created.
+func (e *Error) Is(target error) bool {
+	return target == ErrResourceNotFound && e.Code == ErrorCodeResourceNotFound
+}
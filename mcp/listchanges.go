@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"reflect"
+	"slices"
+)
+
+// ExperimentalListChanges is the experimental capability under which clients
+// and servers agree on list_changed notifications carrying ListChanges.
+//
+// Clients declaring it in capabilities.experimental receive the names of the
+// changed items with each list_changed notification and can re-fetch only
+// those, instead of the whole list.
+const ExperimentalListChanges = "listChanges"
+
+// ListChanges are the params of a list_changed notification under the
+// ExperimentalListChanges extension. Items are identified by name, or by URI
+// for resources.
+type ListChanges struct {
+	// Added lists items that are new.
+	Added []string `json:"added,omitempty"`
+
+	// Removed lists items that no longer exist.
+	Removed []string `json:"removed,omitempty"`
+
+	// Modified lists items whose definition changed.
+	Modified []string `json:"modified,omitempty"`
+}
+
+// IsEmpty reports whether no item changed.
+func (c ListChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// DiffList returns the changes from before to after, identifying items by key.
+//
+// Example usage:
+//
+//	changes := mcp.DiffList(oldTools, newTools, func(t mcp.Tool) string { return t.Name })
+func DiffList[T any](before, after []T, key func(T) string) ListChanges {
+	previous := make(map[string]T, len(before))
+	for _, item := range before {
+		previous[key(item)] = item
+	}
+
+	var changes ListChanges
+	for _, item := range after {
+		name := key(item)
+		old, ok := previous[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case !reflect.DeepEqual(old, item):
+			changes.Modified = append(changes.Modified, name)
+		}
+		delete(previous, name)
+	}
+	for name := range previous {
+		changes.Removed = append(changes.Removed, name)
+	}
+	slices.Sort(changes.Removed)
+	return changes
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Locale describes the client's language and timezone preferences.
+//
+// Hints come from the HTTP Accept-Language header and from the "locale" and
+// "timezone" keys of the initialize request's _meta. The zero value means
+// the client expressed no preference.
+type Locale struct {
+	// Language is a canonical BCP 47 language tag such as "en-US", or empty if unknown.
+	Language string
+
+	// Location is the client's timezone, or nil if unknown.
+	Location *time.Location
+}
+
+// Timezone returns the client's timezone, defaulting to UTC.
+func (l Locale) Timezone() *time.Location {
+	if l.Location == nil {
+		return time.UTC
+	}
+	return l.Location
+}
+
+// LocaleFromContext returns the client's locale for the current request.
+func LocaleFromContext(ctx context.Context) Locale {
+	locale, _ := ctx.Value(LocaleKey).(Locale)
+	return locale
+}
+
+// NormalizeLanguage returns the canonical form of a BCP 47 language tag,
+// e.g. "en_us" becomes "en-US". Invalid tags yield an empty string.
+func NormalizeLanguage(tag string) string {
+	parsed, err := language.Parse(tag)
+	if err != nil || parsed == language.Und {
+		return ""
+	}
+	return parsed.String()
+}
+
+// ParseAcceptLanguage returns the most preferred language of an
+// Accept-Language header value in canonical form, or an empty string.
+func ParseAcceptLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return ""
+	}
+	for _, tag := range tags {
+		if tag != language.Und {
+			return tag.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package mcp
+
+const (
+	// MethodLoggingSetLevel is the method clients use to set the minimum log level.
+	MethodLoggingSetLevel = "logging/setLevel"
+
+	// NotificationMessage is sent by servers to deliver a log message to the client.
+	NotificationMessage = "notifications/message"
+)
+
+// LoggingLevel is the severity of a log message, following RFC 5424 syslog levels.
+type LoggingLevel string
+
+// Logging levels from least to most severe.
+const (
+	LoggingLevelDebug     LoggingLevel = "debug"
+	LoggingLevelInfo      LoggingLevel = "info"
+	LoggingLevelNotice    LoggingLevel = "notice"
+	LoggingLevelWarning   LoggingLevel = "warning"
+	LoggingLevelError     LoggingLevel = "error"
+	LoggingLevelCritical  LoggingLevel = "critical"
+	LoggingLevelAlert     LoggingLevel = "alert"
+	LoggingLevelEmergency LoggingLevel = "emergency"
+)
+
+// loggingSeverity orders the levels from least to most severe.
+var loggingSeverity = map[LoggingLevel]int{
+	LoggingLevelDebug:     0,
+	LoggingLevelInfo:      1,
+	LoggingLevelNotice:    2,
+	LoggingLevelWarning:   3,
+	LoggingLevelError:     4,
+	LoggingLevelCritical:  5,
+	LoggingLevelAlert:     6,
+	LoggingLevelEmergency: 7,
+}
+
+// Valid reports whether l is one of the defined logging levels.
+func (l LoggingLevel) Valid() bool {
+	_, ok := loggingSeverity[l]
+	return ok
+}
+
+// AtLeast reports whether l is at least as severe as min.
+func (l LoggingLevel) AtLeast(min LoggingLevel) bool {
+	return loggingSeverity[l] >= loggingSeverity[min]
+}
+
+// SetLevelParams contains the parameters of a logging/setLevel request.
+type SetLevelParams struct {
+	// Level is the minimum level of messages the client wants to receive.
+	Level LoggingLevel `json:"level"`
+}
+
+// LoggingMessageParams contains the parameters of a notifications/message notification.
+type LoggingMessageParams struct {
+	// Level is the severity of the message.
+	Level LoggingLevel `json:"level"`
+
+	// Logger is an optional name of the component that emitted the message.
+	Logger string `json:"logger,omitempty"`
+
+	// Data is the message itself, any JSON-serializable value.
+	Data any `json:"data"`
+}
@@ -0,0 +1,64 @@
+package mcp
+
+import "context"
+
+// ProgressParams contains the parameters of a progress notification.
+type ProgressParams struct {
+	// ProgressToken is the token the client supplied in the request's _meta.
+	ProgressToken any `json:"progressToken"`
+
+	// Progress is the progress so far. It must increase with every notification.
+	Progress float64 `json:"progress"`
+
+	// Total is the total amount of work, if known.
+	Total float64 `json:"total,omitempty"`
+
+	// Message is an optional human-readable description of the current progress.
+	Message string `json:"message,omitempty"`
+}
+
+// ProgressReporter sends progress notifications for a single request.
+//
+// A reporter is only active if the client asked for progress by supplying a
+// progressToken in the request's _meta. Otherwise Report is a no-op, so
+// handlers can report progress unconditionally.
+type ProgressReporter struct {
+	token  any
+	sender NotificationSender
+}
+
+// NewProgressReporter creates a reporter that sends notifications tied to token via sender.
+func NewProgressReporter(token any, sender NotificationSender) *ProgressReporter {
+	return &ProgressReporter{token: token, sender: sender}
+}
+
+// Report sends a notifications/progress message to the client.
+//
+// Pass 0 as total if the total amount of work is unknown.
+func (p *ProgressReporter) Report(progress, total float64, message string) error {
+	if p == nil || p.token == nil || p.sender == nil {
+		return nil
+	}
+
+	return p.sender.SendNotification(Notification{
+		JSONRPC: JSONRPCVersion,
+		Method:  NotificationProgress,
+		Params: ProgressParams{
+			ProgressToken: p.token,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		},
+	})
+}
+
+// ProgressFromContext returns the progress reporter for the current request.
+//
+// It never returns nil. If the request carries no progress token, the
+// returned reporter silently discards reports.
+func ProgressFromContext(ctx context.Context) *ProgressReporter {
+	if reporter, ok := ctx.Value(ProgressReporterKey).(*ProgressReporter); ok && reporter != nil {
+		return reporter
+	}
+	return &ProgressReporter{}
+}
@@ -10,8 +10,7 @@ type Prompt struct {
 	Name string `json:"name"`
 
 	// Title is a human-friendly display name for the prompt.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Title string `json:"title,omitempty"`
+	Title string `json:"title,omitempty"`
 
 	// Description explains what the prompt does and when to use it.
 	Description string `json:"description"`
@@ -20,8 +19,7 @@ type Prompt struct {
 	Arguments []PromptArgument `json:"arguments,omitempty"`
 
 	// Meta contains implementation-specific metadata.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Meta map[string]any `json:"_meta,omitempty"`
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // PromptArgument defines a parameter that can be passed to a prompt.
@@ -32,6 +30,9 @@ type PromptArgument struct {
 	// Name is the parameter name.
 	Name string `json:"name"`
 
+	// Title is a human-friendly display name for the parameter.
+	Title string `json:"title,omitempty"`
+
 	// Description explains what this argument is used for.
 	Description string `json:"description"`
 
@@ -71,12 +72,20 @@ type PromptMessage struct {
 
 // MessageContent contains the actual content of a prompt message.
 //
-// Content can be text or other media types. The type field indicates
-// what kind of content this is.
+// Content can be text or images. The type field indicates what kind of
+// content this is, see NewTextMessageContent and NewImageMessageContent.
 type MessageContent struct {
-	// Type indicates the content type (typically "text").
+	// Type indicates the content type ("text", "image" or "audio").
 	Type string `json:"type"`
 
 	// Text contains the text content when Type is "text".
-	Text string `json:"text"`
+	Text string `json:"text,omitempty"`
+
+	// Data contains the base64-encoded data when Type is "image" or "audio".
+	Data string `json:"data,omitempty"`
+
+	// MimeType specifies the MIME type of image or audio content, e.g. "image/png".
+	MimeType string `json:"mimeType,omitempty"`
+	// Meta contains implementation-specific metadata.
+	Meta map[string]any `json:"_meta,omitempty"`
 }
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RequestID identifies a JSON-RPC request. It is either a string or a number.
+//
+// The zero value is an absent or null ID, as carried by notifications.
+// RequestIDs are comparable with ==, keeping the string "1" and the number 1
+// distinct. Numbers that are integers within the int64 range are decoded in
+// their canonical form, so 1, 1.0 and 1e0 are the same ID; other numbers keep
+// their exact literal across JSON round-trips, so integers beyond the float64
+// range are not mangled.
+type RequestID struct {
+	// raw is the canonical JSON encoding of the ID, empty for the zero value.
+	raw string
+}
+
+// NewStringID returns a string request ID.
+func NewStringID(id string) RequestID {
+	encoded, _ := json.Marshal(id)
+	return RequestID{raw: string(encoded)}
+}
+
+// NewIntID returns a numeric request ID.
+func NewIntID(id int64) RequestID {
+	return RequestID{raw: strconv.FormatInt(id, 10)}
+}
+
+// IsZero reports whether the ID is absent or null.
+func (id RequestID) IsZero() bool {
+	return id.raw == ""
+}
+
+// IsString reports whether the ID is a string.
+func (id RequestID) IsString() bool {
+	return len(id.raw) > 0 && id.raw[0] == '"'
+}
+
+// String returns the ID for display: strings unquoted, numbers as sent and
+// "null" for the zero value.
+func (id RequestID) String() string {
+	switch {
+	case id.IsZero():
+		return "null"
+	case id.IsString():
+		var s string
+		_ = json.Unmarshal([]byte(id.raw), &s)
+		return s
+	default:
+		return id.raw
+	}
+}
+
+// MarshalJSON encodes the ID as it was received, or null for the zero value.
+func (id RequestID) MarshalJSON() ([]byte, error) {
+	if id.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(id.raw), nil
+}
+
+// UnmarshalJSON decodes a string, number or null ID. Other JSON types fail
+// with an error wrapping ErrInvalidRequestID.
+func (id *RequestID) UnmarshalJSON(data []byte) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		*id = RequestID{}
+	case string:
+		*id = NewStringID(v)
+	case json.Number:
+		*id = RequestID{raw: canonicalNumber(v)}
+	default:
+		return fmt.Errorf("%w: must be a string or number, got %s", ErrInvalidRequestID, data)
+	}
+	return nil
+}
+
+// maxIDExponent bounds the exponents of numeric IDs that are canonicalized,
+// so that an ID such as 1e999999999 cannot make decoding expensive. No
+// integer within the int64 range needs a larger one.
+const maxIDExponent = 1000
+
+// canonicalNumber returns the decimal literal of n if it is an integer within
+// the int64 range, and n as sent otherwise.
+func canonicalNumber(n json.Number) string {
+	if i, err := n.Int64(); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+
+	literal := n.String()
+	if _, exponent, ok := strings.Cut(strings.ToLower(literal), "e"); ok {
+		if e, err := strconv.Atoi(exponent); err != nil || e < -maxIDExponent || e > maxIDExponent {
+			return literal
+		}
+	}
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return literal
+	}
+	return strconv.FormatInt(r.Num().Int64(), 10)
+}
@@ -1,5 +1,7 @@
 package mcp
 
+import "encoding/json"
+
 // Resource represents a piece of data or content that can be read by the client.
 //
 // Resources provide contextual information that can be used by LLMs. They are
@@ -14,24 +16,66 @@ type Resource struct {
 	Name string `json:"name"`
 
 	// Title is a human-friendly display name for the resource.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Title string `json:"title,omitempty"`
+	Title string `json:"title,omitempty"`
+
+	// Description explains what the resource contains.
+	Description string `json:"description,omitempty"`
+
+	// MimeType indicates the MIME type of the resource, if known.
+	MimeType string `json:"mimeType,omitempty"`
+
+	// Size is the size of the raw resource content in bytes, if known.
+	// Clients can use it to decide whether to fetch large resources.
+	Size int64 `json:"size,omitempty"`
+
+	// Annotations optionally describe the audience and importance of the resource.
+	Annotations *Annotations `json:"annotations,omitempty"`
 
 	// Meta contains implementation-specific metadata.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Meta map[string]any `json:"_meta,omitempty"`
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // ResourceContent contains the actual content of a resource.
 //
 // When a resource is read, the server returns the content along with the URI
-// for identification. Content can be text, structured data, or other formats.
+// for identification. Content is either text or, if Blob is set, binary data
+// such as images or PDFs.
 type ResourceContent struct {
 	// URI identifies which resource this content belongs to.
 	URI string `json:"uri"`
 
+	// MimeType specifies the MIME type of the content, e.g. "application/pdf".
+	MimeType string `json:"mimeType,omitempty"`
+
 	// Text contains the textual content of the resource.
 	Text string `json:"text"`
+
+	// Blob contains the base64-encoded binary content of the resource.
+	// If set, Text is ignored.
+	Blob string `json:"blob,omitempty"`
+
+	// Annotations optionally describe the audience and importance of the content.
+	Annotations *Annotations `json:"annotations,omitempty"`
+	// Meta contains implementation-specific metadata.
+	Meta map[string]any `json:"_meta,omitempty"`
+}
+
+// MarshalJSON encodes the content as text or blob resource contents.
+//
+// Blob contents carry no text field, while text contents always carry one,
+// even if empty.
+func (c ResourceContent) MarshalJSON() ([]byte, error) {
+	if c.Blob == "" {
+		type textContent ResourceContent
+		return json.Marshal(textContent(c))
+	}
+	return json.Marshal(struct {
+		URI         string         `json:"uri"`
+		MimeType    string         `json:"mimeType,omitempty"`
+		Blob        string         `json:"blob"`
+		Annotations *Annotations   `json:"annotations,omitempty"`
+		Meta        map[string]any `json:"_meta,omitempty"`
+	}{c.URI, c.MimeType, c.Blob, c.Annotations, c.Meta})
 }
 
 // ResourceResponse is the response to a resource read request.
@@ -61,13 +105,11 @@ type ResourceTemplate struct {
 	// MimeType indicates the MIME type of resources created from this template.
 	MimeType string `json:"mimeType,omitempty"`
 
-	// TODO: Add back when upgrading to newer MCP spec
 	// Title is a human-friendly display name for the resource template.
-	// Title string `json:"title,omitempty"`
+	Title string `json:"title,omitempty"`
 
-	// TODO: Add back when upgrading to newer MCP spec
 	// Meta contains implementation-specific metadata.
-	// Meta map[string]any `json:"_meta,omitempty"`
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // ResourceParams contains the parameters for reading a resource.
@@ -0,0 +1,27 @@
+package mcp
+
+const (
+	// MethodRootsList is the method servers use to request the client's roots.
+	MethodRootsList = "roots/list"
+
+	// NotificationRootsListChanged is sent by clients when their roots change.
+	NotificationRootsListChanged = "notifications/roots/list_changed"
+)
+
+// Root represents a directory or file the client exposes to the server.
+//
+// Roots define the boundaries of where servers may operate, e.g. the
+// workspace folders open in an editor.
+type Root struct {
+	// URI identifies the root. Must currently be a file:// URI.
+	URI string `json:"uri"`
+
+	// Name is an optional human-readable name for the root.
+	Name string `json:"name,omitempty"`
+}
+
+// ListRootsResult is the client's response to a roots/list request.
+type ListRootsResult struct {
+	// Roots contains the client's current roots.
+	Roots []Root `json:"roots"`
+}
@@ -0,0 +1,82 @@
+package mcp
+
+// MethodSamplingCreateMessage is the method servers use to request an LLM completion from the client.
+const MethodSamplingCreateMessage = "sampling/createMessage"
+
+// SamplingMessage represents a single message in a sampling conversation.
+type SamplingMessage struct {
+	// Role indicates who is speaking ("user" or "assistant").
+	Role string `json:"role"`
+
+	// Content contains the message content.
+	Content MessageContent `json:"content"`
+}
+
+// ModelHint suggests a model to the client by (partial) name.
+type ModelHint struct {
+	// Name is a full or partial model name, e.g. "claude-3-5-sonnet" or "claude".
+	Name string `json:"name,omitempty"`
+}
+
+// ModelPreferences expresses the server's priorities for model selection.
+//
+// Clients make the final model choice, these preferences are advisory only.
+// Priorities are values between 0 and 1.
+type ModelPreferences struct {
+	// Hints are evaluated in order to select a model.
+	Hints []ModelHint `json:"hints,omitempty"`
+
+	// CostPriority indicates how important minimizing cost is.
+	CostPriority float64 `json:"costPriority,omitempty"`
+
+	// SpeedPriority indicates how important low latency is.
+	SpeedPriority float64 `json:"speedPriority,omitempty"`
+
+	// IntelligencePriority indicates how important advanced capabilities are.
+	IntelligencePriority float64 `json:"intelligencePriority,omitempty"`
+}
+
+// CreateMessageRequest contains the parameters of a sampling/createMessage request.
+//
+// Sampling allows servers to request LLM completions through the client,
+// which keeps control over model access, selection and user approval.
+type CreateMessageRequest struct {
+	// Messages is the conversation to sample from.
+	Messages []SamplingMessage `json:"messages"`
+
+	// ModelPreferences describes the server's model selection preferences.
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+
+	// SystemPrompt is an optional system prompt the client may use.
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+
+	// IncludeContext requests context from MCP servers ("none", "thisServer", "allServers").
+	IncludeContext string `json:"includeContext,omitempty"`
+
+	// Temperature is the optional sampling temperature.
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// MaxTokens is the maximum number of tokens to sample.
+	MaxTokens int `json:"maxTokens"`
+
+	// StopSequences are sequences that stop sampling.
+	StopSequences []string `json:"stopSequences,omitempty"`
+
+	// Metadata contains provider-specific parameters.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// CreateMessageResult is the client's response to a sampling/createMessage request.
+type CreateMessageResult struct {
+	// Role is the role of the sampled message, typically "assistant".
+	Role string `json:"role"`
+
+	// Content contains the sampled message content.
+	Content MessageContent `json:"content"`
+
+	// Model is the name of the model that generated the message.
+	Model string `json:"model"`
+
+	// StopReason describes why sampling stopped (e.g. "endTurn", "maxTokens").
+	StopReason string `json:"stopReason,omitempty"`
+}
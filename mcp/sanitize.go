@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// StripControlCharacters removes control characters from s.
+//
+// Tabs, newlines and carriage returns are kept since they are common in
+// legitimate text arguments. Invalid UTF-8 sequences are replaced with
+// U+FFFD so the result is always safe to encode and log.
+func StripControlCharacters(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t', r == '\n', r == '\r':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		default:
+			return r
+		}
+	}, s)
+}
+
+// SanitizeParams applies StripControlCharacters to every string in generically
+// decoded JSON params, including object keys and nested values.
+func SanitizeParams(params any) any {
+	switch v := params.(type) {
+	case string:
+		return StripControlCharacters(v)
+	case map[string]any:
+		sanitized := make(map[string]any, len(v))
+		for key, value := range v {
+			sanitized[StripControlCharacters(key)] = SanitizeParams(value)
+		}
+		return sanitized
+	case []any:
+		sanitized := make([]any, len(v))
+		for i, value := range v {
+			sanitized[i] = SanitizeParams(value)
+		}
+		return sanitized
+	default:
+		return params
+	}
+}
@@ -10,8 +10,7 @@ type Tool struct {
 	Name string `json:"name"`
 
 	// Title is a human-friendly display name for the tool.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Title string `json:"title,omitempty"`
+	Title string `json:"title,omitempty"`
 
 	// Description explains what the tool does and when to use it.
 	Description string `json:"description"`
@@ -19,9 +18,12 @@ type Tool struct {
 	// InputSchema defines the expected parameters using JSON Schema.
 	InputSchema InputSchema `json:"inputSchema"`
 
+	// OutputSchema optionally defines the structure of the tool's structured output.
+	// If set, the tool must return StructuredContent conforming to this schema.
+	OutputSchema *OutputSchema `json:"outputSchema,omitempty"`
+
 	// Meta contains implementation-specific metadata.
-	// TODO: Add back when upgrading to newer MCP spec
-	// Meta map[string]any `json:"_meta,omitempty"`
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // InputSchema defines the JSON Schema for tool input parameters.
@@ -39,6 +41,21 @@ type InputSchema struct {
 	Required []string `json:"required,omitempty"`
 }
 
+// OutputSchema defines the JSON Schema for a tool's structured output.
+//
+// Tools declaring an output schema return their result as StructuredContent,
+// which clients can validate and consume without parsing text blocks.
+type OutputSchema struct {
+	// Type is always "object" for tool output.
+	Type string `json:"type"`
+
+	// Properties defines the individual field schemas of the output.
+	Properties map[string]any `json:"properties,omitempty"`
+
+	// Required lists the field names that are always present.
+	Required []string `json:"required,omitempty"`
+}
+
 // ToolCallParams contains the parameters for calling a tool.
 type ToolCallParams struct {
 	// Name is the name of the tool to call.
@@ -55,6 +72,13 @@ type ToolCallParams struct {
 type ToolResponse struct {
 	// Content contains the output of the tool execution.
 	Content []ContentItem `json:"content"`
+
+	// StructuredContent contains the tool's output as a JSON object.
+	// It must conform to the tool's OutputSchema, if one is declared.
+	StructuredContent any `json:"structuredContent,omitempty"`
+
+	// Meta contains implementation-specific metadata about the result.
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // ContentItem represents a piece of content in a tool response.
@@ -63,13 +87,13 @@ type ToolResponse struct {
 // indicates what kind of content this is, and additional fields provide
 // the actual content data.
 type ContentItem struct {
-	// Type indicates the content type (e.g., "text", "image", "resource").
+	// Type indicates the content type (e.g., "text", "image", "audio", "resource").
 	Type string `json:"type"`
 
 	// Text contains the text content when Type is "text".
 	Text string `json:"text,omitempty"`
 
-	// Data contains the raw data when Type is "image" or other binary content.
+	// Data contains the base64-encoded data when Type is "image" or "audio".
 	Data string `json:"data,omitempty"`
 
 	// MimeType specifies the MIME type for binary content.
@@ -77,6 +101,11 @@ type ContentItem struct {
 
 	// Resource contains a reference to an MCP resource when Type is "resource".
 	Resource *ResourceReference `json:"resource,omitempty"`
+
+	// Annotations optionally describe the audience and importance of the content.
+	Annotations *Annotations `json:"annotations,omitempty"`
+	// Meta contains implementation-specific metadata.
+	Meta map[string]any `json:"_meta,omitempty"`
 }
 
 // ResourceReference represents a reference to an MCP resource in tool output.
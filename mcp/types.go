@@ -1,7 +1,7 @@
 // Package mcp provides core Model Context Protocol types and interfaces.
 //
-// This package implements the Model Context Protocol (MCP) specification version 2025-03-26,
-// enabling communication between LLM applications (hosts) and context providers (servers).
+// This package implements the Model Context Protocol (MCP) specification version 2025-06-18
+// (with backwards compatibility for 2025-03-26), enabling communication between LLM applications (hosts) and context providers (servers).
 //
 // The MCP follows a client-server architecture where:
 //   - Hosts are LLM applications that initiate connections
@@ -13,16 +13,30 @@ package mcp
 
 import (
 	"context"
+	"log/slog"
+	"slices"
 )
 
 const (
-	// ProtocolVersion defines the MCP protocol version this implementation supports.
-	ProtocolVersion = "2025-03-26"
+	// ProtocolVersion defines the latest MCP protocol version this implementation supports.
+	ProtocolVersion = "2025-06-18"
 
 	// JSONRPCVersion defines the JSON-RPC version used for all MCP communications.
 	JSONRPCVersion = "2.0"
+
+	// MethodPing is the method either side sends to check the other is still responsive.
+	MethodPing = "ping"
 )
 
+// SupportedProtocolVersions lists all MCP protocol versions this implementation
+// can negotiate, newest first.
+var SupportedProtocolVersions = []string{ProtocolVersion, "2025-03-26"}
+
+// IsSupportedProtocolVersion reports whether the given protocol version can be negotiated.
+func IsSupportedProtocolVersion(version string) bool {
+	return slices.Contains(SupportedProtocolVersions, version)
+}
+
 // Standard JSON-RPC 2.0 error codes as defined in the specification.
 const (
 	// ErrorCodeParseError indicates invalid JSON was received.
@@ -41,15 +55,57 @@ const (
 	ErrorCodeInternalError = -32603
 )
 
+// Error codes defined by the MCP specification.
+const (
+	// ErrorCodeResourceNotFound indicates that a requested resource does not exist.
+	ErrorCodeResourceNotFound = -32002
+)
+
+// Notification methods sent from the server to the client.
+const (
+	// NotificationToolsListChanged informs the client that the list of tools has changed.
+	NotificationToolsListChanged = "notifications/tools/list_changed"
+
+	// NotificationResourcesListChanged informs the client that the list of resources has changed.
+	NotificationResourcesListChanged = "notifications/resources/list_changed"
+
+	// NotificationPromptsListChanged informs the client that the list of prompts has changed.
+	NotificationPromptsListChanged = "notifications/prompts/list_changed"
+
+	// NotificationProgress reports progress of a long-running request.
+	NotificationProgress = "notifications/progress"
+
+	// NotificationCancelled is sent by either side to cancel a previously issued request.
+	NotificationCancelled = "notifications/cancelled"
+
+	// NotificationInitialized is sent by the client once it has processed the initialize result.
+	NotificationInitialized = "notifications/initialized"
+)
+
 // ServerInfo contains metadata about an MCP server implementation.
 type ServerInfo struct {
 	// Name is the human-readable name of the server.
 	Name string `json:"name"`
 
+	// Title is a human-friendly display name for the server.
+	Title string `json:"title,omitempty"`
+
 	// Version is the version of the server implementation.
 	Version string `json:"version"`
 }
 
+// ClientInfo contains metadata about an MCP client implementation.
+type ClientInfo struct {
+	// Name is the name of the client, e.g. "claude-ai".
+	Name string `json:"name"`
+
+	// Title is a human-friendly display name for the client.
+	Title string `json:"title,omitempty"`
+
+	// Version is the version of the client implementation.
+	Version string `json:"version"`
+}
+
 // InitializeResponse is sent by the server in response to an initialize request.
 // It contains the server's protocol version, capabilities, and metadata.
 type InitializeResponse struct {
@@ -57,10 +113,59 @@ type InitializeResponse struct {
 	ProtocolVersion string `json:"protocolVersion"`
 
 	// Capabilities describes what the server can do (tools, resources, prompts).
-	Capabilities map[string]any `json:"capabilities"`
+	Capabilities ServerCapabilities `json:"capabilities"`
 
 	// ServerInfo contains metadata about the server.
 	ServerInfo ServerInfo `json:"serverInfo"`
+
+	// Instructions describe how to use the server and its features. Clients
+	// may add them to the system prompt to guide the model.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ServerCapabilities lists the features a server offers. A nil field means
+// the feature is not offered.
+//
+// Fields are declared in alphabetical order of their JSON names, so the
+// marshaled payload is byte-for-byte stable and clients may hash it to
+// detect changes. Keep new fields in that order.
+type ServerCapabilities struct {
+	// Completions is set if the server completes prompt and resource template arguments.
+	Completions *struct{} `json:"completions,omitempty"`
+
+	// Elicitation is set if the server may ask the user for input.
+	Elicitation *struct{} `json:"elicitation,omitempty"`
+
+	// Experimental lists the non-standard extensions the server supports,
+	// e.g. ExperimentalListChanges. Map keys are marshaled in sorted order.
+	Experimental map[string]any `json:"experimental,omitempty"`
+
+	// Logging is set if the server sends log messages and accepts logging/setLevel.
+	Logging *struct{} `json:"logging,omitempty"`
+
+	// Prompts is set if the server offers prompts.
+	Prompts *ListChangedCapability `json:"prompts,omitempty"`
+
+	// Resources is set if the server offers resources.
+	Resources *ResourcesCapability `json:"resources,omitempty"`
+
+	// Tools is set if the server offers tools.
+	Tools *ListChangedCapability `json:"tools,omitempty"`
+}
+
+// ListChangedCapability describes a list capability such as tools or prompts.
+type ListChangedCapability struct {
+	// ListChanged is set if the server notifies clients when the list changes.
+	ListChanged bool `json:"listChanged"`
+}
+
+// ResourcesCapability describes the resources capability.
+type ResourcesCapability struct {
+	// ListChanged is set if the server notifies clients when the list changes.
+	ListChanged bool `json:"listChanged"`
+
+	// Templates is set if the server lists resource templates.
+	Templates bool `json:"templates"`
 }
 
 // Request represents a JSON-RPC 2.0 request message.
@@ -71,11 +176,12 @@ type Request struct {
 	// Method is the name of the method to be invoked.
 	Method string `json:"method"`
 
-	// ID is the request identifier. Must be string, number, or null.
+	// ID is the request identifier, a string or number.
 	// For MCP, ID MUST NOT be null per specification.
-	ID any `json:"id"`
+	ID RequestID `json:"id"`
 
 	// Params contains the parameter values to be used during method invocation.
+	// Transports pass them as json.RawMessage for the server to decode per method.
 	Params any `json:"params,omitempty"`
 }
 
@@ -85,7 +191,7 @@ type Response struct {
 	JSONRPC string `json:"jsonrpc"`
 
 	// ID must match the ID of the request being responded to.
-	ID any `json:"id"`
+	ID RequestID `json:"id"`
 
 	// Result contains the result of the method invocation.
 	// This field is required on success and must not exist if there was an error.
@@ -96,6 +202,30 @@ type Response struct {
 	Error *ErrorResponse `json:"error,omitempty"`
 }
 
+// Notification represents a JSON-RPC 2.0 notification message.
+//
+// Notifications are one-way messages that do not expect a response and
+// therefore carry no ID.
+type Notification struct {
+	// JSONRPC must be exactly "2.0" to indicate JSON-RPC 2.0.
+	JSONRPC string `json:"jsonrpc"`
+
+	// Method is the name of the notification.
+	Method string `json:"method"`
+
+	// Params contains the parameter values of the notification.
+	Params any `json:"params,omitempty"`
+}
+
+// CancelledParams contains the parameters of a notifications/cancelled message.
+type CancelledParams struct {
+	// RequestID is the ID of the request to cancel.
+	RequestID RequestID `json:"requestId"`
+
+	// Reason optionally describes why the request was cancelled.
+	Reason string `json:"reason,omitempty"`
+}
+
 // ErrorResponse represents a JSON-RPC 2.0 error object.
 type ErrorResponse struct {
 	// Code is a numeric error code indicating the type of error.
@@ -168,6 +298,17 @@ type PromptHandler interface {
 	GetPrompt(ctx context.Context, params PromptParams) (PromptResponse, error)
 }
 
+// NotificationHandler defines the interface for receiving client notifications.
+//
+// The server handles protocol notifications such as notifications/cancelled
+// itself and then passes every notification, including ones it does not
+// know, to the handler.
+type NotificationHandler interface {
+	// HandleNotification processes a notification sent by the client.
+	// Notifications have no response, so returned errors are only logged.
+	HandleNotification(ctx context.Context, notification Notification) error
+}
+
 // ResponseSender defines the interface for sending responses back to clients.
 //
 // ResponseSender abstracts the transport mechanism, allowing the same server
@@ -177,7 +318,35 @@ type ResponseSender interface {
 	SendResponse(response Response) error
 
 	// SendError sends a JSON-RPC error response with the specified error details.
-	SendError(id any, code int, message string, data any) error
+	SendError(id RequestID, code int, message string, data any) error
+}
+
+// NotificationSender defines the interface for sending notifications to clients.
+//
+// Transports implement this for channels that can carry server-initiated
+// messages, such as stdout or an open SSE stream.
+type NotificationSender interface {
+	// SendNotification sends a JSON-RPC notification to the client.
+	SendNotification(notification Notification) error
+}
+
+// RequestSender defines the interface for sending server-initiated requests to clients.
+//
+// The client's response arrives as a separate inbound message, which the
+// transport hands to the server for correlation.
+type RequestSender interface {
+	// SendRequest sends a JSON-RPC request to the client.
+	SendRequest(req Request) error
+}
+
+// SessionSender defines the interface of a session's channel for
+// server-initiated messages, which transports register with the server.
+//
+// Every channel that can carry notifications to a client can carry requests
+// to it as well, such as sampling or elicitation requests.
+type SessionSender interface {
+	NotificationSender
+	RequestSender
 }
 
 // contextKey is a custom type for context keys to avoid collisions.
@@ -189,4 +358,37 @@ const (
 
 	// SessionIDKey is the context key for accessing the session identifier.
 	SessionIDKey contextKey = "sessionID"
+
+	// LoggerKey is the context key for accessing the request-scoped logger.
+	LoggerKey contextKey = "logger"
+
+	// ProgressReporterKey is the context key for accessing the request's ProgressReporter.
+	ProgressReporterKey contextKey = "progressReporter"
+
+	// LocaleKey is the context key for accessing the client's Locale.
+	LocaleKey contextKey = "locale"
+
+	// ClientInfoKey is the context key for accessing the client's ClientInfo.
+	ClientInfoKey contextKey = "clientInfo"
 )
+
+// LoggerFromContext returns the server's logger for the current request.
+//
+// The logger is annotated with the session and request IDs, so handlers
+// can log consistently with the server. If the context carries no logger,
+// slog.Default() is returned.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(LoggerKey).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ClientInfoFromContext returns the clientInfo the client sent with initialize.
+//
+// The second return value is false if the client has not been initialized
+// within the current session.
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(ClientInfoKey).(ClientInfo)
+	return info, ok
+}
@@ -0,0 +1,346 @@
+// Package retry retries calls to flaky upstream services, so that transient
+// failures of the APIs behind bridge handlers do not surface as tool errors.
+//
+// A Policy retries with exponential backoff and full jitter, bounded by a
+// number of attempts and a time budget per call. Each upstream gets its own
+// policy and counters:
+//
+//	weather := retry.NewTransport(retry.Policy{Name: "weather"}, http.DefaultTransport)
+//	client := &http.Client{Transport: weather}
+//	...
+//	stats := weather.Stats()
+//
+// Only idempotent HTTP requests are retried by default, since repeating
+// e.g. a POST may apply its effect twice.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults applied to unset Policy fields.
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultBudget         = 30 * time.Second
+)
+
+// ErrBudgetExhausted is returned when the next retry would exceed the
+// policy's time budget. It wraps the error of the last attempt.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Policy describes how calls to an upstream are retried.
+//
+// The zero value makes up to DefaultMaxAttempts attempts within
+// DefaultBudget, waiting between DefaultInitialBackoff and
+// DefaultMaxBackoff between attempts.
+type Policy struct {
+	// Name identifies the upstream in errors.
+	Name string
+
+	// MaxAttempts caps the attempts of a call including the first one.
+	// Defaults to DefaultMaxAttempts, 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the upper bound of the wait before the first retry,
+	// doubling with every further retry. Defaults to DefaultInitialBackoff.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Defaults to DefaultMaxBackoff.
+	MaxBackoff time.Duration
+
+	// Budget bounds the total time of a call including all attempts and
+	// waits: no retry is started that would wait past it. A single attempt
+	// is bounded by the caller's context only. Defaults to DefaultBudget.
+	Budget time.Duration
+
+	// RetryNonIdempotent also retries HTTP requests with methods that are
+	// not idempotent, such as POST. Requests carrying an Idempotency-Key
+	// header are always retried.
+	RetryNonIdempotent bool
+}
+
+func (p Policy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p Policy) budget() time.Duration {
+	if p.Budget <= 0 {
+		return DefaultBudget
+	}
+	return p.Budget
+}
+
+// backoff returns the wait before the given retry, counting from 1, chosen
+// uniformly up to the exponential bound so that clients retrying at the
+// same time spread out.
+func (p Policy) backoff(retry int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	bound := maxBackoff
+	if shift := retry - 1; shift < 32 && initial<<shift < maxBackoff {
+		bound = initial << shift
+	}
+	return rand.N(bound) + 1
+}
+
+// Stats are the counters of an upstream's calls.
+type Stats struct {
+	// Calls counts the calls made through the policy.
+	Calls uint64 `json:"calls"`
+
+	// Retries counts the attempts after the first one.
+	Retries uint64 `json:"retries"`
+
+	// Recovered counts the calls that succeeded after at least one retry.
+	Recovered uint64 `json:"recovered"`
+
+	// Exhausted counts the calls that failed after using up their attempts
+	// or budget.
+	Exhausted uint64 `json:"exhausted"`
+}
+
+// counters are the atomic counterparts of Stats.
+type counters struct {
+	calls     atomic.Uint64
+	retries   atomic.Uint64
+	recovered atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+func (c *counters) stats() Stats {
+	return Stats{
+		Calls:     c.calls.Load(),
+		Retries:   c.retries.Load(),
+		Recovered: c.recovered.Load(),
+		Exhausted: c.exhausted.Load(),
+	}
+}
+
+// permanentError marks an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable, e.g. a validation error reported by
+// the upstream. Do returns the wrapped error without further attempts.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retrier retries calls to one upstream according to a policy and counts them.
+type Retrier struct {
+	policy   Policy
+	counters counters
+}
+
+// New returns a Retrier for an upstream.
+func New(p Policy) *Retrier {
+	return &Retrier{policy: p}
+}
+
+// Stats returns the counters of the upstream's calls.
+func (r *Retrier) Stats() Stats {
+	return r.counters.stats()
+}
+
+// Do calls fn until it succeeds, returns an error marked with Permanent, or
+// the policy's attempts or budget are used up. Waits end early when ctx is done.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.counters.calls.Add(1)
+
+	deadline := time.Now().Add(r.policy.budget())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				r.counters.recovered.Add(1)
+			}
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= r.policy.maxAttempts() {
+			r.counters.exhausted.Add(1)
+			return err
+		}
+
+		wait := r.policy.backoff(attempt)
+		var after *retryAfterError
+		if errors.As(err, &after) {
+			wait = max(wait, after.wait)
+		}
+		if time.Now().Add(wait).After(deadline) {
+			r.counters.exhausted.Add(1)
+			return fmt.Errorf("%w for %s after %d attempts: %w", ErrBudgetExhausted, r.name(), attempt, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.counters.exhausted.Add(1)
+			return err
+		case <-timer.C:
+		}
+		r.counters.retries.Add(1)
+	}
+}
+
+func (r *Retrier) name() string {
+	if r.policy.Name == "" {
+		return "upstream"
+	}
+	return r.policy.Name
+}
+
+// retryAfterError carries the wait an upstream asked for with a Retry-After header.
+type retryAfterError struct {
+	status int
+	wait   time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("upstream responded %d %s", e.status, http.StatusText(e.status))
+}
+
+// idempotentMethods are the HTTP methods that may be repeated safely (RFC 9110).
+var idempotentMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+	http.MethodPut, http.MethodDelete,
+}
+
+// retryableStatus reports whether a response status indicates a transient failure.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transport is an http.RoundTripper that retries failed requests to an upstream.
+//
+// Network errors and the statuses 429, 502, 503 and 504 are retried,
+// honoring Retry-After. Once the attempts are used up, the last response is
+// returned as is, so callers see the upstream's error.
+type Transport struct {
+	retrier *Retrier
+	next    http.RoundTripper
+}
+
+// NewTransport returns a Transport retrying requests passed to next.
+// A nil next uses http.DefaultTransport.
+func NewTransport(p Policy, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{retrier: New(p), next: next}
+}
+
+// Stats returns the counters of the upstream's requests.
+func (t *Transport) Stats() Stats {
+	return t.retrier.Stats()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := slices.Contains(idempotentMethods, req.Method) ||
+		t.retrier.policy.RetryNonIdempotent ||
+		req.Header.Get("Idempotency-Key") != ""
+	// Bodies must be replayable for a retry
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retryable = false
+	}
+	if !retryable {
+		return t.next.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	err := t.retrier.Do(req.Context(), func(ctx context.Context) error {
+		attempt := req.Clone(ctx)
+		if resp != nil {
+			// A retry, the failed response is no longer needed
+			drain(resp)
+			resp = nil
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return Permanent(err)
+				}
+				attempt.Body = body
+			}
+		}
+
+		var err error
+		resp, err = t.next.RoundTrip(attempt)
+		if err != nil {
+			return err
+		}
+		if !retryableStatus(resp.StatusCode) {
+			return nil
+		}
+		return &retryAfterError{status: resp.StatusCode, wait: retryAfter(resp)}
+	})
+
+	var after *retryAfterError
+	if errors.As(err, &after) {
+		// Out of attempts, hand the upstream's last response to the caller
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// retryAfter returns the wait requested by the response's Retry-After header.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// drain discards the rest of a response body so the connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	transient := errors.New("connection reset")
+	r := New(Policy{Name: "test", MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	calls := 0
+	err := r.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Expected success on the third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	if err := r.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return transient
+	}); !errors.Is(err, transient) || calls != 3 {
+		t.Errorf("Expected last error after 3 attempts, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	invalid := errors.New("invalid city")
+	if err := r.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(invalid)
+	}); err != invalid || calls != 1 {
+		t.Errorf("Expected permanent error without retries, got %v after %d calls", err, calls)
+	}
+
+	expected := Stats{Calls: 3, Retries: 4, Recovered: 1, Exhausted: 1}
+	if stats := r.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	// A retry that would wait past the budget is not started
+	slow := New(Policy{InitialBackoff: time.Hour, MaxBackoff: time.Hour, Budget: 10 * time.Millisecond})
+	if err := slow.Do(context.Background(), func(ctx context.Context) error {
+		return transient
+	}); !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, transient) {
+		t.Errorf("Expected ErrBudgetExhausted wrapping the last error, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for retry, bound := range map[int]time.Duration{1: 10 * time.Millisecond, 3: 40 * time.Millisecond, 10: 50 * time.Millisecond, 100: 50 * time.Millisecond} {
+		for range 100 {
+			if wait := p.backoff(retry); wait <= 0 || wait > bound {
+				t.Fatalf("Expected backoff of retry %d in (0, %v], got %v", retry, bound, wait)
+			}
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if hits.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer upstream.Close()
+
+	transport := NewTransport(Policy{Name: "upstream", InitialBackoff: time.Millisecond}, nil)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("Expected GET to succeed on retry, got %d after %d requests", resp.StatusCode, hits.Load())
+	}
+
+	// Repeating a POST could apply it twice
+	hits.Store(0)
+	resp, err = client.Post(upstream.URL, "text/plain", strings.NewReader("order"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Errorf("Expected POST not to be retried, got %d after %d requests", resp.StatusCode, hits.Load())
+	}
+
+	// Unless it carries an idempotency key, then the body is sent again
+	hits.Store(0)
+	req, _ := http.NewRequest(http.MethodPost, upstream.URL, strings.NewReader("order"))
+	req.Header.Set("Idempotency-Key", "order-1")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "order" {
+		t.Errorf("Expected retried POST to succeed with its body, got %d %q", resp.StatusCode, body)
+	}
+
+	if stats := transport.Stats(); stats.Calls != 2 || stats.Retries != 2 || stats.Recovered != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// errCancelledByClient is the cancellation cause for requests the client cancelled.
+var errCancelledByClient = errors.New("request cancelled by client")
+
+// inFlightKey identifies an in-flight request within a session.
+type inFlightKey struct {
+	session string
+	id      mcp.RequestID
+}
+
+func newInFlightKey(ctx context.Context, id mcp.RequestID) inFlightKey {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	return inFlightKey{session: sessionID, id: id}
+}
+
+// inFlightRequest is the registration of a single in-flight request.
+type inFlightRequest struct {
+	cancel context.CancelCauseFunc
+}
+
+// trackInFlight registers a request so it can be cancelled by the client.
+//
+// The returned context is canceled when a matching notifications/cancelled
+// arrives. The returned function must be called once the request completes,
+// which also releases the request ID for reuse. Reusing the ID of a request
+// that is still in flight within the same session fails with
+// mcp.ErrDuplicateRequestID.
+func (s *Server) trackInFlight(ctx context.Context, id mcp.RequestID) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	key := newInFlightKey(ctx, id)
+	req := &inFlightRequest{cancel: cancel}
+
+	s.inFlightMu.Lock()
+	// Requests without a session come from embedders calling HandleRequest
+	// directly, possibly for different clients, so their IDs can collide legitimately
+	if _, exists := s.inFlight[key]; exists && key.session != "" {
+		s.inFlightMu.Unlock()
+		cancel(nil)
+		s.duplicateIDs.Add(1)
+		return nil, nil, fmt.Errorf("%w: %v", mcp.ErrDuplicateRequestID, id)
+	}
+	s.inFlight[key] = req
+	s.inFlightMu.Unlock()
+
+	return ctx, func() {
+		s.inFlightMu.Lock()
+		if s.inFlight[key] == req {
+			delete(s.inFlight, key)
+		}
+		s.inFlightMu.Unlock()
+		cancel(nil)
+	}, nil
+}
+
+// DuplicateRequestIDs returns how many requests were rejected because their
+// ID was still in use by an in-flight request of the same session.
+func (s *Server) DuplicateRequestIDs() uint64 {
+	return s.duplicateIDs.Load()
+}
+
+// cancelInFlight cancels the in-flight request with the given ID, if any.
+func (s *Server) cancelInFlight(ctx context.Context, id mcp.RequestID) bool {
+	key := newInFlightKey(ctx, id)
+
+	s.inFlightMu.Lock()
+	req, ok := s.inFlight[key]
+	s.inFlightMu.Unlock()
+
+	if ok {
+		req.cancel(errCancelledByClient)
+	}
+	return ok
+}
+
+// isCancelledByClient reports whether the request was cancelled via notifications/cancelled.
+func isCancelledByClient(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errCancelledByClient)
+}
+
+func (s *Server) handleCancelled(ctx context.Context, params any) error {
+	raw, ok := params.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+		}
+	}
+
+	var cancelled mcp.CancelledParams
+	if err := json.Unmarshal(raw, &cancelled); err != nil {
+		return fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+	}
+	if cancelled.RequestID.IsZero() {
+		return fmt.Errorf("%w: requestId is required", mcp.ErrInvalidParams)
+	}
+	if s.config.sanitizeInput {
+		cancelled.Reason = mcp.StripControlCharacters(cancelled.Reason)
+	}
+
+	if s.cancelInFlight(ctx, cancelled.RequestID) {
+		s.logger.Debug("Request cancelled by client", "request_id", cancelled.RequestID, "reason", cancelled.Reason)
+	} else {
+		// The request may already have completed, which is not an error per spec
+		s.logger.Debug("Cancellation for unknown or completed request", "request_id", cancelled.RequestID)
+	}
+	return nil
+}
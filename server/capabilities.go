@@ -0,0 +1,63 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// hasTools reports whether the server offers tools, from its handler or built in.
+func (s *Server) hasTools() bool {
+	return s.toolHandler != nil || s.config.sessionInfoTool
+}
+
+// hasResources reports whether the server offers resources, from its handler or built in.
+func (s *Server) hasResources() bool {
+	return s.resourceHandler != nil || s.config.toolDocs
+}
+
+func (s *Server) hasPrompts() bool {
+	return s.promptHandler != nil
+}
+
+// capabilities returns the capabilities advertised on initialize, derived
+// from the handlers and options the server was created with.
+func (s *Server) capabilities() mcp.ServerCapabilities {
+	capabilities := mcp.ServerCapabilities{
+		Elicitation: &struct{}{},
+		Logging:     &struct{}{},
+	}
+	if s.hasTools() {
+		capabilities.Tools = &mcp.ListChangedCapability{ListChanged: true}
+	}
+	if s.hasResources() {
+		capabilities.Resources = &mcp.ResourcesCapability{ListChanged: true, Templates: true}
+	}
+	if s.hasPrompts() {
+		capabilities.Prompts = &mcp.ListChangedCapability{ListChanged: true}
+	}
+	if s.config.completionHandler != nil {
+		capabilities.Completions = &struct{}{}
+	}
+	if s.hasTools() || s.hasResources() || s.hasPrompts() {
+		capabilities.Experimental = map[string]any{mcp.ExperimentalListChanges: map[string]any{}}
+	}
+	return capabilities
+}
+
+// supportsMethod reports whether method belongs to an advertised capability.
+// Methods of capabilities the server does not offer are unknown to clients.
+func (s *Server) supportsMethod(method string) bool {
+	switch {
+	case strings.HasPrefix(method, "tools/"):
+		return s.hasTools()
+	case strings.HasPrefix(method, "resources/"):
+		return s.hasResources()
+	case strings.HasPrefix(method, "prompts/"):
+		return s.hasPrompts()
+	case method == mcp.MethodCompletionComplete:
+		return s.config.completionHandler != nil
+	default:
+		return true
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// clientState holds what the server learned about a client during its session.
+//
+// It is keyed by session ID and kept independently of registered sessions,
+// since plain HTTP clients may never open a notification stream. EndSession
+// releases it.
+type clientState struct {
+	phase           sessionPhase
+	info            mcp.ClientInfo
+	capabilities    map[string]any
+	protocolVersion string
+	locale          mcp.Locale
+	logLevel        mcp.LoggingLevel
+
+	// requests counts the session's requests being handled, which keep the
+	// state after EndSession until they finish, see beginSessionRequest
+	requests int
+}
+
+// initialized reports whether the client has sent initialize, after which
+// its info is reported to handlers.
+func (c *clientState) initialized() bool {
+	return c.phase >= phaseInitializing
+}
+
+// recordClientState stores what an initialize request tells about the client behind ctx.
+func (s *Server) recordClientState(ctx context.Context, params any) {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	if sessionID == "" {
+		return
+	}
+
+	paramsMap, _ := params.(map[string]any)
+	var info mcp.ClientInfo
+	if clientInfo, ok := paramsMap["clientInfo"].(map[string]any); ok {
+		info.Name, _ = clientInfo["name"].(string)
+		info.Title, _ = clientInfo["title"].(string)
+		info.Version, _ = clientInfo["version"].(string)
+	}
+	capabilities, _ := paramsMap["capabilities"].(map[string]any)
+	protocolVersion := negotiateProtocolVersion(params)
+	locale := parseLocaleHints(ctx, params)
+
+	s.updateClientState(sessionID, func(state *clientState) {
+		// An initialize racing EndSession must not restart the ending session
+		if state.phase != phaseShutdown {
+			state.phase = phaseInitializing
+		}
+		state.info = info
+		state.capabilities = capabilities
+		state.protocolVersion = protocolVersion
+		state.locale = locale
+	})
+}
+
+// ClientSupportsSampling reports whether the client behind ctx declared the
+// sampling capability, i.e. whether RequestSampling can succeed.
+func (s *Server) ClientSupportsSampling(ctx context.Context) bool {
+	return s.clientHasCapability(ctx, "sampling")
+}
+
+// ClientSupportsRoots reports whether the client behind ctx declared the
+// roots capability, i.e. whether ListRoots can succeed.
+func (s *Server) ClientSupportsRoots(ctx context.Context) bool {
+	return s.clientHasCapability(ctx, "roots")
+}
+
+// ClientProtocolVersion returns the protocol version negotiated with the
+// client behind ctx, or an empty string if it has not been initialized.
+func (s *Server) ClientProtocolVersion(ctx context.Context) string {
+	if state := s.clientState(ctx); state != nil {
+		return state.protocolVersion
+	}
+	return ""
+}
+
+func (s *Server) clientHasCapability(ctx context.Context, name string) bool {
+	state := s.clientState(ctx)
+	if state == nil {
+		return false
+	}
+	_, ok := state.capabilities[name]
+	return ok
+}
+
+// clientSupportsListChanges reports whether the client of the session
+// declared the mcp.ExperimentalListChanges capability.
+func (s *Server) clientSupportsListChanges(sessionID string) bool {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	state, ok := s.clients[sessionID]
+	if !ok {
+		return false
+	}
+	experimental, _ := state.capabilities["experimental"].(map[string]any)
+	_, ok = experimental[mcp.ExperimentalListChanges]
+	return ok
+}
+
+// updateClientState applies update to the session's state, creating it if needed.
+func (s *Server) updateClientState(sessionID string, update func(*clientState)) {
+	if sessionID == "" {
+		return
+	}
+
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	state, ok := s.clients[sessionID]
+	if !ok {
+		state = &clientState{}
+		s.clients[sessionID] = state
+	}
+	update(state)
+}
+
+// clientState returns a snapshot of the state of the session behind ctx, or nil.
+func (s *Server) clientState(ctx context.Context) *clientState {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	state, ok := s.clients[sessionID]
+	if !ok {
+		return nil
+	}
+	snapshot := *state
+	return &snapshot
+}
+
+// withClientContext adds what is known about the client to the request context.
+//
+// Locale hints sent with initialize take precedence over those the transport
+// derived from the request, such as the HTTP Accept-Language header.
+func (s *Server) withClientContext(ctx context.Context) context.Context {
+	locale := mcp.LocaleFromContext(ctx)
+	state := s.clientState(ctx)
+	if state != nil {
+		if state.locale.Language != "" {
+			locale.Language = state.locale.Language
+		}
+		if state.locale.Location != nil {
+			locale.Location = state.locale.Location
+		}
+	}
+	ctx = context.WithValue(ctx, mcp.LocaleKey, locale)
+
+	if state != nil && state.initialized() {
+		ctx = context.WithValue(ctx, mcp.ClientInfoKey, state.info)
+	}
+	return ctx
+}
+
+// parseLocaleHints extracts the locale and timezone hints from initialize params.
+//
+// Invalid hints are logged and ignored rather than failing initialization.
+func parseLocaleHints(ctx context.Context, params any) mcp.Locale {
+	var locale mcp.Locale
+
+	paramsMap, _ := params.(map[string]any)
+	meta, _ := paramsMap["_meta"].(map[string]any)
+	if meta == nil {
+		return locale
+	}
+
+	if tag, ok := meta["locale"].(string); ok {
+		if locale.Language = mcp.NormalizeLanguage(tag); locale.Language == "" {
+			mcp.LoggerFromContext(ctx).Debug("Ignoring invalid locale hint", "locale", tag)
+		}
+	}
+
+	if name, ok := meta["timezone"].(string); ok {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			mcp.LoggerFromContext(ctx).Debug("Ignoring invalid timezone hint", "timezone", name, "error", err)
+		} else {
+			locale.Location = location
+		}
+	}
+
+	return locale
+}
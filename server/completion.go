@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// WithCompletionHandler enables the completion/complete method.
+//
+// The completions capability is only advertised when a handler is set.
+func WithCompletionHandler(handler mcp.CompletionHandler) Option {
+	return func(cfg *serverConfig) {
+		cfg.completionHandler = handler
+	}
+}
+
+func (s *Server) handleCompletionComplete(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
+	handler := s.config.completionHandler
+	if handler == nil {
+		return s.sendError(ctx, id, mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method %s not found", req.Method), nil)
+	}
+
+	params, err := s.parseCompleteParams(req.Params)
+	if err != nil {
+		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid completion parameters", err.Error())
+	}
+
+	response, err := handler.Complete(ctx, params)
+	if err != nil {
+		mcp.LoggerFromContext(ctx).Error("Completion failed", "ref", params.Ref.Type, "argument", params.Argument.Name, "error", err)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Completion failed: %s", err.Error()), nil)
+	}
+
+	// Per spec, responses carry at most 100 values
+	if len(response.Completion.Values) > mcp.MaxCompletionValues {
+		if response.Completion.Total == 0 {
+			response.Completion.Total = len(response.Completion.Values)
+		}
+		response.Completion.Values = response.Completion.Values[:mcp.MaxCompletionValues]
+		response.Completion.HasMore = true
+	}
+	if response.Completion.Values == nil {
+		response.Completion.Values = []string{}
+	}
+
+	return s.sendResponse(ctx, id, response)
+}
+
+func (s *Server) parseCompleteParams(params any) (mcp.CompleteParams, error) {
+	if params == nil {
+		return mcp.CompleteParams{}, fmt.Errorf("%w: params cannot be nil", mcp.ErrInvalidParams)
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return mcp.CompleteParams{}, fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+	}
+
+	var result mcp.CompleteParams
+	if err := json.Unmarshal(data, &result); err != nil {
+		return mcp.CompleteParams{}, fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+	}
+
+	switch result.Ref.Type {
+	case mcp.CompletionRefPrompt:
+		if result.Ref.Name == "" {
+			return mcp.CompleteParams{}, fmt.Errorf("%w: ref.name is required for %s", mcp.ErrInvalidParams, mcp.CompletionRefPrompt)
+		}
+	case mcp.CompletionRefResource:
+		if result.Ref.URI == "" {
+			return mcp.CompleteParams{}, fmt.Errorf("%w: ref.uri is required for %s", mcp.ErrInvalidParams, mcp.CompletionRefResource)
+		}
+	default:
+		return mcp.CompleteParams{}, fmt.Errorf("%w: unsupported ref type %q", mcp.ErrInvalidParams, result.Ref.Type)
+	}
+
+	if result.Argument.Name == "" {
+		return mcp.CompleteParams{}, fmt.Errorf("%w: argument.name is required", mcp.ErrInvalidParams)
+	}
+
+	return result, nil
+}
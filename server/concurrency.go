@@ -0,0 +1,129 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveConcurrency configures the adaptive per-tool concurrency limiter.
+//
+// The limiter follows an AIMD (additive increase, multiplicative decrease)
+// scheme: every successful call that completes below LatencyThreshold raises
+// the limit by roughly one per limit's worth of calls, while every failed or
+// slow call multiplies the limit by BackoffRatio. Calls beyond the current
+// limit are rejected immediately instead of piling up on a struggling tool.
+type AdaptiveConcurrency struct {
+	// InitialLimit is the starting number of concurrent calls per tool. Defaults to 10.
+	InitialLimit int
+
+	// MinLimit is the lower bound of the limit. Defaults to 1.
+	MinLimit int
+
+	// MaxLimit is the upper bound of the limit. Defaults to 100.
+	MaxLimit int
+
+	// LatencyThreshold marks calls slower than this as congestion. Defaults to 1s.
+	LatencyThreshold time.Duration
+
+	// BackoffRatio is the factor applied to the limit on congestion. Defaults to 0.9.
+	BackoffRatio float64
+}
+
+// WithAdaptiveConcurrency enables adaptive concurrency limiting of tools/call per tool.
+//
+// Only tool calls are limited. Protocol housekeeping such as initialize, ping
+// and notifications/cancelled always bypasses the limiter, so clients can
+// verify liveness and cancel work even while every tool is saturated.
+func WithAdaptiveConcurrency(cfg AdaptiveConcurrency) Option {
+	return func(c *serverConfig) {
+		if cfg.InitialLimit <= 0 {
+			cfg.InitialLimit = 10
+		}
+		if cfg.MinLimit <= 0 {
+			cfg.MinLimit = 1
+		}
+		if cfg.MaxLimit <= 0 {
+			cfg.MaxLimit = 100
+		}
+		if cfg.LatencyThreshold <= 0 {
+			cfg.LatencyThreshold = time.Second
+		}
+		if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+			cfg.BackoffRatio = 0.9
+		}
+		cfg.MinLimit = min(cfg.MinLimit, cfg.MaxLimit)
+		cfg.InitialLimit = max(cfg.MinLimit, min(cfg.InitialLimit, cfg.MaxLimit))
+		c.adaptiveConcurrency = &cfg
+	}
+}
+
+// adaptiveLimiter holds one AIMD limit per tool.
+type adaptiveLimiter struct {
+	cfg AdaptiveConcurrency
+
+	mu    sync.Mutex
+	tools map[string]*toolLimit
+}
+
+type toolLimit struct {
+	limit    float64
+	inFlight int
+}
+
+func newAdaptiveLimiter(cfg AdaptiveConcurrency) *adaptiveLimiter {
+	return &adaptiveLimiter{cfg: cfg, tools: make(map[string]*toolLimit)}
+}
+
+// acquire reserves a slot for the tool. It returns false if the tool is at its limit.
+// On success the returned function must be called with the call's outcome.
+func (l *adaptiveLimiter) acquire(tool string) (func(latency time.Duration, failed bool), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tl, ok := l.tools[tool]
+	if !ok {
+		tl = &toolLimit{limit: float64(l.cfg.InitialLimit)}
+		l.tools[tool] = tl
+	}
+
+	if tl.inFlight >= int(math.Floor(tl.limit)) {
+		return nil, false
+	}
+	tl.inFlight++
+
+	return func(latency time.Duration, failed bool) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		tl.inFlight--
+		if failed || latency > l.cfg.LatencyThreshold {
+			tl.limit = math.Max(float64(l.cfg.MinLimit), tl.limit*l.cfg.BackoffRatio)
+		} else {
+			tl.limit = math.Min(float64(l.cfg.MaxLimit), tl.limit+1/tl.limit)
+		}
+	}, true
+}
+
+// limit returns the current limit of the tool.
+func (l *adaptiveLimiter) limit(tool string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tl, ok := l.tools[tool]; ok {
+		return int(math.Floor(tl.limit))
+	}
+	return l.cfg.InitialLimit
+}
+
+// snapshot returns the current limit and in-flight calls of every tool seen so far.
+func (l *adaptiveLimiter) snapshot() map[string]ToolConcurrency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tools := make(map[string]ToolConcurrency, len(l.tools))
+	for name, tl := range l.tools {
+		tools[name] = ToolConcurrency{Limit: int(math.Floor(tl.limit)), InFlight: tl.inFlight}
+	}
+	return tools
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// sessionPhase is a step of the MCP lifecycle of a client session.
+type sessionPhase int
+
+const (
+	// phaseUninitialized is the phase of sessions that never sent initialize.
+	phaseUninitialized sessionPhase = iota
+
+	// phaseInitializing follows initialize until notifications/initialized arrives.
+	phaseInitializing
+
+	// phaseInitialized is normal operation.
+	phaseInitialized
+
+	// phaseShutdown follows EndSession until the requests in flight finish,
+	// no further requests are served.
+	phaseShutdown
+)
+
+func (p sessionPhase) String() string {
+	switch p {
+	case phaseUninitialized:
+		return "uninitialized"
+	case phaseInitializing:
+		return "initializing"
+	case phaseInitialized:
+		return "initialized"
+	case phaseShutdown:
+		return "shutdown"
+	default:
+		return fmt.Sprintf("sessionPhase(%d)", int(p))
+	}
+}
+
+// requiresInitialization reports whether a method may only be called after
+// the client sent notifications/initialized. Pings, logging and the
+// handshake itself are allowed at any time.
+func requiresInitialization(method string) bool {
+	return strings.HasPrefix(method, "tools/") ||
+		strings.HasPrefix(method, "resources/") ||
+		strings.HasPrefix(method, "prompts/") ||
+		method == mcp.MethodCompletionComplete
+}
+
+// EndSession moves a session to its final lifecycle phase.
+//
+// Transports call this when a client session is over for good (e.g. stdin
+// closed). Requests arriving while the session's requests in flight finish
+// are rejected. Then the session's state, such as the client's info and
+// cached roots, is released, and a later initialize with the same session ID
+// starts a new session, as when the stdio transport is started again.
+func (s *Server) EndSession(id string) {
+	s.clientsMu.Lock()
+	if state, ok := s.clients[id]; ok {
+		state.phase = phaseShutdown
+		if state.requests == 0 {
+			delete(s.clients, id)
+		}
+	}
+	s.clientsMu.Unlock()
+
+	s.forgetRoots(id)
+	s.stats.sessionsClosed.Add(1)
+	s.logger.Debug("Session ended", "session", id)
+}
+
+// beginSessionRequest counts a request of the session behind ctx as being
+// handled, so that EndSession keeps the session's state until it finishes.
+// The returned function must be called once the request is handled.
+func (s *Server) beginSessionRequest(ctx context.Context) func() {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+
+	s.clientsMu.Lock()
+	state, ok := s.clients[sessionID]
+	if ok {
+		state.requests++
+	}
+	s.clientsMu.Unlock()
+	if !ok {
+		return func() {}
+	}
+
+	return func() {
+		s.clientsMu.Lock()
+		defer s.clientsMu.Unlock()
+
+		state.requests--
+		if state.requests == 0 && state.phase == phaseShutdown && s.clients[sessionID] == state {
+			delete(s.clients, sessionID)
+		}
+	}
+}
+
+// checkLifecycle rejects requests that are not allowed in the session's current phase.
+//
+// Every built-in transport assigns a session ID, so only requests passed to
+// HandleRequest directly by an embedder, such as a custom transport, lack
+// one. They cannot be tracked and are always allowed.
+func (s *Server) checkLifecycle(ctx context.Context, method string) error {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	if sessionID == "" {
+		return nil
+	}
+
+	phase := phaseUninitialized
+	if state := s.clientState(ctx); state != nil {
+		phase = state.phase
+	}
+
+	switch {
+	case phase == phaseShutdown:
+		return mcp.ErrSessionShutdown
+	case phase != phaseInitialized && requiresInitialization(method):
+		return fmt.Errorf("%w: %s is not allowed while the session is %s", mcp.ErrNotInitialized, method, phase)
+	default:
+		return nil
+	}
+}
+
+// handleInitialized completes the handshake of the notifying session and
+// runs the OnInitialized hook, see WithOnInitialized.
+func (s *Server) handleInitialized(ctx context.Context) {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	completed := false
+	// Unlike updateClientState, this does not revive the state of an ended session
+	s.clientsMu.Lock()
+	if state, ok := s.clients[sessionID]; ok && state.phase == phaseInitializing {
+		state.phase = phaseInitialized
+		completed = true
+	}
+	s.clientsMu.Unlock()
+	if !completed {
+		// Repeated or premature notifications must not run the hook again
+		s.logger.Debug("Ignoring initialized notification", "session", sessionID)
+		return
+	}
+	s.logger.Debug("Client initialized", "session", sessionID)
+
+	if s.config.onInitialized == nil {
+		return
+	}
+	// The notification's context may end with the transport request that carried it
+	hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.requestTimeout)
+	go func() {
+		defer cancel()
+		s.config.onInitialized(hookCtx)
+	}()
+}
+
+// checkClientRequest rejects server-initiated requests to a session that has
+// not completed the handshake. Contexts without a session ID, which only
+// embedders calling the server directly pass, are allowed like in
+// checkLifecycle.
+func (s *Server) checkClientRequest(ctx context.Context, method string) error {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	if sessionID == "" {
+		return nil
+	}
+
+	phase := phaseUninitialized
+	if state := s.clientState(ctx); state != nil {
+		phase = state.phase
+	}
+
+	switch phase {
+	case phaseInitialized:
+		return nil
+	case phaseShutdown:
+		return mcp.ErrSessionShutdown
+	default:
+		return fmt.Errorf("%w: cannot send %s while the session is %s", mcp.ErrNotInitialized, method, phase)
+	}
+}
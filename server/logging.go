@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// defaultClientLogLevel applies until a client sends logging/setLevel.
+const defaultClientLogLevel = mcp.LoggingLevelInfo
+
+// LogToClient sends a log message to the client behind ctx via notifications/message.
+//
+// Messages below the level the client set with logging/setLevel are dropped
+// silently. Until the client sets a level, info and above are sent.
+func (s *Server) LogToClient(ctx context.Context, level mcp.LoggingLevel, logger string, data any) error {
+	if !level.Valid() {
+		return fmt.Errorf("%w: invalid logging level %q", mcp.ErrInvalidParams, level)
+	}
+	if !level.AtLeast(s.clientLogLevel(ctx)) {
+		return nil
+	}
+
+	notifier := s.notifier(ctx)
+	if notifier == nil {
+		sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+		return fmt.Errorf("%w: %q", mcp.ErrSessionNotFound, sessionID)
+	}
+
+	return notifier.SendNotification(mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationMessage,
+		Params: mcp.LoggingMessageParams{
+			Level:  level,
+			Logger: logger,
+			Data:   data,
+		},
+	})
+}
+
+func (s *Server) clientLogLevel(ctx context.Context) mcp.LoggingLevel {
+	if state := s.clientState(ctx); state != nil && state.logLevel != "" {
+		return state.logLevel
+	}
+	return defaultClientLogLevel
+}
+
+func (s *Server) handleLoggingSetLevel(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
+	paramsMap, ok := req.Params.(map[string]any)
+	if !ok {
+		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid logging parameters",
+			fmt.Sprintf("%s: params must be an object", mcp.ErrInvalidParams))
+	}
+	levelStr, _ := paramsMap["level"].(string)
+	level := mcp.LoggingLevel(levelStr)
+	if !level.Valid() {
+		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid logging parameters",
+			fmt.Sprintf("%s: invalid logging level %q", mcp.ErrInvalidParams, levelStr))
+	}
+
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	s.updateClientState(sessionID, func(state *clientState) {
+		state.logLevel = level
+	})
+
+	mcp.LoggerFromContext(ctx).Debug("Client log level set", "level", level)
+	return s.sendResponse(ctx, id, map[string]any{})
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// LogPolicy controls how verbosely individual requests are logged.
+//
+// It allows debug logging in production without logging every request:
+// SampleEvery logs every Nth request at debug level, and MethodLevels
+// overrides the level for specific methods, e.g. debug only for tools/call.
+// Errors are always logged. The policy can be changed at runtime with
+// SetLogPolicy, e.g. through the admin listener of the HTTP transport.
+type LogPolicy struct {
+	// SampleEvery logs every Nth request at debug level. 0 disables sampling.
+	SampleEvery int `json:"sampleEvery"`
+
+	// MethodLevels maps methods to the minimum level logged for their
+	// requests, using slog level names such as "debug" or "warn".
+	MethodLevels map[string]string `json:"methodLevels,omitempty"`
+}
+
+// WithLogPolicy sets the initial per-request log policy.
+func WithLogPolicy(policy LogPolicy) Option {
+	return func(cfg *serverConfig) {
+		cfg.logPolicy = policy
+	}
+}
+
+// logPolicy is the compiled, concurrency-safe form of a LogPolicy.
+type logPolicy struct {
+	mu           sync.RWMutex
+	sampleEvery  uint64
+	methodLevels map[string]slog.Level
+	requests     atomic.Uint64
+}
+
+func (p LogPolicy) compile() (uint64, map[string]slog.Level, error) {
+	if p.SampleEvery < 0 {
+		return 0, nil, fmt.Errorf("invalid log sampling rate: %d (must not be negative)", p.SampleEvery)
+	}
+
+	levels := make(map[string]slog.Level, len(p.MethodLevels))
+	for method, name := range p.MethodLevels {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			return 0, nil, fmt.Errorf("invalid log level %q for method %s: %w", name, method, err)
+		}
+		levels[method] = level
+	}
+	return uint64(p.SampleEvery), levels, nil
+}
+
+func newLogPolicy(policy LogPolicy) (*logPolicy, error) {
+	sampleEvery, levels, err := policy.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &logPolicy{sampleEvery: sampleEvery, methodLevels: levels}, nil
+}
+
+// LogPolicy returns the current per-request log policy.
+func (s *Server) LogPolicy() LogPolicy {
+	s.logPolicy.mu.RLock()
+	defer s.logPolicy.mu.RUnlock()
+
+	policy := LogPolicy{SampleEvery: int(s.logPolicy.sampleEvery)}
+	if len(s.logPolicy.methodLevels) > 0 {
+		policy.MethodLevels = make(map[string]string, len(s.logPolicy.methodLevels))
+		for method, level := range s.logPolicy.methodLevels {
+			policy.MethodLevels[method] = level.String()
+		}
+	}
+	return policy
+}
+
+// SetLogPolicy replaces the per-request log policy at runtime and persists
+// it if a settings file is configured, see WithSettingsFile.
+func (s *Server) SetLogPolicy(policy LogPolicy) error {
+	sampleEvery, levels, err := policy.compile()
+	if err != nil {
+		return err
+	}
+
+	s.logPolicy.mu.Lock()
+	s.logPolicy.sampleEvery = sampleEvery
+	s.logPolicy.methodLevels = levels
+	s.logPolicy.mu.Unlock()
+	return s.saveSettings()
+}
+
+// requestHandler wraps handler with the level that applies to a request of the given method.
+func (p *logPolicy) requestHandler(handler slog.Handler, method string) slog.Handler {
+	p.mu.RLock()
+	level, override := p.methodLevels[method]
+	sampleEvery := p.sampleEvery
+	p.mu.RUnlock()
+
+	switch {
+	case override:
+		return &requestLevelHandler{Handler: handler, level: level}
+	case sampleEvery > 0 && p.requests.Add(1)%sampleEvery == 0:
+		return &requestLevelHandler{Handler: handler, level: slog.LevelDebug}
+	default:
+		return handler
+	}
+}
+
+// requestLevelHandler applies a request-specific minimum level.
+//
+// It replaces the level check of the wrapped handler, so it can both raise
+// and lower the server's level. Errors are always logged.
+type requestLevelHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h *requestLevelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level || level >= slog.LevelError
+}
+
+func (h *requestLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestLevelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *requestLevelHandler) WithGroup(name string) slog.Handler {
+	return &requestLevelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryCheckInterval bounds how often memory is sampled when no
+// CheckInterval is set.
+const DefaultMemoryCheckInterval = time.Second
+
+// MemoryPressure configures load shedding under memory pressure, see
+// WithMemoryPressure.
+//
+// Memory in use is measured like the runtime's soft memory limit does: all
+// memory mapped by the Go runtime minus heap memory returned to the OS.
+type MemoryPressure struct {
+	// Threshold is the memory in use, in bytes, above which the server is
+	// under pressure. 0 disables the absolute threshold.
+	Threshold uint64
+
+	// LimitRatio puts the server under pressure once memory in use reaches
+	// this fraction of the soft memory limit set with GOMEMLIMIT or
+	// debug.SetMemoryLimit, e.g. 0.9. 0 disables it, as does running
+	// without a limit.
+	LimitRatio float64
+
+	// CheckInterval bounds how often memory is sampled. Requests in between
+	// reuse the last sample. Defaults to DefaultMemoryCheckInterval.
+	CheckInterval time.Duration
+
+	// OnChange is called when the server comes under or recovers from memory
+	// pressure, e.g. to page an operator. It runs synchronously on the
+	// request path and should return quickly.
+	OnChange func(ctx context.Context, status MemoryStatus)
+}
+
+// MemoryStatus is a sample of the server's memory use.
+type MemoryStatus struct {
+	// InUse is the memory in use in bytes.
+	InUse uint64 `json:"inUse"`
+
+	// Threshold is the effective threshold in bytes, the lower of
+	// MemoryPressure.Threshold and the share of the soft memory limit.
+	Threshold uint64 `json:"threshold"`
+
+	UnderPressure bool `json:"underPressure"`
+}
+
+// WithMemoryPressure rejects tool calls and resource reads while memory in
+// use is above a threshold, so bursts of requests with large content cannot
+// get the server killed for running out of memory.
+//
+// Rejected requests fail with mcp.ErrMemoryPressure. Lightweight requests
+// such as listings and ping are still served, as are requests already running.
+func WithMemoryPressure(cfg MemoryPressure) Option {
+	return func(c *serverConfig) {
+		if cfg.CheckInterval <= 0 {
+			cfg.CheckInterval = DefaultMemoryCheckInterval
+		}
+		c.memoryPressure = &cfg
+	}
+}
+
+func (cfg MemoryPressure) validate() error {
+	if cfg.LimitRatio < 0 || cfg.LimitRatio > 1 {
+		return fmt.Errorf("invalid memory limit ratio: %v (must be between 0 and 1)", cfg.LimitRatio)
+	}
+	if cfg.Threshold == 0 && cfg.LimitRatio == 0 {
+		return fmt.Errorf("memory pressure requires a threshold or a limit ratio")
+	}
+	return nil
+}
+
+// memoryMonitor samples memory in use and tracks whether the server is
+// under pressure. A nil monitor never reports pressure.
+type memoryMonitor struct {
+	cfg    MemoryPressure
+	logger *slog.Logger
+
+	// inUse and memoryLimit read the memory in use and the soft memory limit.
+	inUse       func() uint64
+	memoryLimit func() int64
+
+	mu      sync.Mutex
+	sampled time.Time
+	status  MemoryStatus
+}
+
+func newMemoryMonitor(cfg *MemoryPressure, logger *slog.Logger) *memoryMonitor {
+	if cfg == nil {
+		return nil
+	}
+	return &memoryMonitor{
+		cfg:         *cfg,
+		logger:      logger,
+		inUse:       readMemoryInUse,
+		memoryLimit: func() int64 { return debug.SetMemoryLimit(-1) },
+	}
+}
+
+// check returns whether the server is under memory pressure, sampling
+// memory if the last sample is older than the check interval.
+func (m *memoryMonitor) check(ctx context.Context) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	if time.Since(m.sampled) < m.cfg.CheckInterval {
+		defer m.mu.Unlock()
+		return m.status.UnderPressure
+	}
+
+	previous := m.status
+	m.sampled = time.Now()
+	m.status = m.sample()
+	status := m.status
+	m.mu.Unlock()
+
+	if status.UnderPressure != previous.UnderPressure {
+		if status.UnderPressure {
+			m.logger.Warn("Server under memory pressure, rejecting tool calls and resource reads", "inUse", status.InUse, "threshold", status.Threshold)
+		} else {
+			m.logger.Info("Server recovered from memory pressure", "inUse", status.InUse, "threshold", status.Threshold)
+		}
+		if m.cfg.OnChange != nil {
+			m.cfg.OnChange(ctx, status)
+		}
+	}
+	return status.UnderPressure
+}
+
+func (m *memoryMonitor) sample() MemoryStatus {
+	threshold := m.cfg.Threshold
+	if limit := m.memoryLimit(); m.cfg.LimitRatio > 0 && limit > 0 && limit < math.MaxInt64 {
+		share := uint64(float64(limit) * m.cfg.LimitRatio)
+		if threshold == 0 || share < threshold {
+			threshold = share
+		}
+	}
+
+	status := MemoryStatus{InUse: m.inUse(), Threshold: threshold}
+	status.UnderPressure = threshold > 0 && status.InUse >= threshold
+	return status
+}
+
+// memoryMetrics are the runtime metrics the soft memory limit is enforced on.
+var memoryMetrics = []string{
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// readMemoryInUse returns the memory mapped by the runtime minus the heap
+// memory released to the OS. Unlike runtime.ReadMemStats, reading metrics
+// does not stop the world.
+func readMemoryInUse() uint64 {
+	samples := make([]metrics.Sample, len(memoryMetrics))
+	for i, name := range memoryMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	total, released := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if released > total {
+		return 0
+	}
+	return total - released
+}
+
+// MemoryStatus returns the last memory sample. It reports false if memory
+// pressure monitoring is disabled.
+func (s *Server) MemoryStatus() (MemoryStatus, bool) {
+	m := s.memory
+	if m == nil {
+		return MemoryStatus{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status, true
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// HandlerFunc handles a request received from the client and sends its
+// response through the mcp.ResponseSender in ctx.
+type HandlerFunc func(ctx context.Context, req mcp.Request) error
+
+// Middleware wraps the handling of client requests, see WithMiddleware.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// WithMiddleware wraps the dispatch of every client request in middleware,
+// e.g. for authorization, logging, metrics or rewriting requests. Repeat it
+// to add more; the first middleware is outermost.
+//
+// Middleware runs once the request has been decoded and passed the session
+// lifecycle checks, so ctx carries the request logger, the session ID and
+// the client's info, and req.Params holds the decoded params. Middleware may
+// change the method and params before calling next, but not the request ID.
+//
+// To reject a request, middleware returns an error without calling next.
+// The server sends an *mcp.Error in its chain as the error response, and
+// any other error as an internal error. Middleware that neither calls next
+// nor returns an error must send the response itself.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(cfg *serverConfig) {
+		cfg.middleware = append(cfg.middleware, middleware...)
+	}
+}
+
+func validateMiddleware(middleware []Middleware) error {
+	for i, m := range middleware {
+		if m == nil {
+			return fmt.Errorf("nil middleware %d", i)
+		}
+	}
+	return nil
+}
+
+// dispatchWithMiddleware passes the request through the middleware to dispatch.
+func (s *Server) dispatchWithMiddleware(ctx context.Context, req mcp.Request) error {
+	if len(s.config.middleware) == 0 {
+		return s.dispatch(ctx, req)
+	}
+
+	dispatched := false
+	next := func(ctx context.Context, req mcp.Request) error {
+		dispatched = true
+		return s.dispatch(ctx, req)
+	}
+	for i := len(s.config.middleware) - 1; i >= 0; i-- {
+		next = s.config.middleware[i](next)
+	}
+
+	err := next(ctx, req)
+	if err == nil || dispatched {
+		return err
+	}
+	mcp.LoggerFromContext(ctx).Warn("Request rejected by middleware", "method", req.Method, "error", err)
+	return s.sendHandlerError(ctx, req.ID, err, mcp.ErrorCodeInternalError, "Internal error", nil)
+}
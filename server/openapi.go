@@ -0,0 +1,133 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// OpenAPIVersion is the OpenAPI version of documents generated by OpenAPI.
+//
+// OpenAPI 3.1 schemas are JSON Schema, so tool schemas are embedded as-is.
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPIDocument is an OpenAPI description of the tools hosted by the server.
+type OpenAPIDocument struct {
+	OpenAPI string                     `json:"openapi"`
+	Info    OpenAPIInfo                `json:"info"`
+	Paths   map[string]OpenAPIPathItem `json:"paths"`
+}
+
+// OpenAPIInfo describes the server in an OpenAPIDocument.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem describes the operations available on a path.
+type OpenAPIPathItem struct {
+	Post *OpenAPIOperation `json:"post,omitempty"`
+}
+
+// OpenAPIOperation describes a single tool as an HTTP operation.
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIRequestBody describes the arguments of a tool.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse describes the result of a tool.
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType holds the JSON Schema of a request or response body.
+type OpenAPIMediaType struct {
+	Schema any `json:"schema"`
+}
+
+// toolResponseSchema describes the content of tools without an output schema.
+var toolResponseSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"content": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":     "object",
+				"required": []string{"type"},
+				"properties": map[string]any{
+					"type":     map[string]any{"type": "string"},
+					"text":     map[string]any{"type": "string"},
+					"data":     map[string]any{"type": "string", "contentEncoding": "base64"},
+					"mimeType": map[string]any{"type": "string"},
+				},
+			},
+		},
+	},
+	"required": []string{"content"},
+}
+
+// OpenAPI describes every hosted tool as an HTTP operation.
+//
+// Each tool becomes a POST operation on /tools/{name} whose request body is the
+// tool's input schema and whose response is its output schema, or the generic
+// content list for tools without one. This lets non-MCP consumers and
+// documentation portals reuse the tool definitions the server exposes; the
+// server itself serves the tools over MCP only.
+func (s *Server) OpenAPI(ctx context.Context) (*OpenAPIDocument, error) {
+	tools, err := s.listTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       cmp.Or(s.serverInfo.Title, s.serverInfo.Name),
+			Version:     s.serverInfo.Version,
+			Description: "Tools hosted by an MCP server, described as HTTP operations.",
+		},
+		Paths: make(map[string]OpenAPIPathItem, len(tools)),
+	}
+	for _, tool := range tools {
+		doc.Paths["/tools/"+url.PathEscape(tool.Name)] = OpenAPIPathItem{Post: toolOperation(tool)}
+	}
+	return doc, nil
+}
+
+func toolOperation(tool mcp.Tool) *OpenAPIOperation {
+	var result any = toolResponseSchema
+	if tool.OutputSchema != nil {
+		result = tool.OutputSchema
+	}
+
+	return &OpenAPIOperation{
+		OperationID: tool.Name,
+		Summary:     cmp.Or(tool.Title, tool.Name),
+		Description: tool.Description,
+		RequestBody: &OpenAPIRequestBody{
+			Required: len(tool.InputSchema.Required) > 0,
+			Content:  map[string]OpenAPIMediaType{"application/json": {Schema: tool.InputSchema}},
+		},
+		Responses: map[string]OpenAPIResponse{
+			"200": {
+				Description: "Tool result",
+				Content:     map[string]OpenAPIMediaType{"application/json": {Schema: result}},
+			},
+			"default": {Description: "Tool error"},
+		},
+	}
+}
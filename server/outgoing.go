@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// ErrClientRequestsUnsupported was returned when the current channel could not
+// carry server-initiated requests.
+//
+// Deprecated: Sessions are registered with an mcp.SessionSender, which can
+// always carry requests, so it is no longer returned.
+var ErrClientRequestsUnsupported = errors.New("transport cannot send requests to the client")
+
+// ClientError is returned when the client answers a server-initiated request with an error.
+type ClientError struct {
+	// Method is the method of the server-initiated request.
+	Method string
+
+	// Err is the JSON-RPC error returned by the client.
+	Err *mcp.ErrorResponse
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("client returned error for %s: %s (code %d)", e.Method, e.Err.Message, e.Err.Code)
+}
+
+// RequestSampling asks the client to sample an LLM completion.
+//
+// It must be called with the context of an in-flight request (e.g. from a
+// tool handler) or with the ID of the session to ask as mcp.SessionIDKey,
+// so the server knows which client to ask. It blocks until the client
+// responds or ctx is done.
+func (s *Server) RequestSampling(ctx context.Context, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	var result mcp.CreateMessageResult
+	if err := s.sendClientRequest(ctx, mcp.MethodSamplingCreateMessage, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RequestElicitation asks the client to collect input from the user, e.g. to
+// confirm an action or fill in missing arguments. Like RequestSampling, it
+// must be called with the context of an in-flight request or with the ID of
+// the session to ask as mcp.SessionIDKey.
+func (s *Server) RequestElicitation(ctx context.Context, req mcp.ElicitationRequest) (*mcp.ElicitationResponse, error) {
+	var result mcp.ElicitationResponse
+	if err := s.sendClientRequest(ctx, mcp.MethodElicitationCreate, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Ping checks that the client of the session behind ctx is still responsive.
+// It returns once the client answers, or with an error when ctx is done first.
+func (s *Server) Ping(ctx context.Context) error {
+	var result struct{}
+	return s.sendClientRequest(ctx, mcp.MethodPing, nil, &result)
+}
+
+// HandleResponse delivers a client's response to a server-initiated request.
+//
+// Transports call this for inbound messages that carry a result or error
+// instead of a method.
+func (s *Server) HandleResponse(ctx context.Context, resp mcp.Response) error {
+	s.transcripts.recordContext(ctx, DirectionIn, resp)
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+
+	s.pendingMu.Lock()
+	pending, ok := s.pending[resp.ID]
+	owned := ok && pending.sessionID == sessionID
+	if owned {
+		delete(s.pending, resp.ID)
+	}
+	s.pendingMu.Unlock()
+
+	if ok && !owned {
+		// Only the session the request was sent to may answer it
+		s.logger.Warn("Dropping response to a request of another session", "request_id", resp.ID, "session", sessionID)
+	}
+	if !owned {
+		return fmt.Errorf("unexpected response for unknown request ID %v", resp.ID)
+	}
+
+	pending.ch <- resp
+	return nil
+}
+
+// pendingRequest is a server-initiated request awaiting the client's response.
+type pendingRequest struct {
+	// sessionID is the session the request was sent to
+	sessionID string
+	ch        chan mcp.Response
+}
+
+// requestSender returns the channel for server-initiated requests related to the current request.
+func (s *Server) requestSender(ctx context.Context) (mcp.RequestSender, error) {
+	if sender, ok := ctx.Value(mcp.ResponseSenderKey).(mcp.RequestSender); ok {
+		return sender, nil
+	}
+
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	s.sessionsMu.RLock()
+	sess, ok := s.sessions[sessionID]
+	s.sessionsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", mcp.ErrSessionNotFound, sessionID)
+	}
+
+	return sess.sender, nil
+}
+
+// sendClientRequest sends a request to the client and decodes its result into result.
+func (s *Server) sendClientRequest(ctx context.Context, method string, params, result any) error {
+	if err := s.checkClientRequest(ctx, method); err != nil {
+		return err
+	}
+
+	sender, err := s.requestSender(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Random IDs keep clients from guessing the requests of other sessions
+	id := mcp.NewStringID("server-" + rand.Text())
+	ch := make(chan mcp.Response, 1)
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+
+	s.pendingMu.Lock()
+	s.pending[id] = &pendingRequest{sessionID: sessionID, ch: ch}
+	s.pendingMu.Unlock()
+
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+	}()
+
+	request := mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  method,
+		ID:      id,
+		Params:  params,
+	}
+	s.transcripts.recordContext(ctx, DirectionOut, request)
+	if err := sender.SendRequest(request); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return &ClientError{Method: method, Err: resp.Error}
+		}
+		return decodeResult(resp.Result, result)
+	case <-ctx.Done():
+		s.cancelClientRequest(ctx, id)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s: %w", mcp.ErrTimeout, method, ctx.Err())
+		}
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// cancelClientRequest tells the client to stop working on an abandoned request.
+func (s *Server) cancelClientRequest(ctx context.Context, id mcp.RequestID) {
+	notifier := s.notifier(ctx)
+	if notifier == nil {
+		return
+	}
+	if err := notifier.SendNotification(mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  mcp.CancelledParams{RequestID: id, Reason: "request abandoned by server"},
+	}); err != nil {
+		s.logger.Debug("Failed to cancel client request", "request_id", id, "error", err)
+	}
+}
+
+// decodeResult converts a generically decoded JSON result into the target type.
+func decodeResult(raw, target any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to marshal client result: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode client result: %w", err)
+	}
+	return nil
+}
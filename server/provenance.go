@@ -0,0 +1,67 @@
+package server
+
+import (
+	"maps"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// ProvenanceMetaKey is the _meta key of the provenance added to tool results.
+const ProvenanceMetaKey = "io.github.cbrgm/provenance"
+
+// Provenance identifies the source of a tool result.
+type Provenance struct {
+	// Server and Version identify the server that handled the call.
+	Server  string `json:"server"`
+	Version string `json:"version"`
+
+	// Tool is the name of the tool that produced the result.
+	Tool string `json:"tool"`
+
+	// Origin identifies the upstream server that produced the result. Handlers
+	// proxying to other servers set it by returning a Provenance under
+	// ProvenanceMetaKey; the server fills in all other fields.
+	Origin string `json:"origin,omitempty"`
+
+	// Timestamp is when the call started, in RFC 3339 format.
+	Timestamp string `json:"timestamp"`
+
+	// DurationMs is how long the call took in milliseconds.
+	DurationMs float64 `json:"durationMs"`
+}
+
+// WithProvenance adds a Provenance to the _meta of every tool result, so
+// agents combining several servers can attribute results to their source.
+func WithProvenance(enabled bool) Option {
+	return func(cfg *serverConfig) {
+		cfg.provenance = enabled
+	}
+}
+
+// withProvenance returns response with its provenance added to _meta, if enabled.
+func (s *Server) withProvenance(response mcp.ToolResponse, tool string, start time.Time) mcp.ToolResponse {
+	if !s.config.provenance {
+		return response
+	}
+
+	provenance := Provenance{
+		Server:     s.serverInfo.Name,
+		Version:    s.serverInfo.Version,
+		Tool:       tool,
+		Timestamp:  start.UTC().Format(time.RFC3339Nano),
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if existing, ok := response.Meta[ProvenanceMetaKey].(Provenance); ok {
+		provenance.Origin = existing.Origin
+	}
+
+	// Handlers may return a shared map, so never modify it in place
+	meta := maps.Clone(response.Meta)
+	if meta == nil {
+		meta = make(map[string]any, 1)
+	}
+	meta[ProvenanceMetaKey] = provenance
+	response.Meta = meta
+	return response
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// readinessEvent is the machine-readable line written once a transport accepts traffic.
+type readinessEvent struct {
+	Event             string `json:"event"`
+	Time              string `json:"time"`
+	Server            string `json:"server"`
+	Version           string `json:"version"`
+	ProtocolVersion   string `json:"protocolVersion"`
+	Transport         string `json:"transport"`
+	Port              int    `json:"port,omitempty"`
+	Tools             int    `json:"tools"`
+	Resources         int    `json:"resources"`
+	ResourceTemplates int    `json:"resourceTemplates"`
+	Prompts           int    `json:"prompts"`
+}
+
+// AnnounceReady writes a single-line JSON readiness event to the readiness output.
+//
+// Transports call this once they are accepting traffic, so supervisors and
+// clients spawning the server can detect readiness instead of guessing. The
+// port is the actually bound port for network transports and 0 otherwise.
+func (s *Server) AnnounceReady(ctx context.Context, transport string, port int) error {
+	event := readinessEvent{
+		Event:           "ready",
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		Server:          s.serverInfo.Name,
+		Version:         s.serverInfo.Version,
+		ProtocolVersion: mcp.ProtocolVersion,
+		Transport:       transport,
+		Port:            port,
+	}
+
+	if tools, err := s.listTools(ctx); err == nil {
+		event.Tools = len(tools)
+	} else {
+		s.logger.Warn("Failed to count tools for readiness event", "error", err)
+	}
+	if s.resourceHandler != nil {
+		if resources, err := s.resourceHandler.ListResources(ctx); err == nil {
+			event.Resources = len(resources)
+		} else {
+			s.logger.Warn("Failed to count resources for readiness event", "error", err)
+		}
+		if templates, err := s.resourceHandler.ListResourceTemplates(ctx); err == nil {
+			event.ResourceTemplates = len(templates)
+		} else {
+			s.logger.Warn("Failed to count resource templates for readiness event", "error", err)
+		}
+	}
+	if s.promptHandler != nil {
+		if prompts, err := s.promptHandler.ListPrompts(ctx); err == nil {
+			event.Prompts = len(prompts)
+		} else {
+			s.logger.Warn("Failed to count prompts for readiness event", "error", err)
+		}
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal readiness event: %w", err)
+	}
+
+	_, err = fmt.Fprintln(s.config.readyOutput, string(line))
+	return err
+}
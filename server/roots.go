@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// ListRoots returns the workspace roots of the client behind ctx.
+//
+// Roots are requested from the client via roots/list and cached per session
+// until the client sends notifications/roots/list_changed. Like
+// RequestSampling, it must be called with the context of an in-flight request.
+func (s *Server) ListRoots(ctx context.Context) ([]mcp.Root, error) {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+
+	if sessionID != "" {
+		s.rootsMu.Lock()
+		roots, ok := s.roots[sessionID]
+		s.rootsMu.Unlock()
+		if ok {
+			return roots, nil
+		}
+	}
+
+	var result mcp.ListRootsResult
+	if err := s.sendClientRequest(ctx, mcp.MethodRootsList, nil, &result); err != nil {
+		return nil, err
+	}
+
+	if sessionID != "" {
+		s.rootsMu.Lock()
+		s.roots[sessionID] = result.Roots
+		s.rootsMu.Unlock()
+	}
+
+	return result.Roots, nil
+}
+
+// handleRootsListChanged drops the cached roots of the notifying session.
+func (s *Server) handleRootsListChanged(ctx context.Context) {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	s.forgetRoots(sessionID)
+	mcp.LoggerFromContext(ctx).Debug("Client roots changed", "session", sessionID)
+}
+
+func (s *Server) forgetRoots(sessionID string) {
+	s.rootsMu.Lock()
+	defer s.rootsMu.Unlock()
+	delete(s.roots, sessionID)
+}
@@ -2,10 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cbrgm/go-mcp-server/mcp"
@@ -18,17 +24,58 @@ type Server struct {
 	serverInfo      mcp.ServerInfo
 	logger          *slog.Logger
 	config          *serverConfig
+	sessionsMu      sync.RWMutex
+	sessions        map[string]*session
+	inFlightMu      sync.Mutex
+	inFlight        map[inFlightKey]*inFlightRequest
+	sloTrackers     []*sloTracker
+	toolLimiter     *adaptiveLimiter
+	pendingMu       sync.Mutex
+	pending         map[mcp.RequestID]*pendingRequest
+	duplicateIDs    atomic.Uint64
+	rootsMu         sync.Mutex
+	roots           map[string][]mcp.Root
+	clientsMu       sync.RWMutex
+	clients         map[string]*clientState
+	logPolicy       *logPolicy
+	settings        settingsStore
+	stats           serverStats
+	telemetry       *telemetryCollector
+	transcripts     *transcriptRecorder
+	memory          *memoryMonitor
 }
 
 type serverConfig struct {
-	requestTimeout  time.Duration
-	shutdownTimeout time.Duration
-	readTimeout     time.Duration
-	writeTimeout    time.Duration
-	idleTimeout     time.Duration
-	logLevel        string
-	logJSON         bool
-	customLogger    *slog.Logger
+	requestTimeout   time.Duration
+	shutdownTimeout  time.Duration
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+	idleTimeout      time.Duration
+	logLevel         string
+	logJSON          bool
+	customLogger     *slog.Logger
+	readyOutput      io.Writer
+	slos             []SLO
+	alertSink        AlertSink
+	sanitizeInput    bool
+	strictValidation bool
+	provenance       bool
+	sessionInfoTool  bool
+	toolDocs         bool
+	serverTitle      string
+	instructions     string
+	logPolicy        LogPolicy
+	settingsFile     string
+
+	completionHandler   mcp.CompletionHandler
+	notificationHandler mcp.NotificationHandler
+	onInitialized       func(ctx context.Context)
+	middleware          []Middleware
+
+	adaptiveConcurrency *AdaptiveConcurrency
+	telemetry           *Telemetry
+	transcripts         *Transcripts
+	memoryPressure      *MemoryPressure
 }
 
 type Option func(*serverConfig)
@@ -81,11 +128,67 @@ func WithLogJSON(enabled bool) Option {
 	}
 }
 
+// WithReadyOutput sets where the readiness event is written (stderr by default).
+func WithReadyOutput(w io.Writer) Option {
+	return func(cfg *serverConfig) {
+		cfg.readyOutput = w
+	}
+}
+
+// WithServerTitle sets the human-friendly display name sent in serverInfo.
+func WithServerTitle(title string) Option {
+	return func(cfg *serverConfig) {
+		cfg.serverTitle = title
+	}
+}
+
+// WithInstructions sets usage guidance returned in the initialize response,
+// e.g. "prefer getTeaInfo over getTeaNames when the tea is known". Clients
+// may add it to the model's system prompt.
+func WithInstructions(instructions string) Option {
+	return func(cfg *serverConfig) {
+		cfg.instructions = instructions
+	}
+}
+
+// WithInputSanitization strips control characters from all string params
+// before they reach handlers or logs, see mcp.StripControlCharacters.
+func WithInputSanitization(enabled bool) Option {
+	return func(cfg *serverConfig) {
+		cfg.sanitizeInput = enabled
+	}
+}
+
+// WithNotificationHandler passes every notification received from the client
+// to handler, after the server has processed it.
+func WithNotificationHandler(handler mcp.NotificationHandler) Option {
+	return func(cfg *serverConfig) {
+		cfg.notificationHandler = handler
+	}
+}
+
+// WithOnInitialized calls hook once a session completes the handshake, i.e.
+// when the client sends notifications/initialized.
+//
+// This is where servers typically query the client's roots or send a welcome
+// log message, since requests to the client are refused before. The hook
+// runs in its own goroutine, so it may wait for client responses, with a
+// context carrying the session and bounded by the request timeout.
+func WithOnInitialized(hook func(ctx context.Context)) Option {
+	return func(cfg *serverConfig) {
+		cfg.onInitialized = hook
+	}
+}
+
 // NewMCPServer creates a new MCP server using the options pattern.
 //
 // This constructor provides a more flexible way to configure the server
-// using functional options. It requires the server name, version, and handlers,
-// while all other settings can be configured via options.
+// using functional options. It requires the server name and version, while
+// all other settings can be configured via options.
+//
+// Any handler may be nil. The server then neither advertises the matching
+// capability on initialize nor serves its methods, so e.g. a tools-only
+// server passes nil resource and prompt handlers.
 //
 // Example usage:
 //
@@ -97,16 +200,6 @@ func WithLogJSON(enabled bool) Option {
 //	    WithLogLevel("debug"),
 //	)
 func NewMCPServer(name, version string, toolHandler mcp.ToolHandler, resourceHandler mcp.ResourceHandler, promptHandler mcp.PromptHandler, opts ...Option) (*Server, error) {
-	if toolHandler == nil {
-		return nil, fmt.Errorf("toolHandler cannot be nil")
-	}
-	if resourceHandler == nil {
-		return nil, fmt.Errorf("resourceHandler cannot be nil")
-	}
-	if promptHandler == nil {
-		return nil, fmt.Errorf("promptHandler cannot be nil")
-	}
-
 	config := &serverConfig{
 		requestTimeout:  30 * time.Second,
 		shutdownTimeout: 5 * time.Second,
@@ -115,6 +208,7 @@ func NewMCPServer(name, version string, toolHandler mcp.ToolHandler, resourceHan
 		idleTimeout:     120 * time.Second,
 		logLevel:        "info",
 		logJSON:         false,
+		readyOutput:     os.Stderr,
 	}
 
 	for _, opt := range opts {
@@ -128,38 +222,158 @@ func NewMCPServer(name, version string, toolHandler mcp.ToolHandler, resourceHan
 		logger = createDefaultLogger(config.logLevel, config.logJSON)
 	}
 
-	return &Server{
+	var stored *Settings
+	if config.settingsFile != "" {
+		var err error
+		if stored, err = loadSettings(config.settingsFile); err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			config.logPolicy = stored.LogPolicy
+		}
+	}
+
+	policy, err := newLogPolicy(config.logPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.telemetry != nil && config.telemetry.Endpoint == "" {
+		return nil, fmt.Errorf("telemetry endpoint cannot be empty")
+	}
+	if config.memoryPressure != nil {
+		if err := config.memoryPressure.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateMiddleware(config.middleware); err != nil {
+		return nil, err
+	}
+
+	var toolLimiter *adaptiveLimiter
+	if config.adaptiveConcurrency != nil {
+		toolLimiter = newAdaptiveLimiter(*config.adaptiveConcurrency)
+	}
+
+	srv := &Server{
 		toolHandler:     toolHandler,
 		resourceHandler: resourceHandler,
 		promptHandler:   promptHandler,
 		logger:          logger,
 		config:          config,
+		sessions:        make(map[string]*session),
+		inFlight:        make(map[inFlightKey]*inFlightRequest),
+		sloTrackers:     newSLOTrackers(config.slos),
+		toolLimiter:     toolLimiter,
+		pending:         make(map[mcp.RequestID]*pendingRequest),
+		roots:           make(map[string][]mcp.Root),
+		clients:         make(map[string]*clientState),
+		logPolicy:       policy,
+		telemetry:       newTelemetryCollector(config.telemetry),
+		transcripts:     newTranscriptRecorder(config.transcripts),
+		memory:          newMemoryMonitor(config.memoryPressure, logger),
 		serverInfo: mcp.ServerInfo{
 			Name:    name,
+			Title:   config.serverTitle,
 			Version: version,
 		},
-	}, nil
+	}
+	if stored != nil {
+		srv.setDisabledTools(stored.DisabledTools)
+	}
+	return srv, nil
 }
 
 func (s *Server) Initialize(ctx context.Context) (*mcp.InitializeResponse, error) {
 	return &mcp.InitializeResponse{
 		ProtocolVersion: mcp.ProtocolVersion,
-		Capabilities: map[string]any{
-			"tools":       map[string]bool{"listChanged": true},
-			"resources":   map[string]bool{"listChanged": true, "templates": true},
-			"prompts":     map[string]bool{"listChanged": true},
-			"elicitation": map[string]any{},
-		},
-		ServerInfo: s.serverInfo,
+		Capabilities:    s.capabilities(),
+		ServerInfo:      s.serverInfo,
+		Instructions:    s.config.instructions,
 	}, nil
 }
 
 func (s *Server) HandleRequest(ctx context.Context, req mcp.Request) error {
-	s.logger.Debug("Handling request", "method", req.Method, "id", req.ID)
+	var paramsErr error
+	if raw, ok := req.Params.(json.RawMessage); ok {
+		req.Params, paramsErr = s.decodeParams(req.Method, raw)
+	}
+
+	if s.config.sanitizeInput {
+		req.Method = mcp.StripControlCharacters(req.Method)
+		req.Params = mcp.SanitizeParams(req.Params)
+	}
+	s.transcripts.recordContext(ctx, DirectionIn, req)
+
+	ctx = context.WithValue(ctx, mcp.LoggerKey, s.requestLogger(ctx, req))
+	mcp.LoggerFromContext(ctx).Debug("Handling request", "method", req.Method)
+	s.stats.requests.Add(1)
+	defer s.beginSessionRequest(ctx)()
+
+	if s.stats.shuttingDown.Load() {
+		mcp.LoggerFromContext(ctx).Warn("Rejecting request during shutdown", "method", req.Method)
+		return s.sendError(ctx, req.ID, mcp.ErrorCodeInternalError, "Server shutting down", mcp.ErrServerShutdown.Error())
+	}
+
+	if err := s.checkLifecycle(ctx, req.Method); err != nil {
+		mcp.LoggerFromContext(ctx).Warn("Rejecting request outside of session lifecycle", "method", req.Method, "error", err)
+		return s.sendError(ctx, req.ID, mcp.ErrorCodeInvalidRequest, "Invalid request", err.Error())
+	}
+
+	if paramsErr != nil {
+		mcp.LoggerFromContext(ctx).Error("Invalid request parameters", "method", req.Method, "error", paramsErr)
+		return s.sendError(ctx, req.ID, mcp.ErrorCodeInvalidParams, "Invalid params", paramsErr.Error())
+	}
+
+	if req.Method == "initialize" {
+		s.recordClientState(ctx, req.Params)
+		s.telemetry.recordProtocolVersion(negotiateProtocolVersion(req.Params))
+	}
+	ctx = s.withClientContext(ctx)
+
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s: %w", mcp.ErrTimeout, req.Method, err)
+		}
+		return fmt.Errorf("%s: %w", req.Method, err)
+	}
+
+	if len(s.sloTrackers) > 0 {
+		start := time.Now()
+		sloCtx := context.WithoutCancel(ctx)
+		defer func() { s.recordLatency(sloCtx, req.Method, time.Since(start)) }()
+	}
+
+	// Per spec, the initialize request must not be cancelled
+	if req.Method != "initialize" {
+		tracked, done, err := s.trackInFlight(ctx, req.ID)
+		if err != nil {
+			mcp.LoggerFromContext(ctx).Warn("Rejecting request with duplicate ID", "method", req.Method, "error", err)
+			return s.sendError(ctx, req.ID, mcp.ErrorCodeInvalidRequest, "Invalid request", err.Error())
+		}
+		ctx = tracked
+		defer done()
+	}
+
+	if token := progressToken(req.Params); token != nil {
+		ctx = context.WithValue(ctx, mcp.ProgressReporterKey, mcp.NewProgressReporter(token, s.notifier(ctx)))
+	}
+
+	return s.dispatchWithMiddleware(ctx, req)
+}
+
+// dispatch calls the handler of the request's method.
+func (s *Server) dispatch(ctx context.Context, req mcp.Request) error {
+	s.telemetry.recordMethod(req.Method)
+
+	if !s.supportsMethod(req.Method) {
+		mcp.LoggerFromContext(ctx).Warn("Method of unsupported capability requested", "method", req.Method)
+		return s.sendError(ctx, req.ID, mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method %s not found", req.Method), nil)
+	}
 
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(ctx, req.ID)
+		return s.handleInitialize(ctx, req.ID, req)
 	case "tools/list":
 		return s.handleToolsList(ctx, req.ID)
 	case "tools/call":
@@ -174,15 +388,77 @@ func (s *Server) HandleRequest(ctx context.Context, req mcp.Request) error {
 		return s.handlePromptsList(ctx, req.ID)
 	case "prompts/get":
 		return s.handlePromptsGet(ctx, req.ID, req)
+	case mcp.MethodLoggingSetLevel:
+		return s.handleLoggingSetLevel(ctx, req.ID, req)
+	case mcp.MethodCompletionComplete:
+		return s.handleCompletionComplete(ctx, req.ID, req)
 	case "ping":
 		return s.handlePing(ctx, req.ID)
 	default:
-		s.logger.Warn("Unknown method requested", "method", req.Method, "id", req.ID)
+		mcp.LoggerFromContext(ctx).Warn("Unknown method requested", "method", req.Method)
 		return s.sendError(ctx, req.ID, mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method %s not found", req.Method), nil)
 	}
 }
 
-func (s *Server) sendResponse(ctx context.Context, id, result any) error {
+// requestLogger returns the server logger annotated with the session and request IDs.
+func (s *Server) requestLogger(ctx context.Context, req mcp.Request) *slog.Logger {
+	logger := slog.New(s.logPolicy.requestHandler(s.logger.Handler(), req.Method)).With("request_id", req.ID)
+	if sessionID, ok := ctx.Value(mcp.SessionIDKey).(string); ok && sessionID != "" {
+		logger = logger.With("session", sessionID)
+	}
+	return logger
+}
+
+// HandleNotification processes a JSON-RPC notification received from the client.
+//
+// Protocol notifications are handled by the server, then every notification
+// is passed to the handler set with WithNotificationHandler. Notifications
+// never produce a response. Errors are returned for invalid notifications and
+// handler failures so transports can log them.
+func (s *Server) HandleNotification(ctx context.Context, notification mcp.Notification) error {
+	// Cancellations are decoded from the raw params, so numeric request IDs stay exact
+	raw, _ := notification.Params.(json.RawMessage)
+	if raw != nil {
+		var params any
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+		}
+		notification.Params = params
+	}
+
+	if s.config.sanitizeInput {
+		notification.Method = mcp.StripControlCharacters(notification.Method)
+		notification.Params = mcp.SanitizeParams(notification.Params)
+	}
+
+	s.logger.Debug("Handling notification", "method", notification.Method)
+	s.transcripts.recordContext(ctx, DirectionIn, notification)
+
+	switch notification.Method {
+	case mcp.NotificationCancelled:
+		params := notification.Params
+		if raw != nil {
+			params = raw
+		}
+		if err := s.handleCancelled(ctx, params); err != nil {
+			return err
+		}
+	case mcp.NotificationInitialized:
+		s.handleInitialized(ctx)
+	case mcp.NotificationRootsListChanged:
+		s.handleRootsListChanged(ctx)
+	}
+
+	if s.config.notificationHandler == nil {
+		return nil
+	}
+	if err := s.config.notificationHandler.HandleNotification(ctx, notification); err != nil {
+		return fmt.Errorf("notification handler failed for %s: %w", notification.Method, err)
+	}
+	return nil
+}
+
+func (s *Server) sendResponse(ctx context.Context, id mcp.RequestID, result any) error {
 	response := mcp.Response{
 		JSONRPC: mcp.JSONRPCVersion,
 		ID:      id,
@@ -191,113 +467,253 @@ func (s *Server) sendResponse(ctx context.Context, id, result any) error {
 	return s.sendResponseDirect(ctx, response)
 }
 
-func (s *Server) sendError(ctx context.Context, id any, code int, message string, data any) error {
-	sender := ctx.Value(mcp.ResponseSenderKey)
-	if sender == nil {
-		return fmt.Errorf("missing response sender in context")
+func (s *Server) sendError(ctx context.Context, id mcp.RequestID, code int, message string, data any) error {
+	if isCancelledByClient(ctx) {
+		mcp.LoggerFromContext(ctx).Debug("Suppressing error response for cancelled request")
+		return nil
 	}
 
-	rs, ok := sender.(mcp.ResponseSender)
-	if !ok {
-		return fmt.Errorf("invalid response sender type in context")
+	rs, err := responseSender(ctx)
+	if err != nil {
+		return err
 	}
-
+	s.stats.errors.Add(1)
+	s.transcripts.recordContext(ctx, DirectionOut, mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error:   &mcp.ErrorResponse{Code: code, Message: message, Data: data},
+	})
 	return rs.SendError(id, code, message, data)
 }
 
+// sendHandlerError reports a failed handler call. An *mcp.Error in err's
+// chain determines the error response, and other resource-not-found errors
+// get the MCP code for them. All remaining errors are sent with the given
+// code, message and data.
+func (s *Server) sendHandlerError(ctx context.Context, id mcp.RequestID, err error, code int, message string, data any) error {
+	var mcpErr *mcp.Error
+	if errors.As(err, &mcpErr) {
+		return s.sendError(ctx, id, mcpErr.Code, mcpErr.Message, mcpErr.Data)
+	}
+	if errors.Is(err, mcp.ErrResourceNotFound) {
+		code = mcp.ErrorCodeResourceNotFound
+	}
+	return s.sendError(ctx, id, code, message, data)
+}
+
 // sendResponseDirect sends a JSON-RPC response directly.
 func (s *Server) sendResponseDirect(ctx context.Context, response mcp.Response) error {
+	if isCancelledByClient(ctx) {
+		mcp.LoggerFromContext(ctx).Debug("Suppressing response for cancelled request")
+		return nil
+	}
+
+	rs, err := responseSender(ctx)
+	if err != nil {
+		return err
+	}
+	s.transcripts.recordContext(ctx, DirectionOut, response)
+	return rs.SendResponse(response)
+}
+
+// responseSender returns the ResponseSender stored in the request context.
+func responseSender(ctx context.Context) (mcp.ResponseSender, error) {
 	sender := ctx.Value(mcp.ResponseSenderKey)
 	if sender == nil {
-		return fmt.Errorf("missing response sender in context")
+		return nil, mcp.ErrMissingResponseSender
 	}
 
 	rs, ok := sender.(mcp.ResponseSender)
 	if !ok {
-		return fmt.Errorf("invalid response sender type in context")
+		return nil, fmt.Errorf("%w: invalid response sender type %T", mcp.ErrMissingResponseSender, sender)
 	}
-
-	return rs.SendResponse(response)
+	return rs, nil
 }
 
 // Request handlers.
-func (s *Server) handleInitialize(ctx context.Context, id any) error {
+func (s *Server) handleInitialize(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
 	result, err := s.Initialize(ctx)
 	if err != nil {
-		s.logger.Error("Failed to initialize server", "error", err, "id", id)
+		mcp.LoggerFromContext(ctx).Error("Failed to initialize server", "error", err)
 		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to initialize", err.Error())
 	}
-	s.logger.Info("Server initialized successfully", "id", id)
+
+	result.ProtocolVersion = negotiateProtocolVersion(req.Params)
+
+	mcp.LoggerFromContext(ctx).Info("Server initialized successfully", "protocol_version", result.ProtocolVersion)
 	return s.sendResponse(ctx, id, result)
 }
 
-func (s *Server) handleToolsList(ctx context.Context, id any) error {
-	tools, err := s.toolHandler.ListTools(ctx)
+// negotiateProtocolVersion picks the protocol version for an initialize request.
+//
+// Per spec, the server agrees to the client's version if supported, and
+// otherwise offers the latest version it supports.
+func negotiateProtocolVersion(params any) string {
+	if paramsMap, ok := params.(map[string]any); ok {
+		if requested, ok := paramsMap["protocolVersion"].(string); ok && mcp.IsSupportedProtocolVersion(requested) {
+			return requested
+		}
+	}
+	return mcp.ProtocolVersion
+}
+
+func (s *Server) handleToolsList(ctx context.Context, id mcp.RequestID) error {
+	tools, err := s.listTools(ctx)
 	if err != nil {
-		s.logger.Error("Failed to list tools", "error", err, "id", id)
-		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to list tools", err.Error())
+		mcp.LoggerFromContext(ctx).Error("Failed to list tools", "error", err)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInternalError, "Failed to list tools", err.Error())
 	}
-	s.logger.Debug("Listed tools", "count", len(tools), "id", id)
+	mcp.LoggerFromContext(ctx).Debug("Listed tools", "count", len(tools))
 	return s.sendResponse(ctx, id, map[string][]mcp.Tool{"tools": tools})
 }
 
-func (s *Server) handleToolsCall(ctx context.Context, id any, req mcp.Request) error {
+func (s *Server) handleToolsCall(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
 	params, err := s.parseToolCallParams(req.Params)
 	if err != nil {
-		s.logger.Error("Invalid tool call parameters", "error", err, "id", id)
+		mcp.LoggerFromContext(ctx).Error("Invalid tool call parameters", "error", err)
 		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid tool call parameters", err.Error())
 	}
 
-	s.logger.Debug("Calling tool", "tool", params.Name, "id", id)
-	response, err := s.toolHandler.CallTool(ctx, params)
+	if s.toolDisabled(params.Name) {
+		err := fmt.Errorf("%w: %s", mcp.ErrToolNotFound, params.Name)
+		mcp.LoggerFromContext(ctx).Warn("Call of disabled tool rejected", "tool", params.Name)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Tool call failed: %s", err.Error()), nil)
+	}
+
+	if s.config.sessionInfoTool && params.Name == SessionInfoToolName {
+		response := mcp.ToolResponse{StructuredContent: s.sessionInfo(ctx)}
+		return s.sendToolResponse(ctx, id, params.Name, s.withProvenance(response, params.Name, time.Now()))
+	}
+
+	if s.memory.check(ctx) {
+		mcp.LoggerFromContext(ctx).Warn("Tool call rejected under memory pressure", "tool", params.Name)
+		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, fmt.Sprintf("Tool call failed: %s", mcp.ErrMemoryPressure), nil)
+	}
+
+	var release func(latency time.Duration, failed bool)
+	if s.toolLimiter != nil {
+		var ok bool
+		if release, ok = s.toolLimiter.acquire(params.Name); !ok {
+			mcp.LoggerFromContext(ctx).Warn("Tool call rejected by concurrency limit", "tool", params.Name, "limit", s.toolLimiter.limit(params.Name))
+			return s.sendError(ctx, id, mcp.ErrorCodeInternalError, fmt.Sprintf("Tool call failed: %s: %s is at its concurrency limit", mcp.ErrOverloaded, params.Name), nil)
+		}
+	}
+
+	mcp.LoggerFromContext(ctx).Debug("Calling tool", "tool", params.Name)
+	start := time.Now()
+	var response mcp.ToolResponse
+	if s.toolHandler != nil {
+		response, err = s.toolHandler.CallTool(ctx, params)
+	} else {
+		err = fmt.Errorf("%w: %s", mcp.ErrToolNotFound, params.Name)
+	}
+	if release != nil {
+		release(time.Since(start), err != nil)
+	}
 	if err != nil {
-		s.logger.Error("Tool call failed", "tool", params.Name, "error", err, "id", id)
-		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Tool call failed: %s", err.Error()), nil)
+		mcp.LoggerFromContext(ctx).Error("Tool call failed", "tool", params.Name, "error", err)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Tool call failed: %s", err.Error()), nil)
+	}
+
+	mcp.LoggerFromContext(ctx).Debug("Tool call completed", "tool", params.Name)
+	return s.sendToolResponse(ctx, id, params.Name, s.withProvenance(response, params.Name, start))
+}
+
+func (s *Server) sendToolResponse(ctx context.Context, id mcp.RequestID, tool string, response mcp.ToolResponse) error {
+	// For backwards compatibility, structured output is also returned as serialized JSON text
+	if response.StructuredContent != nil && len(response.Content) == 0 {
+		structured, err := json.Marshal(response.StructuredContent)
+		if err != nil {
+			mcp.LoggerFromContext(ctx).Error("Failed to marshal structured content", "tool", tool, "error", err)
+			return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to marshal structured content", err.Error())
+		}
+		response.Content = []mcp.ContentItem{{Type: "text", Text: string(structured)}}
+	}
+
+	for _, item := range response.Content {
+		if err := item.Validate(); err != nil {
+			mcp.LoggerFromContext(ctx).Error("Tool returned invalid content", "tool", tool, "error", err)
+			return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Tool returned invalid content", err.Error())
+		}
 	}
-	s.logger.Debug("Tool call completed", "tool", params.Name, "id", id)
 	return s.sendResponse(ctx, id, response)
 }
 
-func (s *Server) handleResourcesList(ctx context.Context, id any) error {
-	resources, err := s.resourceHandler.ListResources(ctx)
-	if err != nil {
-		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to list resources", err.Error())
+func (s *Server) handleResourcesList(ctx context.Context, id mcp.RequestID) error {
+	var resources []mcp.Resource
+	if s.resourceHandler != nil {
+		var err error
+		if resources, err = s.resourceHandler.ListResources(ctx); err != nil {
+			return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInternalError, "Failed to list resources", err.Error())
+		}
+	}
+	if s.config.toolDocs {
+		docs, err := s.toolDocResources(ctx)
+		if err != nil {
+			return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInternalError, "Failed to list resources", err.Error())
+		}
+		resources = append(resources, docs...)
 	}
 	return s.sendResponse(ctx, id, map[string][]mcp.Resource{"resources": resources})
 }
 
-func (s *Server) handleResourcesRead(ctx context.Context, id any, req mcp.Request) error {
+func (s *Server) handleResourcesRead(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
 	params, err := s.parseResourceParams(req.Params)
 	if err != nil {
 		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid resource read parameters", err.Error())
 	}
 
-	response, err := s.resourceHandler.ReadResource(ctx, params)
+	if s.memory.check(ctx) {
+		mcp.LoggerFromContext(ctx).Warn("Resource read rejected under memory pressure", "uri", params.URI)
+		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, fmt.Sprintf("Resource read failed: %s", mcp.ErrMemoryPressure), nil)
+	}
+
+	var response mcp.ResourceResponse
+	if s.config.toolDocs && strings.HasPrefix(params.URI, ToolDocsURIPrefix) {
+		response, err = s.readToolDoc(ctx, params.URI)
+	} else if s.resourceHandler != nil {
+		response, err = s.resourceHandler.ReadResource(ctx, params)
+	} else {
+		err = mcp.NewResourceNotFoundError(params.URI)
+	}
 	if err != nil {
-		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Resource read failed: %s", err.Error()), nil)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Resource read failed: %s", err.Error()), nil)
+	}
+
+	for _, content := range response.Contents {
+		if err := content.Validate(); err != nil {
+			mcp.LoggerFromContext(ctx).Error("Resource returned invalid content", "uri", params.URI, "error", err)
+			return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Resource returned invalid content", err.Error())
+		}
 	}
 	return s.sendResponse(ctx, id, response)
 }
 
-func (s *Server) handleResourceTemplatesList(ctx context.Context, id any) error {
-	templates, err := s.resourceHandler.ListResourceTemplates(ctx)
-	if err != nil {
-		s.logger.Error("Failed to list resource templates", "error", err, "id", id)
-		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to list resource templates", err.Error())
+func (s *Server) handleResourceTemplatesList(ctx context.Context, id mcp.RequestID) error {
+	var templates []mcp.ResourceTemplate
+	if s.resourceHandler != nil {
+		var err error
+		if templates, err = s.resourceHandler.ListResourceTemplates(ctx); err != nil {
+			mcp.LoggerFromContext(ctx).Error("Failed to list resource templates", "error", err)
+			return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInternalError, "Failed to list resource templates", err.Error())
+		}
+	}
+	if s.config.toolDocs {
+		templates = append(templates, toolDocTemplate())
 	}
-	s.logger.Debug("Listed resource templates", "count", len(templates), "id", id)
+	mcp.LoggerFromContext(ctx).Debug("Listed resource templates", "count", len(templates))
 	return s.sendResponse(ctx, id, map[string][]mcp.ResourceTemplate{"resourceTemplates": templates})
 }
 
-func (s *Server) handlePromptsList(ctx context.Context, id any) error {
+func (s *Server) handlePromptsList(ctx context.Context, id mcp.RequestID) error {
 	prompts, err := s.promptHandler.ListPrompts(ctx)
 	if err != nil {
-		return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Failed to list prompts", err.Error())
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInternalError, "Failed to list prompts", err.Error())
 	}
 	return s.sendResponse(ctx, id, map[string][]mcp.Prompt{"prompts": prompts})
 }
 
-func (s *Server) handlePromptsGet(ctx context.Context, id any, req mcp.Request) error {
+func (s *Server) handlePromptsGet(ctx context.Context, id mcp.RequestID, req mcp.Request) error {
 	params, err := s.parsePromptParams(req.Params)
 	if err != nil {
 		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, "Invalid prompt parameters", err.Error())
@@ -305,35 +721,48 @@ func (s *Server) handlePromptsGet(ctx context.Context, id any, req mcp.Request)
 
 	response, err := s.promptHandler.GetPrompt(ctx, params)
 	if err != nil {
-		return s.sendError(ctx, id, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Prompt call failed: %s", err.Error()), nil)
+		return s.sendHandlerError(ctx, id, err, mcp.ErrorCodeInvalidParams, fmt.Sprintf("Prompt call failed: %s", err.Error()), nil)
+	}
+
+	for _, message := range response.Messages {
+		if err := message.Content.Validate(); err != nil {
+			mcp.LoggerFromContext(ctx).Error("Prompt returned invalid content", "prompt", params.Name, "error", err)
+			return s.sendError(ctx, id, mcp.ErrorCodeInternalError, "Prompt returned invalid content", err.Error())
+		}
 	}
 	return s.sendResponse(ctx, id, response)
 }
 
-func (s *Server) handlePing(ctx context.Context, id any) error {
+func (s *Server) handlePing(ctx context.Context, id mcp.RequestID) error {
 	return s.sendResponse(ctx, id, map[string]any{})
 }
 
 func (s *Server) parseToolCallParams(params any) (mcp.ToolCallParams, error) {
 	if params == nil {
-		return mcp.ToolCallParams{}, fmt.Errorf("params cannot be nil")
+		return mcp.ToolCallParams{}, fmt.Errorf("%w: params cannot be nil", mcp.ErrInvalidParams)
+	}
+
+	if decoded, ok := params.(*toolCallRequest); ok {
+		return decoded.toolCallParams()
 	}
 
 	paramsMap, ok := params.(map[string]any)
 	if !ok {
-		return mcp.ToolCallParams{}, fmt.Errorf("params must be an object")
+		return mcp.ToolCallParams{}, fmt.Errorf("%w: params must be an object", mcp.ErrInvalidParams)
 	}
 
 	name, ok := paramsMap["name"].(string)
 	if !ok {
-		return mcp.ToolCallParams{}, fmt.Errorf("name parameter is required and must be a string")
+		return mcp.ToolCallParams{}, fmt.Errorf("%w: name parameter is required and must be a string", mcp.ErrInvalidParams)
 	}
 
 	args := make(map[string]any)
-	if arguments, exists := paramsMap["arguments"]; exists {
-		if argsMap, ok := arguments.(map[string]any); ok {
-			args = argsMap
-		}
+	switch arguments := paramsMap["arguments"].(type) {
+	case nil:
+	case map[string]any:
+		args = arguments
+	default:
+		return mcp.ToolCallParams{}, fmt.Errorf("%w: arguments must be an object", mcp.ErrInvalidParams)
 	}
 
 	return mcp.ToolCallParams{
@@ -344,17 +773,17 @@ func (s *Server) parseToolCallParams(params any) (mcp.ToolCallParams, error) {
 
 func (s *Server) parseResourceParams(params any) (mcp.ResourceParams, error) {
 	if params == nil {
-		return mcp.ResourceParams{}, fmt.Errorf("params cannot be nil")
+		return mcp.ResourceParams{}, fmt.Errorf("%w: params cannot be nil", mcp.ErrInvalidParams)
 	}
 
 	paramsMap, ok := params.(map[string]any)
 	if !ok {
-		return mcp.ResourceParams{}, fmt.Errorf("params must be an object")
+		return mcp.ResourceParams{}, fmt.Errorf("%w: params must be an object", mcp.ErrInvalidParams)
 	}
 
 	uri, ok := paramsMap["uri"].(string)
 	if !ok {
-		return mcp.ResourceParams{}, fmt.Errorf("uri parameter is required and must be a string")
+		return mcp.ResourceParams{}, fmt.Errorf("%w: uri parameter is required and must be a string", mcp.ErrInvalidParams)
 	}
 
 	return mcp.ResourceParams{URI: uri}, nil
@@ -362,17 +791,17 @@ func (s *Server) parseResourceParams(params any) (mcp.ResourceParams, error) {
 
 func (s *Server) parsePromptParams(params any) (mcp.PromptParams, error) {
 	if params == nil {
-		return mcp.PromptParams{}, fmt.Errorf("params cannot be nil")
+		return mcp.PromptParams{}, fmt.Errorf("%w: params cannot be nil", mcp.ErrInvalidParams)
 	}
 
 	paramsMap, ok := params.(map[string]any)
 	if !ok {
-		return mcp.PromptParams{}, fmt.Errorf("params must be an object")
+		return mcp.PromptParams{}, fmt.Errorf("%w: params must be an object", mcp.ErrInvalidParams)
 	}
 
 	name, ok := paramsMap["name"].(string)
 	if !ok {
-		return mcp.PromptParams{}, fmt.Errorf("name parameter is required and must be a string")
+		return mcp.PromptParams{}, fmt.Errorf("%w: name parameter is required and must be a string", mcp.ErrInvalidParams)
 	}
 
 	args := make(map[string]any)
@@ -388,6 +817,23 @@ func (s *Server) parsePromptParams(params any) (mcp.PromptParams, error) {
 	}, nil
 }
 
+// progressToken extracts the progress token from the request's _meta, if any.
+func progressToken(params any) any {
+	if decoded, ok := params.(*toolCallRequest); ok {
+		return decoded.progressToken()
+	}
+
+	paramsMap, ok := params.(map[string]any)
+	if !ok {
+		return nil
+	}
+	meta, ok := paramsMap["_meta"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return meta["progressToken"]
+}
+
 func createDefaultLogger(logLevel string, logJSON bool) *slog.Logger {
 	var handler slog.Handler
 
@@ -1,8 +1,22 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -126,7 +140,7 @@ func TestNewMCPServerDefaults(t *testing.T) {
 	}
 }
 
-func TestNewMCPServerValidation(t *testing.T) {
+func TestCapabilitiesFromHandlers(t *testing.T) {
 	handler := &handlers.TeaHandler{}
 
 	tests := []struct {
@@ -134,23 +148,2740 @@ func TestNewMCPServerValidation(t *testing.T) {
 		toolHandler     mcp.ToolHandler
 		resourceHandler mcp.ResourceHandler
 		promptHandler   mcp.PromptHandler
-		expectError     bool
+		opts            []Option
+		want            []string
 	}{
-		{"nil tool handler", nil, handler, handler, true},
-		{"nil resource handler", handler, nil, handler, true},
-		{"nil prompt handler", handler, handler, nil, true},
-		{"all handlers valid", handler, handler, handler, false},
+		{"all handlers", handler, handler, handler, nil, []string{"elicitation", "experimental", "logging", "prompts", "resources", "tools"}},
+		{"tools only", handler, nil, nil, nil, []string{"elicitation", "experimental", "logging", "tools"}},
+		{"no handlers", nil, nil, nil, nil, []string{"elicitation", "logging"}},
+		{"built-in tool and docs", nil, nil, nil, []Option{WithSessionInfoTool(true), WithToolDocs(true)}, []string{"elicitation", "experimental", "logging", "resources", "tools"}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewMCPServer("Test", "1.0.0", tt.toolHandler, tt.resourceHandler, tt.promptHandler)
-			if tt.expectError && err == nil {
-				t.Errorf("Expected error but got none")
+			server, err := NewMCPServer("Test", "1.0.0", tt.toolHandler, tt.resourceHandler, tt.promptHandler, tt.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
 			}
-			if !tt.expectError && err != nil {
-				t.Errorf("Expected no error but got: %v", err)
+			init, err := server.Initialize(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
 			}
+
+			data, err := json.Marshal(init.Capabilities)
+			if err != nil {
+				t.Fatalf("Failed to marshal capabilities: %v", err)
+			}
+			var capabilities map[string]json.RawMessage
+			if err := json.Unmarshal(data, &capabilities); err != nil {
+				t.Fatalf("Failed to decode capabilities: %v", err)
+			}
+			got := slices.Sorted(maps.Keys(capabilities))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected capabilities %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	server, err := NewMCPServer("Test", "1.0.0", handler, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, method := range []string{"resources/list", "prompts/get"} {
+		sender := &recordingSender{}
+		if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  method,
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(sender.responses) != 1 || sender.responses[0].Error == nil || sender.responses[0].Error.Code != mcp.ErrorCodeMethodNotFound {
+			t.Errorf("Expected %s to be method not found, got %+v", method, sender.responses)
+		}
+	}
+}
+
+// TestCapabilitiesWireFormat pins the initialize payload byte for byte, since
+// clients hash it to detect changes. Update it only for intended changes.
+func TestCapabilitiesWireFormat(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			"all handlers",
+			[]Option{WithCompletionHandler(handler)},
+			`{"protocolVersion":"2025-06-18","capabilities":{"completions":{},"elicitation":{},"experimental":{"listChanges":{}},"logging":{},"prompts":{"listChanged":true},"resources":{"listChanged":true,"templates":true},"tools":{"listChanged":true}},"serverInfo":{"name":"Test","version":"1.0.0"}}`,
+		},
+		{
+			"no completions",
+			nil,
+			`{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{},"experimental":{"listChanges":{}},"logging":{},"prompts":{"listChanged":true},"resources":{"listChanged":true,"templates":true},"tools":{"listChanged":true}},"serverInfo":{"name":"Test","version":"1.0.0"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, tt.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for range 10 {
+				init, err := server.Initialize(context.Background())
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				data, err := json.Marshal(init)
+				if err != nil {
+					t.Fatalf("Failed to marshal initialize response: %v", err)
+				}
+				if string(data) != tt.want {
+					t.Fatalf("Expected initialize response\n%s\ngot\n%s", tt.want, data)
+				}
+			}
+		})
+	}
+
+	// The catalogs must marshal identically on every listing, too
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, method := range []string{"tools/list", "resources/list", "resources/templates/list", "prompts/list"} {
+		var first []byte
+		for range 10 {
+			sender := &recordingSender{}
+			if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+				JSONRPC: mcp.JSONRPCVersion,
+				ID:      mcp.NewIntID(1),
+				Method:  method,
+			}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(sender.responses) != 1 || sender.responses[0].Error != nil {
+				t.Fatalf("Expected %s to succeed, got %+v", method, sender.responses)
+			}
+			data, err := json.Marshal(sender.responses[0].Result)
+			if err != nil {
+				t.Fatalf("Failed to marshal %s result: %v", method, err)
+			}
+			if first == nil {
+				first = data
+			} else if !bytes.Equal(data, first) {
+				t.Fatalf("Expected %s to marshal deterministically, got\n%s\nand\n%s", method, first, data)
+			}
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	var out bytes.Buffer
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithReadyOutput(&out))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	ctx = context.WithValue(ctx, mcp.ResponseSenderKey, sender)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(ctx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			Method:  "tools/call",
+			ID:      mcp.NewIntID(1),
+			Params:  map[string]any{"name": "getTeaNames"},
+		})
+	}()
+	<-handler.started
+
+	// The blocked request does not finish in time and is aborted
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected in-flight request to be aborted")
+	}
+
+	// New requests are rejected
+	if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/list", ID: mcp.NewIntID(2)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	last := sender.responses[len(sender.responses)-1]
+	if last.ID != mcp.NewIntID(2) || last.Error == nil || last.Error.Code != mcp.ErrorCodeInternalError {
+		t.Errorf("Expected request during shutdown to be rejected, got %+v", last)
+	}
+
+	server.EndSession("session-1")
+	if err := server.AnnounceShutdown(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var report ShutdownReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if report.Event != "shutdown" {
+		t.Errorf("Expected event 'shutdown', got %q", report.Event)
+	}
+	if report.RequestsAborted != 1 || report.RequestsDrained != 0 {
+		t.Errorf("Expected 1 aborted and 0 drained requests, got %+v", report)
+	}
+	if report.SessionsClosed != 1 {
+		t.Errorf("Expected 1 closed session, got %d", report.SessionsClosed)
+	}
+	if report.RequestsServed < 3 || report.Errors < 1 {
+		t.Errorf("Expected served requests and errors to be counted, got %+v", report)
+	}
+}
+
+func TestAnnounceReady(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	var out bytes.Buffer
+
+	server, err := NewMCPServer("Ready Server", "1.2.3", handler, handler, handler, WithReadyOutput(&out))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := server.AnnounceReady(context.Background(), "http", 8080); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("Expected exactly one line, got %q", out.String())
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(out.Bytes(), &event); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	if event["event"] != "ready" {
+		t.Errorf("Expected event 'ready', got %v", event["event"])
+	}
+	if event["transport"] != "http" {
+		t.Errorf("Expected transport 'http', got %v", event["transport"])
+	}
+	if event["port"] != float64(8080) {
+		t.Errorf("Expected port 8080, got %v", event["port"])
+	}
+	if event["tools"] != float64(3) {
+		t.Errorf("Expected 3 tools, got %v", event["tools"])
+	}
+	if event["prompts"] != float64(3) {
+		t.Errorf("Expected 3 prompts, got %v", event["prompts"])
+	}
+}
+
+// recordingSender captures everything the server sends to a client.
+type recordingSender struct {
+	mu            sync.Mutex
+	responses     []mcp.Response
+	notifications []mcp.Notification
+	requests      []mcp.Request
+}
+
+func (r *recordingSender) SendResponse(response mcp.Response) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, response)
+	return nil
+}
+
+func (r *recordingSender) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return r.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error:   &mcp.ErrorResponse{Code: code, Message: message, Data: data},
+	})
+}
+
+func (r *recordingSender) SendNotification(notification mcp.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *recordingSender) SendRequest(req mcp.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	return nil
+}
+
+// initializeSession completes the lifecycle handshake for a session.
+func initializeSession(t *testing.T, server *Server, sessionID string, params map[string]any) {
+	t.Helper()
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, sessionID)
+	if params == nil {
+		params = map[string]any{"protocolVersion": mcp.ProtocolVersion}
+	}
+	if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, &recordingSender{}), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      mcp.NewStringID("init"),
+		Method:  "initialize",
+		Params:  params,
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationInitialized,
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestNotifyListChanged(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	first := &recordingSender{}
+	second := &recordingSender{}
+	gone := &recordingSender{}
+	server.RegisterSession("first", first)
+	server.RegisterSession("second", second)
+	server.RegisterSession("gone", gone)
+	server.UnregisterSession("gone")
+
+	ctx := context.Background()
+	if err := server.NotifyToolsListChanged(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.NotifyPromptsListChanged(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for name, sender := range map[string]*recordingSender{"first": first, "second": second} {
+		if len(sender.notifications) != 2 {
+			t.Fatalf("Expected 2 notifications for session %s, got %d", name, len(sender.notifications))
+		}
+		if sender.notifications[0].Method != mcp.NotificationToolsListChanged {
+			t.Errorf("Expected method %s, got %s", mcp.NotificationToolsListChanged, sender.notifications[0].Method)
+		}
+		if sender.notifications[1].Method != mcp.NotificationPromptsListChanged {
+			t.Errorf("Expected method %s, got %s", mcp.NotificationPromptsListChanged, sender.notifications[1].Method)
+		}
+		if sender.notifications[0].JSONRPC != mcp.JSONRPCVersion {
+			t.Errorf("Expected JSON-RPC version %s, got %s", mcp.JSONRPCVersion, sender.notifications[0].JSONRPC)
+		}
+	}
+
+	if len(gone.notifications) != 0 {
+		t.Errorf("Expected no notifications for unregistered session, got %d", len(gone.notifications))
+	}
+}
+
+func TestLoggerFromContextInHandlers(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	handler := &loggingToolHandler{TeaHandler: &handlers.TeaHandler{}}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, &recordingSender{})
+	ctx = context.WithValue(ctx, mcp.SessionIDKey, "session-1")
+	req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/list", ID: mcp.NewIntID(7)}
+	if err := server.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected JSON log line, got %q", line)
+		}
+		if entry["msg"] != "handler log" {
+			continue
+		}
+		found = true
+		if entry["session"] != "session-1" {
+			t.Errorf("Expected session 'session-1', got %v", entry["session"])
+		}
+		if entry["request_id"] != float64(7) {
+			t.Errorf("Expected request_id 7, got %v", entry["request_id"])
+		}
+	}
+	if !found {
+		t.Error("Expected handler log entry to be written through the server logger")
+	}
+}
+
+// loggingToolHandler logs through the context logger before delegating.
+type loggingToolHandler struct {
+	*handlers.TeaHandler
+}
+
+func (h *loggingToolHandler) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	mcp.LoggerFromContext(ctx).Info("handler log")
+	return h.TeaHandler.ListTools(ctx)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "ping", ID: mcp.NewIntID(1)}
+
+	if err := server.HandleRequest(context.Background(), req); !errors.Is(err, mcp.ErrMissingResponseSender) {
+		t.Errorf("Expected ErrMissingResponseSender, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	ctx = context.WithValue(ctx, mcp.ResponseSenderKey, &recordingSender{})
+	if err := server.HandleRequest(ctx, req); !errors.Is(err, mcp.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+
+	_, err = handler.CallTool(context.Background(), mcp.ToolCallParams{Name: "brewCoffee"})
+	if !errors.Is(err, mcp.ErrToolNotFound) {
+		t.Errorf("Expected ErrToolNotFound, got %v", err)
+	}
+
+	_, err = server.parseToolCallParams("not an object")
+	if !errors.Is(err, mcp.ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams, got %v", err)
+	}
+}
+
+// progressToolHandler reports progress from CallTool before delegating.
+type progressToolHandler struct {
+	*handlers.TeaHandler
+}
+
+func (h *progressToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	progress := mcp.ProgressFromContext(ctx)
+	for i := 1; i <= 2; i++ {
+		if err := progress.Report(float64(i), 2, fmt.Sprintf("step %d", i)); err != nil {
+			return mcp.ToolResponse{}, err
+		}
+	}
+	return h.TeaHandler.CallTool(ctx, params)
+}
+
+func TestProgressNotifications(t *testing.T) {
+	handler := &progressToolHandler{TeaHandler: &handlers.TeaHandler{}}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		params        map[string]any
+		notifications int
+	}{
+		{
+			name: "with progress token",
+			params: map[string]any{
+				"name":  "getTeaNames",
+				"_meta": map[string]any{"progressToken": "abc"},
+			},
+			notifications: 2,
+		},
+		{
+			name:          "without progress token",
+			params:        map[string]any{"name": "getTeaNames"},
+			notifications: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &recordingSender{}
+			ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+			req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: mcp.NewIntID(1), Params: tt.params}
+
+			if err := server.HandleRequest(ctx, req); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(sender.notifications) != tt.notifications {
+				t.Fatalf("Expected %d notifications, got %d", tt.notifications, len(sender.notifications))
+			}
+			if len(sender.responses) != 1 || sender.responses[0].Error != nil {
+				t.Fatalf("Expected one successful response, got %+v", sender.responses)
+			}
+			if tt.notifications == 0 {
+				return
+			}
+
+			params, ok := sender.notifications[1].Params.(mcp.ProgressParams)
+			if !ok {
+				t.Fatalf("Expected ProgressParams, got %T", sender.notifications[1].Params)
+			}
+			if sender.notifications[1].Method != mcp.NotificationProgress {
+				t.Errorf("Expected method %s, got %s", mcp.NotificationProgress, sender.notifications[1].Method)
+			}
+			if params.ProgressToken != "abc" || params.Progress != 2 || params.Total != 2 || params.Message != "step 2" {
+				t.Errorf("Unexpected progress params: %+v", params)
+			}
+		})
+	}
+}
+
+// blockingToolHandler blocks in CallTool until its context is done.
+type blockingToolHandler struct {
+	*handlers.TeaHandler
+	started chan struct{}
+}
+
+func (h *blockingToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	close(h.started)
+	<-ctx.Done()
+	return mcp.ToolResponse{}, ctx.Err()
+}
+
+func TestCancelledNotification(t *testing.T) {
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	reqCtx := context.WithValue(ctx, mcp.ResponseSenderKey, sender)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(reqCtx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			Method:  "tools/call",
+			ID:      mcp.NewIntID(42),
+			Params:  map[string]any{"name": "getTeaNames"},
+		})
+	}()
+
+	<-handler.started
+
+	// A cancellation from another session must not affect the request
+	otherCtx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-2")
+	if err := server.HandleNotification(otherCtx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  map[string]any{"requestId": float64(42)},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("Expected request to keep running after cancellation from another session")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  map[string]any{"requestId": float64(42), "reason": "user aborted"},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected request to be cancelled")
+	}
+
+	if len(sender.responses) != 0 {
+		t.Errorf("Expected no response for cancelled request, got %+v", sender.responses)
+	}
+}
+
+func TestSLOTracking(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	var alerts []SLOStatus
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler,
+		WithSLO(SLO{Method: "ping", Percentile: 0.99, Threshold: time.Nanosecond, MinSamples: 2}),
+		WithSLO(SLO{Method: "tools/list", Percentile: 0.5, Threshold: time.Hour}),
+		WithAlertSink(func(ctx context.Context, status SLOStatus) {
+			alerts = append(alerts, status)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, &recordingSender{})
+	ping := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "ping", ID: mcp.NewIntID(1)}
+
+	if err := server.HandleRequest(ctx, ping); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !server.Ready() {
+		t.Error("Expected server to be ready below the minimum sample count")
+	}
+
+	if err := server.HandleRequest(ctx, ping); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if server.Ready() {
+		t.Error("Expected server not to be ready once the SLO is breached")
+	}
+
+	if len(alerts) != 1 || !alerts[0].Breached || alerts[0].Method != "ping" {
+		t.Fatalf("Expected one breach alert for ping, got %+v", alerts)
+	}
+
+	statuses := server.SLOStatus()
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 SLO statuses, got %d", len(statuses))
+	}
+	if statuses[0].Samples != 2 || !statuses[0].Breached {
+		t.Errorf("Expected breached ping SLO with 2 samples, got %+v", statuses[0])
+	}
+	if statuses[1].Samples != 0 || statuses[1].Breached {
+		t.Errorf("Expected healthy tools/list SLO without samples, got %+v", statuses[1])
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	limiter := newAdaptiveLimiter(AdaptiveConcurrency{
+		InitialLimit:     2,
+		MinLimit:         1,
+		MaxLimit:         4,
+		LatencyThreshold: time.Second,
+		BackoffRatio:     0.5,
+	})
+
+	first, ok := limiter.acquire("slow")
+	if !ok {
+		t.Fatal("Expected first call to be admitted")
+	}
+	second, ok := limiter.acquire("slow")
+	if !ok {
+		t.Fatal("Expected second call to be admitted")
+	}
+	if _, ok := limiter.acquire("slow"); ok {
+		t.Fatal("Expected third call to be rejected at limit 2")
+	}
+	if _, ok := limiter.acquire("other"); !ok {
+		t.Fatal("Expected limits to be tracked per tool")
+	}
+
+	// Congestion halves the limit
+	first(2*time.Second, false)
+	if got := limiter.limit("slow"); got != 1 {
+		t.Errorf("Expected limit 1 after slow call, got %d", got)
+	}
+
+	// Failures never push the limit below the minimum
+	second(time.Millisecond, true)
+	if got := limiter.limit("slow"); got != 1 {
+		t.Errorf("Expected limit to stay at minimum 1, got %d", got)
+	}
+
+	// Fast successes increase the limit additively
+	for range 3 {
+		release, ok := limiter.acquire("slow")
+		if !ok {
+			t.Fatal("Expected call to be admitted below the limit")
+		}
+		release(time.Millisecond, false)
+	}
+	if got := limiter.limit("slow"); got != 2 {
+		t.Errorf("Expected limit 2 after fast successes, got %d", got)
+	}
+}
+
+func TestInitializeProtocolNegotiation(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		requested any
+		expected  string
+	}{
+		{"latest version", mcp.ProtocolVersion, mcp.ProtocolVersion},
+		{"older supported version", "2025-03-26", "2025-03-26"},
+		{"unsupported version", "2024-11-05", mcp.ProtocolVersion},
+		{"missing version", nil, mcp.ProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &recordingSender{}
+			ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+			params := map[string]any{}
+			if tt.requested != nil {
+				params["protocolVersion"] = tt.requested
+			}
+
+			if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "initialize", ID: mcp.NewIntID(1), Params: params}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			result, ok := sender.responses[0].Result.(*mcp.InitializeResponse)
+			if !ok {
+				t.Fatalf("Expected InitializeResponse, got %T", sender.responses[0].Result)
+			}
+			if result.ProtocolVersion != tt.expected {
+				t.Errorf("Expected protocol version %s, got %s", tt.expected, result.ProtocolVersion)
+			}
+		})
+	}
+}
+
+// structuredToolHandler returns only structured content from CallTool.
+type structuredToolHandler struct {
+	*handlers.TeaHandler
+}
+
+func (h *structuredToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	return mcp.ToolResponse{StructuredContent: map[string]any{"temperature": 175}}, nil
+}
+
+func TestStructuredContentTextFallback(t *testing.T) {
+	handler := &structuredToolHandler{TeaHandler: &handlers.TeaHandler{}}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: mcp.NewIntID(1), Params: map[string]any{"name": "anything"}}
+	if err := server.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, ok := sender.responses[0].Result.(mcp.ToolResponse)
+	if !ok {
+		t.Fatalf("Expected ToolResponse, got %T", sender.responses[0].Result)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != "text" || result.Content[0].Text != `{"temperature":175}` {
+		t.Errorf("Expected serialized structured content as text block, got %+v", result.Content)
+	}
+	if result.StructuredContent == nil {
+		t.Error("Expected structured content to be preserved")
+	}
+}
+
+func TestHousekeepingBypassesConcurrencyLimit(t *testing.T) {
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler,
+		WithAdaptiveConcurrency(AdaptiveConcurrency{InitialLimit: 1, MaxLimit: 1}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	call := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: mcp.NewStringID("call"), Params: map[string]any{"name": "getTeaNames"}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, &recordingSender{}), call)
+	}()
+	<-handler.started
+
+	// The tool is saturated: another call is rejected
+	rejected := &recordingSender{}
+	call.ID = mcp.NewStringID("rejected")
+	if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, rejected), call); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rejected.responses) != 1 || rejected.responses[0].Error == nil {
+		t.Fatalf("Expected overloaded error, got %+v", rejected.responses)
+	}
+
+	// Housekeeping still goes through
+	for _, method := range []string{"initialize", "ping"} {
+		sender := &recordingSender{}
+		req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: method, ID: mcp.NewStringID(method)}
+		if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, sender), req); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", method, err)
+		}
+		if len(sender.responses) != 1 || sender.responses[0].Error != nil {
+			t.Errorf("Expected successful %s response while saturated, got %+v", method, sender.responses)
+		}
+	}
+
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  map[string]any{"requestId": "call"},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected saturated call to be cancellable")
+	}
+}
+
+// replyingClient answers server-initiated requests by calling back into the server.
+type replyingClient struct {
+	recordingSender
+	server  *Server
+	session string
+	reply   func(req mcp.Request) mcp.Response
+}
+
+func (c *replyingClient) SendRequest(req mcp.Request) error {
+	go func() {
+		ctx := context.WithValue(context.Background(), mcp.SessionIDKey, c.session)
+		if err := c.server.HandleResponse(ctx, c.reply(req)); err != nil {
+			panic(err)
+		}
+	}()
+	return nil
+}
+
+func TestRequestSampling(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var received mcp.Request
+	client := &replyingClient{server: server, session: "client", reply: func(req mcp.Request) mcp.Response {
+		received = req
+		return mcp.Response{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      req.ID,
+			Result: map[string]any{
+				"role":       "assistant",
+				"content":    map[string]any{"type": "text", "text": "Try a sencha."},
+				"model":      "test-model",
+				"stopReason": "endTurn",
+			},
+		}
+	}}
+	server.RegisterSession("client", client)
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "client")
+	if _, err := server.RequestSampling(ctx, mcp.CreateMessageRequest{MaxTokens: 10}); !errors.Is(err, mcp.ErrNotInitialized) {
+		t.Fatalf("Expected ErrNotInitialized before the handshake, got %v", err)
+	}
+	initializeSession(t, server, "client", nil)
+
+	result, err := server.RequestSampling(ctx, mcp.CreateMessageRequest{
+		Messages: []mcp.SamplingMessage{{
+			Role:    "user",
+			Content: mcp.MessageContent{Type: "text", Text: "Which tea should I drink?"},
+		}},
+		MaxTokens: 100,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if received.Method != mcp.MethodSamplingCreateMessage {
+		t.Errorf("Expected method %s, got %s", mcp.MethodSamplingCreateMessage, received.Method)
+	}
+	if result.Model != "test-model" || result.Content.Text != "Try a sencha." || result.StopReason != "endTurn" {
+		t.Errorf("Unexpected sampling result: %+v", result)
+	}
+
+	client.reply = func(req mcp.Request) mcp.Response {
+		return mcp.Response{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      req.ID,
+			Error:   &mcp.ErrorResponse{Code: -1, Message: "User rejected sampling request"},
+		}
+	}
+	_, err = server.RequestSampling(ctx, mcp.CreateMessageRequest{MaxTokens: 10})
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("Expected ClientError, got %v", err)
+	}
+
+	_, err = server.RequestSampling(context.Background(), mcp.CreateMessageRequest{MaxTokens: 10})
+	if !errors.Is(err, mcp.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound without a session, got %v", err)
+	}
+
+	if err := server.HandleResponse(ctx, mcp.Response{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewStringID("unknown")}); err == nil {
+		t.Error("Expected error for response to unknown request")
+	}
+
+	// Another session cannot answer the client's requests
+	initializeSession(t, server, "other", nil)
+	var hijacked error
+	client.reply = func(req mcp.Request) mcp.Response {
+		if !strings.HasPrefix(req.ID.String(), "server-") || req.ID == received.ID {
+			t.Errorf("Expected a random request ID, got %v", req.ID)
+		}
+		forged := mcp.Response{JSONRPC: mcp.JSONRPCVersion, ID: req.ID, Result: map[string]any{"model": "forged"}}
+		hijacked = server.HandleResponse(context.WithValue(context.Background(), mcp.SessionIDKey, "other"), forged)
+		return mcp.Response{JSONRPC: mcp.JSONRPCVersion, ID: req.ID, Result: map[string]any{"model": "test-model"}}
+	}
+	result, err = server.RequestSampling(ctx, mcp.CreateMessageRequest{MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hijacked == nil {
+		t.Error("Expected response from another session to be rejected")
+	}
+	if result.Model != "test-model" {
+		t.Errorf("Expected the client's own result, got %+v", result)
+	}
+}
+
+func TestListRoots(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var mu sync.Mutex
+	requests := 0
+	client := &replyingClient{server: server, session: "client", reply: func(req mcp.Request) mcp.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		return mcp.Response{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      req.ID,
+			Result: map[string]any{
+				"roots": []any{map[string]any{"uri": "file:///home/user/project", "name": "project"}},
+			},
+		}
+	}}
+	server.RegisterSession("client", client)
+	initializeSession(t, server, "client", nil)
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "client")
+
+	for range 2 {
+		roots, err := server.ListRoots(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(roots) != 1 || roots[0].URI != "file:///home/user/project" || roots[0].Name != "project" {
+			t.Fatalf("Unexpected roots: %+v", roots)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected roots to be cached after the first request, got %d requests", requests)
+	}
+
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationRootsListChanged,
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := server.ListRoots(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected roots to be re-fetched after list_changed, got %d requests", requests)
+	}
+}
+
+func TestOnInitialized(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	roots := make(chan []mcp.Root, 2)
+	var server *Server
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithOnInitialized(func(ctx context.Context) {
+		// Hooks may wait for client responses
+		result, err := server.ListRoots(ctx)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		roots <- result
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	client := &replyingClient{server: server, session: "client", reply: func(req mcp.Request) mcp.Response {
+		return mcp.Response{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      req.ID,
+			Result:  map[string]any{"roots": []any{map[string]any{"uri": "file:///project"}}},
+		}
+	}}
+	server.RegisterSession("client", client)
+	initializeSession(t, server, "client", nil)
+
+	select {
+	case result := <-roots:
+		if len(result) != 1 || result[0].URI != "file:///project" {
+			t.Errorf("Unexpected roots: %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected hook to run after notifications/initialized")
+	}
+
+	// A repeated notification does not run the hook again
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "client")
+	if err := server.HandleNotification(ctx, mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: mcp.NotificationInitialized}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case <-roots:
+		t.Error("Expected hook to run only once per session")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTranscripts(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithTranscripts(Transcripts{MaxSessions: 1}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	ctx = context.WithValue(ctx, mcp.ResponseSenderKey, sender)
+	if err := server.HandleRequest(ctx, mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  "tools/call",
+		ID:      mcp.NewIntID(2),
+		Params:  json.RawMessage(`{"name":"getTeaInfo","arguments":{"name":"secret"}}`),
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.LogToClient(ctx, mcp.LoggingLevelWarning, "test", "careful"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	transcript, ok := server.Transcript("session-1")
+	if !ok {
+		t.Fatal("Expected transcript for session-1")
+	}
+	var kinds []string
+	for _, entry := range transcript.Entries {
+		kinds = append(kinds, entry.Direction+" "+entry.Kind+" "+entry.Method)
+	}
+	expected := []string{
+		"in request initialize",
+		"out response ",
+		"in notification notifications/initialized",
+		"in request tools/call",
+		"out response ",
+		"out notification notifications/message",
+	}
+	if !slices.Equal(kinds, expected) {
+		t.Fatalf("Expected entries %v, got %v", expected, kinds)
+	}
+
+	call := string(transcript.Entries[3].Message)
+	if strings.Contains(call, "secret") || !strings.Contains(call, `"arguments":{"name":"[REDACTED]"}`) {
+		t.Errorf("Expected tool arguments to be redacted, got %s", call)
+	}
+	if markdown := transcript.Markdown(); !strings.Contains(markdown, "## ") || !strings.Contains(markdown, "client → server: request `tools/call`") {
+		t.Errorf("Unexpected Markdown transcript: %s", markdown)
+	}
+
+	// Only the most recent session is retained
+	initializeSession(t, server, "session-2", nil)
+	if sessions := server.TranscriptSessions(); !slices.Equal(sessions, []string{"session-2"}) {
+		t.Errorf("Expected only session-2 to be retained, got %v", sessions)
+	}
+}
+
+func TestInputSanitization(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithInputSanitization(true))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	if err := server.HandleRequest(ctx, mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      mcp.NewIntID(1),
+		Method:  "tools/call",
+		Params: map[string]any{
+			"name":      "getTeaInfo",
+			"arguments": map[string]any{"name": "gyo\x00kuro\x1b"},
+		},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(sender.responses) != 1 || sender.responses[0].Error != nil {
+		t.Fatalf("Expected successful response for sanitized arguments, got %+v", sender.responses)
+	}
+
+	got := mcp.SanitizeParams(map[string]any{
+		"text":   "line one\nline two\tend\u0085\x7f",
+		"nested": []any{"ok", "bad\x07", 42.0},
+	}).(map[string]any)
+	if got["text"] != "line one\nline two\tend" {
+		t.Errorf("Unexpected sanitized text %q", got["text"])
+	}
+	if nested := got["nested"].([]any); nested[1] != "bad" || nested[2] != 42.0 {
+		t.Errorf("Unexpected sanitized nested values %v", nested)
+	}
+}
+
+func TestCompletionComplete(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+
+	complete := func(server *Server, params any) mcp.Response {
+		t.Helper()
+		sender := &recordingSender{}
+		ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+		if err := server.HandleRequest(ctx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  mcp.MethodCompletionComplete,
+			Params:  params,
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(sender.responses) != 1 {
+			t.Fatalf("Expected 1 response, got %d", len(sender.responses))
+		}
+		return sender.responses[0]
+	}
+
+	params := map[string]any{
+		"ref":      map[string]any{"type": mcp.CompletionRefPrompt, "name": "brewing_guide"},
+		"argument": map[string]any{"name": "tea_name", "value": "da"},
+	}
+
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp := complete(server, params); resp.Error == nil || resp.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("Expected method not found without a completion handler, got %+v", resp)
+	}
+	init, _ := server.Initialize(context.Background())
+	if init.Capabilities.Completions != nil {
+		t.Error("Expected no completions capability without a completion handler")
+	}
+
+	server, err = NewMCPServer("Test", "1.0.0", handler, handler, handler, WithCompletionHandler(handler))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	init, _ = server.Initialize(context.Background())
+	if init.Capabilities.Completions == nil {
+		t.Error("Expected completions capability with a completion handler")
+	}
+
+	resp := complete(server, params)
+	if resp.Error != nil {
+		t.Fatalf("Expected no error, got %+v", resp.Error)
+	}
+	result, ok := resp.Result.(mcp.CompleteResponse)
+	if !ok {
+		t.Fatalf("Expected CompleteResponse, got %T", resp.Result)
+	}
+	if len(result.Completion.Values) != 1 || result.Completion.Values[0] != "da-hong-pao" {
+		t.Errorf("Unexpected completion values %v", result.Completion.Values)
+	}
+
+	resp = complete(server, map[string]any{
+		"ref":      map[string]any{"type": "ref/unknown"},
+		"argument": map[string]any{"name": "tea_name", "value": ""},
+	})
+	if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("Expected invalid params for unknown ref type, got %+v", resp)
+	}
+}
+
+func TestLocaleHints(t *testing.T) {
+	handler := &localeToolHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, &handlers.TeaHandler{}, &handlers.TeaHandler{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "client", nil)
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	ctx = context.WithValue(ctx, mcp.SessionIDKey, "client")
+	ctx = context.WithValue(ctx, mcp.LocaleKey, mcp.Locale{Language: "fr"})
+
+	call := func() mcp.Locale {
+		t.Helper()
+		if err := server.HandleRequest(ctx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(2),
+			Method:  "tools/call",
+			Params:  map[string]any{"name": "locale"},
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return handler.locale
+	}
+
+	if locale := call(); locale.Language != "fr" || locale.Timezone() != time.UTC {
+		t.Errorf("Expected transport locale fr in UTC, got %q in %v", locale.Language, locale.Timezone())
+	}
+
+	initializeSession(t, server, "client", map[string]any{
+		"protocolVersion": mcp.ProtocolVersion,
+		"_meta":           map[string]any{"locale": "de_de", "timezone": "UTC"},
+	})
+
+	if locale := call(); locale.Language != "de-DE" || locale.Location == nil {
+		t.Errorf("Expected initialize hints to take precedence, got %q in %v", locale.Language, locale.Location)
+	}
+
+	if got := mcp.ParseAcceptLanguage("da, en-gb;q=0.8, en;q=0.7"); got != "da" {
+		t.Errorf("Expected da, got %q", got)
+	}
+	if got := mcp.ParseAcceptLanguage("en-gb;q=0.5, pt-BR"); got != "pt-BR" {
+		t.Errorf("Expected pt-BR, got %q", got)
+	}
+	if got := mcp.NormalizeLanguage("not a tag"); got != "" {
+		t.Errorf("Expected empty tag for invalid input, got %q", got)
+	}
+}
+
+// localeToolHandler records the locale seen by CallTool.
+type localeToolHandler struct {
+	handlers.TeaHandler
+	locale mcp.Locale
+}
+
+func (h *localeToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	h.locale = mcp.LocaleFromContext(ctx)
+	return mcp.ToolResponse{Content: []mcp.ContentItem{{Type: "text", Text: h.locale.Language}}}, nil
+}
+
+func TestLogToClient(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	ctx = context.WithValue(ctx, mcp.SessionIDKey, "client")
+
+	if err := server.LogToClient(ctx, mcp.LoggingLevelDebug, "tea", "hidden by default"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.LogToClient(ctx, mcp.LoggingLevelInfo, "tea", "brewing"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sender.notifications) != 1 {
+		t.Fatalf("Expected only info message with default level, got %d", len(sender.notifications))
+	}
+	params, ok := sender.notifications[0].Params.(mcp.LoggingMessageParams)
+	if !ok || sender.notifications[0].Method != mcp.NotificationMessage || params.Data != "brewing" || params.Logger != "tea" {
+		t.Errorf("Unexpected notification %+v", sender.notifications[0])
+	}
+
+	setLevel := func(level string) mcp.Response {
+		t.Helper()
+		sender.responses = nil
+		if err := server.HandleRequest(ctx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  mcp.MethodLoggingSetLevel,
+			Params:  map[string]any{"level": level},
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return sender.responses[0]
+	}
+
+	if resp := setLevel("verbose"); resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("Expected invalid params for unknown level, got %+v", resp)
+	}
+	if resp := setLevel("error"); resp.Error != nil {
+		t.Fatalf("Expected no error, got %+v", resp.Error)
+	}
+
+	sender.notifications = nil
+	for _, level := range []mcp.LoggingLevel{mcp.LoggingLevelWarning, mcp.LoggingLevelError, mcp.LoggingLevelCritical} {
+		if err := server.LogToClient(ctx, level, "", map[string]any{"level": level}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if len(sender.notifications) != 2 {
+		t.Errorf("Expected error and critical messages only, got %d", len(sender.notifications))
+	}
+
+	if err := server.LogToClient(ctx, "loud", "", "x"); !errors.Is(err, mcp.ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams for invalid level, got %v", err)
+	}
+}
+
+func TestClientInfoAndCapabilities(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	ctx = context.WithValue(ctx, mcp.SessionIDKey, "client")
+
+	if server.ClientSupportsSampling(ctx) || server.ClientProtocolVersion(ctx) != "" {
+		t.Error("Expected no client state before initialize")
+	}
+
+	if err := server.HandleRequest(ctx, mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      mcp.NewIntID(1),
+		Method:  "initialize",
+		Params: map[string]any{
+			"protocolVersion": "2025-03-26",
+			"capabilities":    map[string]any{"sampling": map[string]any{}},
+			"clientInfo":      map[string]any{"name": "tea-client", "title": "Tea Client", "version": "2.1.0"},
+		},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !server.ClientSupportsSampling(ctx) {
+		t.Error("Expected client to support sampling")
+	}
+	if server.ClientSupportsRoots(ctx) {
+		t.Error("Expected client not to support roots")
+	}
+	if got := server.ClientProtocolVersion(ctx); got != "2025-03-26" {
+		t.Errorf("Expected negotiated version 2025-03-26, got %q", got)
+	}
+
+	info, ok := mcp.ClientInfoFromContext(server.withClientContext(ctx))
+	if !ok || info.Name != "tea-client" || info.Title != "Tea Client" || info.Version != "2.1.0" {
+		t.Errorf("Unexpected client info %+v (ok=%v)", info, ok)
+	}
+
+	other := context.WithValue(ctx, mcp.SessionIDKey, "other")
+	if _, ok := mcp.ClientInfoFromContext(server.withClientContext(other)); ok {
+		t.Error("Expected no client info for an uninitialized session")
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "client")
+	call := func(method string) mcp.Response {
+		t.Helper()
+		sender := &recordingSender{}
+		if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, sender), mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  method,
+			Params:  map[string]any{"protocolVersion": mcp.ProtocolVersion},
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return sender.responses[0]
+	}
+	expectRejected := func(method, phase string) {
+		t.Helper()
+		resp := call(method)
+		if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidRequest {
+			t.Errorf("Expected %s to be rejected while %s, got %+v", method, phase, resp)
+		}
+	}
+
+	expectRejected("tools/list", "uninitialized")
+	if resp := call("ping"); resp.Error != nil {
+		t.Errorf("Expected ping to be allowed before initialization, got %+v", resp.Error)
+	}
+
+	if resp := call("initialize"); resp.Error != nil {
+		t.Fatalf("Expected no error, got %+v", resp.Error)
+	}
+	expectRejected("prompts/list", "initializing")
+
+	if err := server.HandleNotification(ctx, mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: mcp.NotificationInitialized}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp := call("tools/list"); resp.Error != nil {
+		t.Errorf("Expected tools/list after initialization, got %+v", resp.Error)
+	}
+
+	// Requests without a session cannot be tracked and are always served
+	sender := &recordingSender{}
+	if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      mcp.NewIntID(1),
+		Method:  "resources/list",
+	}); err != nil || sender.responses[0].Error != nil {
+		t.Errorf("Expected session-less request to be served, got %v %+v", err, sender.responses)
+	}
+
+	// Ending the session releases its state, and its ID may start a new
+	// session, as when the stdio transport is started again
+	server.EndSession("client")
+	if n := len(server.clients); n != 0 {
+		t.Errorf("Expected the ended session's state to be released, got %d states", n)
+	}
+	expectRejected("tools/list", "uninitialized")
+	if resp := call("initialize"); resp.Error != nil {
+		t.Errorf("Expected a new session to be initialized, got %+v", resp.Error)
+	}
+}
+
+func TestEndSessionWithRequestsInFlight(t *testing.T) {
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+	initializeSession(t, server, "session-2", nil)
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(context.WithValue(reqCtx, mcp.ResponseSenderKey, &recordingSender{}), mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			Method:  "tools/call",
+			ID:      mcp.NewIntID(1),
+			Params:  map[string]any{"name": "getTeaNames"},
+		})
+	}()
+	<-handler.started
+
+	// The session expires while its request is in flight
+	server.EndSession("session-1")
+	server.EndSession("session-2")
+
+	sender := &recordingSender{}
+	if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, sender), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  "ping",
+		ID:      mcp.NewIntID(2),
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp := sender.responses[0]; resp.Error == nil || !strings.Contains(resp.Error.Data.(string), "shut down") {
+		t.Errorf("Expected ping to be rejected while the session shuts down, got %+v", resp)
+	}
+
+	server.clientsMu.RLock()
+	_, busyKept := server.clients["session-1"]
+	_, idleKept := server.clients["session-2"]
+	server.clientsMu.RUnlock()
+	if !busyKept || idleKept {
+		t.Errorf("Expected only the state of the session with a request in flight to be kept, got %v and %v", busyKept, idleKept)
+	}
+
+	cancel()
+	<-done
+	if n := len(server.clients); n != 0 {
+		t.Errorf("Expected all session states to be released, got %d", n)
+	}
+}
+
+func TestDuplicateRequestIDs(t *testing.T) {
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	call := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: mcp.NewIntID(7), Params: map[string]any{"name": "getTeaNames"}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, &recordingSender{}), call)
+	}()
+	<-handler.started
+
+	duplicate := &recordingSender{}
+	if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, duplicate), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion, Method: "ping", ID: mcp.NewIntID(7),
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(duplicate.responses) != 1 || duplicate.responses[0].Error == nil || duplicate.responses[0].Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Fatalf("Expected invalid request for duplicate ID, got %+v", duplicate.responses)
+	}
+	if got := server.DuplicateRequestIDs(); got != 1 {
+		t.Errorf("Expected 1 duplicate request ID, got %d", got)
+	}
+
+	// The same ID in another session is unrelated
+	initializeSession(t, server, "session-2", nil)
+	other := &recordingSender{}
+	otherCtx := context.WithValue(context.WithValue(context.Background(), mcp.SessionIDKey, "session-2"), mcp.ResponseSenderKey, other)
+	if err := server.HandleRequest(otherCtx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: "ping", ID: mcp.NewIntID(7)}); err != nil || other.responses[0].Error != nil {
+		t.Errorf("Expected ping with same ID in other session to succeed, got %v %+v", err, other.responses)
+	}
+
+	// Cancellation completes the request and releases its ID
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  map[string]any{"requestId": float64(7)},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	<-done
+
+	reused := &recordingSender{}
+	if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, reused), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion, Method: "ping", ID: mcp.NewIntID(7),
+	}); err != nil || reused.responses[0].Error != nil {
+		t.Errorf("Expected released ID to be reusable, got %v %+v", err, reused.responses)
+	}
+}
+
+func TestSessionInfoTool(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler,
+		WithSessionInfoTool(true),
+		WithAdaptiveConcurrency(AdaptiveConcurrency{InitialLimit: 4}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "client", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"clientInfo":      map[string]any{"name": "inspector", "version": "0.9.0"},
+	})
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.WithValue(context.Background(), mcp.SessionIDKey, "client"), mcp.ResponseSenderKey, sender)
+	for _, req := range []mcp.Request{
+		{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(1), Method: "tools/list"},
+		{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(2), Method: "tools/call", Params: map[string]any{"name": "getTeaNames"}},
+		{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(3), Method: "tools/call", Params: map[string]any{"name": SessionInfoToolName}},
+	} {
+		if err := server.HandleRequest(ctx, req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	tools := sender.responses[0].Result.(map[string][]mcp.Tool)["tools"]
+	if tools[len(tools)-1].Name != SessionInfoToolName {
+		t.Errorf("Expected %s to be listed, got %v", SessionInfoToolName, tools)
+	}
+
+	resp := sender.responses[2]
+	if resp.Error != nil {
+		t.Fatalf("Expected no error, got %+v", resp.Error)
+	}
+	result := resp.Result.(mcp.ToolResponse)
+	info, ok := result.StructuredContent.(SessionInfo)
+	if !ok {
+		t.Fatalf("Expected SessionInfo, got %T", result.StructuredContent)
+	}
+	if info.SessionID != "client" || info.ProtocolVersion != "2025-03-26" || info.Phase != "initialized" {
+		t.Errorf("Unexpected session info %+v", info)
+	}
+	if info.ClientInfo == nil || info.ClientInfo.Name != "inspector" {
+		t.Errorf("Expected client info, got %+v", info.ClientInfo)
+	}
+	if info.Limits.ToolConcurrency["getTeaNames"].Limit < 4 {
+		t.Errorf("Expected tool concurrency limits, got %+v", info.Limits.ToolConcurrency)
+	}
+	if len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, `"sessionId":"client"`) {
+		t.Errorf("Expected JSON text fallback, got %+v", result.Content)
+	}
+}
+
+// imageToolHandler returns the configured content from every tool call.
+type imageToolHandler struct {
+	handlers.TeaHandler
+	content mcp.ContentItem
+}
+
+func (h *imageToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	return mcp.ToolResponse{Content: []mcp.ContentItem{h.content}}, nil
+}
+
+func TestImageContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	image := mcp.NewImageContent(png, "")
+	if image.Type != mcp.ContentTypeImage || image.MimeType != "image/png" {
+		t.Errorf("Expected detected image/png content, got %+v", image)
+	}
+	if data, err := image.DecodeData(); err != nil || !bytes.Equal(data, png) {
+		t.Errorf("Expected data to round-trip, got %v %v", data, err)
+	}
+
+	message := mcp.NewImageMessageContent(png, "image/png")
+	if err := message.Validate(); err != nil {
+		t.Errorf("Expected valid image message content, got %v", err)
+	}
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(string(encoded), `"text"`) {
+		t.Errorf("Expected no text field in image content, got %s", encoded)
+	}
+
+	tests := []struct {
+		name    string
+		content mcp.ContentItem
+		wantErr bool
+	}{
+		{"valid image", image, false},
+		{"invalid base64", mcp.ContentItem{Type: mcp.ContentTypeImage, Data: "not base64!", MimeType: "image/png"}, true},
+		{"non-image MIME type", mcp.ContentItem{Type: mcp.ContentTypeImage, Data: image.Data, MimeType: "text/plain"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &imageToolHandler{content: tt.content}
+			server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			sender := &recordingSender{}
+			if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+				JSONRPC: mcp.JSONRPCVersion,
+				ID:      mcp.NewIntID(1),
+				Method:  "tools/call",
+				Params:  map[string]any{"name": "chart"},
+			}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if gotErr := sender.responses[0].Error != nil; gotErr != tt.wantErr {
+				t.Errorf("Expected error %v, got response %+v", tt.wantErr, sender.responses[0])
+			}
+		})
+	}
+}
+
+func TestLogPolicy(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithLogger(logger),
+		WithLogPolicy(LogPolicy{SampleEvery: 3, MethodLevels: map[string]string{"tools/list": "debug"}}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	handle := func(method string, id int) {
+		ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, &recordingSender{})
+		if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: method, ID: mcp.NewIntID(int64(id))}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	for i := range 6 {
+		handle("ping", i)
+	}
+	handle("tools/list", 6)
+
+	if got := strings.Count(logs.String(), "method=ping"); got != 2 {
+		t.Errorf("Expected every 3rd of 6 pings to be logged at debug, got %d:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "method=tools/list") {
+		t.Errorf("Expected tools/list to be logged at debug, got:\n%s", logs.String())
+	}
+
+	if _, err := NewMCPServer("Test", "1.0.0", handler, handler, handler,
+		WithLogPolicy(LogPolicy{MethodLevels: map[string]string{"ping": "verbose"}})); err == nil {
+		t.Error("Expected invalid method level to be rejected")
+	}
+	if err := server.SetLogPolicy(LogPolicy{SampleEvery: -1}); err == nil {
+		t.Error("Expected negative sampling rate to be rejected")
+	}
+
+	if err := server.SetLogPolicy(LogPolicy{MethodLevels: map[string]string{"tools/call": "warn"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	policy := server.LogPolicy()
+	if policy.SampleEvery != 0 || policy.MethodLevels["tools/call"] != "WARN" || len(policy.MethodLevels) != 1 {
+		t.Errorf("Expected updated policy, got %+v", policy)
+	}
+
+	logs.Reset()
+	for i := range 6 {
+		handle("ping", i)
+	}
+	if strings.Contains(logs.String(), "method=ping") {
+		t.Errorf("Expected sampling to be disabled, got:\n%s", logs.String())
+	}
+}
+
+func TestAudioContent(t *testing.T) {
+	wav := []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+	audio := mcp.NewAudioContent(wav, "")
+	if audio.Type != mcp.ContentTypeAudio || audio.MimeType != "audio/wave" {
+		t.Errorf("Expected detected audio/wave content, got %+v", audio)
+	}
+	if err := audio.Validate(); err != nil {
+		t.Errorf("Expected valid audio content, got %v", err)
+	}
+	if data, err := audio.DecodeData(); err != nil || !bytes.Equal(data, wav) {
+		t.Errorf("Expected data to round-trip, got %v %v", data, err)
+	}
+
+	message := mcp.NewAudioMessageContent(wav, "audio/wav")
+	if err := message.Validate(); err != nil {
+		t.Errorf("Expected valid audio message content, got %v", err)
+	}
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(encoded) != `{"type":"audio","data":"`+audio.Data+`","mimeType":"audio/wav"}` {
+		t.Errorf("Expected audio content on the wire, got %s", encoded)
+	}
+
+	invalid := mcp.NewAudioContent(wav, "image/png")
+	if err := invalid.Validate(); !errors.Is(err, mcp.ErrInvalidContent) {
+		t.Errorf("Expected ErrInvalidContent for non-audio MIME type, got %v", err)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Tea", "2.0.0", handler, handler, handler, WithSessionInfoTool(true))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	doc, err := server.OpenAPI(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if doc.OpenAPI != OpenAPIVersion || doc.Info.Title != "Tea" || doc.Info.Version != "2.0.0" {
+		t.Errorf("Expected document for Tea 2.0.0, got %+v", doc.Info)
+	}
+
+	tools, _ := handler.ListTools(context.Background())
+	if len(doc.Paths) != len(tools)+1 {
+		t.Errorf("Expected %d paths, got %d", len(tools)+1, len(doc.Paths))
+	}
+
+	op := doc.Paths["/tools/getTeaInfo"].Post
+	if op == nil {
+		t.Fatal("Expected POST operation for getTeaInfo")
+	}
+	if op.OperationID != "getTeaInfo" || !op.RequestBody.Required {
+		t.Errorf("Expected required request body for getTeaInfo, got %+v", op)
+	}
+	if _, ok := op.Responses["200"].Content["application/json"].Schema.(map[string]any); !ok {
+		t.Errorf("Expected generic content schema for tool without output schema, got %T", op.Responses["200"].Content["application/json"].Schema)
+	}
+
+	info := doc.Paths["/tools/"+SessionInfoToolName].Post
+	if info == nil {
+		t.Fatal("Expected POST operation for the session info tool")
+	}
+	if _, ok := info.Responses["200"].Content["application/json"].Schema.(*mcp.OutputSchema); !ok {
+		t.Errorf("Expected output schema for session info tool, got %T", info.Responses["200"].Content["application/json"].Schema)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("Expected document to marshal, got %v", err)
+	}
+}
+
+type blobResourceHandler struct {
+	handlers.TeaHandler
+	content mcp.ResourceContent
+}
+
+func (h *blobResourceHandler) ReadResource(ctx context.Context, params mcp.ResourceParams) (mcp.ResourceResponse, error) {
+	return mcp.ResourceResponse{Contents: []mcp.ResourceContent{h.content}}, nil
+}
+
+func TestBlobResourceContent(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n\x00\xff\xfe binary")
+	blob := mcp.NewBlobResourceContent("file:///menu.pdf", pdf, "")
+	if blob.MimeType != "application/pdf" {
+		t.Errorf("Expected detected application/pdf, got %q", blob.MimeType)
+	}
+
+	tests := []struct {
+		name     string
+		content  mcp.ResourceContent
+		wantErr  bool
+		wantJSON string
+	}{
+		{"blob", blob, false, `{"uri":"file:///menu.pdf","mimeType":"application/pdf","blob":"` + blob.Blob + `"}`},
+		{"empty text", mcp.NewTextResourceContent("menu://tea", "", ""), false, `{"uri":"menu://tea","text":""}`},
+		{"invalid base64", mcp.ResourceContent{URI: "file:///menu.pdf", Blob: "not base64!"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &blobResourceHandler{content: tt.content}
+			server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			sender := &recordingSender{}
+			if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+				JSONRPC: mcp.JSONRPCVersion,
+				ID:      mcp.NewIntID(1),
+				Method:  "resources/read",
+				Params:  map[string]any{"uri": tt.content.URI},
+			}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			response := sender.responses[0]
+			if gotErr := response.Error != nil; gotErr != tt.wantErr {
+				t.Fatalf("Expected error %v, got response %+v", tt.wantErr, response)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			encoded, err := json.Marshal(response.Result.(mcp.ResourceResponse).Contents[0])
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(encoded) != tt.wantJSON {
+				t.Errorf("Expected %s, got %s", tt.wantJSON, encoded)
+			}
+
+			var decoded mcp.ResourceContent
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if data, err := decoded.DecodeBlob(); tt.content.Blob != "" && (err != nil || !bytes.Equal(data, pdf)) {
+				t.Errorf("Expected blob to round-trip, got %q %v", data, err)
+			}
+		})
+	}
+}
+
+func TestToolDocs(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithToolDocs(true))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	call := func(method string, params any) mcp.Response {
+		t.Helper()
+		sender := &recordingSender{}
+		if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  method,
+			Params:  params,
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return sender.responses[0]
+	}
+
+	resources := call("resources/list", nil).Result.(map[string][]mcp.Resource)["resources"]
+	if !slices.ContainsFunc(resources, func(r mcp.Resource) bool { return r.URI == "doc://tools/getTeaInfo" }) {
+		t.Errorf("Expected doc resource for getTeaInfo, got %+v", resources)
+	}
+	if !slices.ContainsFunc(resources, func(r mcp.Resource) bool { return r.URI == "menu://tea" }) {
+		t.Errorf("Expected handler resources to be kept, got %+v", resources)
+	}
+
+	templates := call("resources/templates/list", nil).Result.(map[string][]mcp.ResourceTemplate)["resourceTemplates"]
+	if len(templates) != 1 || templates[0].URITemplate != "doc://tools/{name}" {
+		t.Errorf("Expected doc resource template, got %+v", templates)
+	}
+
+	response := call("resources/read", map[string]any{"uri": "doc://tools/getTeaInfo"})
+	if response.Error != nil {
+		t.Fatalf("Expected no error, got %+v", response.Error)
+	}
+	content := response.Result.(mcp.ResourceResponse).Contents[0]
+	if content.MimeType != "text/markdown" {
+		t.Errorf("Expected text/markdown, got %q", content.MimeType)
+	}
+	for _, want := range []string{"# Get Tea Info (`getTeaInfo`)\n", "| `name` | string | yes |", "## Input schema"} {
+		if !strings.Contains(content.Text, want) {
+			t.Errorf("Expected docs to contain %q, got:\n%s", want, content.Text)
+		}
+	}
+
+	if response := call("resources/read", map[string]any{"uri": "doc://tools/unknown"}); response.Error == nil {
+		t.Error("Expected error for docs of unknown tool")
+	}
+
+	doc := renderToolDoc(mcp.Tool{
+		Name: "brew",
+		InputSchema: mcp.InputSchema{Type: "object", Properties: map[string]any{
+			"tea":   map[string]any{"type": "string", "examples": []any{"sencha"}},
+			"steep": map[string]any{"type": "integer", "default": 3, "description": "Minutes | roughly"},
+		}},
+	})
+	for _, want := range []string{`"tea": "sencha"`, `"steep": 3`, `Minutes \| roughly`, "Returns a list of content items."} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Expected docs to contain %q, got:\n%s", want, doc)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	modified := time.Date(2025, 1, 12, 15, 0, 58, 0, time.UTC)
+	content := mcp.NewTextContent("Steep for 2 minutes.")
+	content.Annotations = mcp.NewAnnotations(0, mcp.RoleUser).WithLastModified(modified)
+
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `{"type":"text","text":"Steep for 2 minutes.","annotations":{"audience":["user"],"priority":0,"lastModified":"2025-01-12T15:00:58Z"}}`
+	if string(encoded) != want {
+		t.Errorf("Expected %s, got %s", want, encoded)
+	}
+
+	blob := mcp.NewBlobResourceContent("file:///menu.pdf", []byte("%PDF-1.7"), "")
+	blob.Annotations = mcp.NewAnnotations(1, mcp.RoleAssistant)
+	if encoded, err := json.Marshal(blob); err != nil || !strings.Contains(string(encoded), `"annotations":{"audience":["assistant"],"priority":1}`) {
+		t.Errorf("Expected annotations on blob content, got %s %v", encoded, err)
+	}
+
+	tests := []struct {
+		name        string
+		annotations *mcp.Annotations
+		wantErr     bool
+	}{
+		{"nil", nil, false},
+		{"valid", mcp.NewAnnotations(0.5, mcp.RoleUser, mcp.RoleAssistant), false},
+		{"unknown role", mcp.NewAnnotations(0.5, "system"), true},
+		{"priority out of range", mcp.NewAnnotations(1.5), true},
+		{"invalid timestamp", &mcp.Annotations{LastModified: "yesterday"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := mcp.NewTextContent("sencha")
+			item.Annotations = tt.annotations
+			if err := item.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTelemetry(t *testing.T) {
+	reports := make(chan TelemetryReport, 1)
+	var attempts atomic.Int32
+	var firstKey atomic.Value
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails transiently and is retried with the same key
+		attempt := attempts.Add(1)
+		if attempt == 1 {
+			firstKey.Store(r.Header.Get("Idempotency-Key"))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if key := r.Header.Get("Idempotency-Key"); attempt == 2 && (key == "" || key != firstKey.Load()) {
+			t.Errorf("Expected the retry to carry the first attempt's idempotency key %q, got %q", firstKey.Load(), key)
+		}
+		var report TelemetryReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("Expected JSON report, got %v", err)
+		}
+		select {
+		case reports <- report:
+		default:
+		}
+	}))
+	defer endpoint.Close()
+
+	handler := &handlers.TeaHandler{}
+	if _, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithTelemetry(Telemetry{})); err == nil {
+		t.Error("Expected error for telemetry without endpoint")
+	}
+
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithReadyOutput(io.Discard),
+		WithTelemetry(Telemetry{Endpoint: endpoint.URL, Interval: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	initializeSession(t, server, "session-1", map[string]any{"protocolVersion": "2025-03-26"})
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, &recordingSender{})
+	for i, method := range []string{"tools/list", "tools/list", "vendor/secret"} {
+		if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(int64(i)), Method: method}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Announcing readiness does not start reporting
+	if err := server.AnnounceReady(runCtx, "stdio", 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case report := <-reports:
+		t.Fatalf("Expected no report before StartTelemetry, got %+v", report)
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	server.StartTelemetry(runCtx, "stdio")
+	select {
+	case report := <-reports:
+		if report.SchemaVersion != TelemetrySchemaVersion || report.Transport != "stdio" {
+			t.Errorf("Expected stdio report, got %+v", report)
+		}
+		if report.ProtocolVersions["2025-03-26"] != 1 {
+			t.Errorf("Expected one 2025-03-26 session, got %v", report.ProtocolVersions)
+		}
+		if report.Methods["tools/list"] != 2 || report.Methods["other"] != 1 || report.Methods["vendor/secret"] != 0 {
+			t.Errorf("Expected anonymized method counts, got %v", report.Methods)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a telemetry report")
+	}
+}
+
+func TestTitleAndMeta(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("tea", "1.0.0", handler, handler, handler, WithServerTitle("Tea House"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	for i, method := range []string{"initialize", "tools/list", "prompts/list"} {
+		if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(int64(i)), Method: method}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	encoded, err := json.Marshal(sender.responses)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{`"serverInfo":{"name":"tea","title":"Tea House","version":"1.0.0"}`, `"title":"Get Tea Info"`, `"title":"Brewing Guide"`} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("Expected responses to contain %s, got %s", want, encoded)
+		}
+	}
+
+	tool := mcp.Tool{Name: "brew", Meta: map[string]any{"vendor/kettle": "gooseneck"}, InputSchema: mcp.InputSchema{Type: "object"}}
+	if encoded, err := json.Marshal(tool); err != nil || !strings.Contains(string(encoded), `"_meta":{"vendor/kettle":"gooseneck"}`) {
+		t.Errorf("Expected _meta on tool, got %s %v", encoded, err)
+	}
+	if encoded, err := json.Marshal(mcp.Tool{Name: "brew"}); err != nil || strings.Contains(string(encoded), "title") || strings.Contains(string(encoded), "_meta") {
+		t.Errorf("Expected title and _meta to be omitted when unset, got %s %v", encoded, err)
+	}
+
+	blob := mcp.NewBlobResourceContent("file:///menu.pdf", []byte("%PDF-1.7"), "")
+	blob.Meta = map[string]any{"pages": 2}
+	if encoded, err := json.Marshal(blob); err != nil || !strings.Contains(string(encoded), `"_meta":{"pages":2}`) {
+		t.Errorf("Expected _meta on blob content, got %s %v", encoded, err)
+	}
+}
+
+func TestResourceMetadata(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(1), Method: "resources/list"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.HandleRequest(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(2), Method: "resources/read", Params: map[string]any{"uri": "menu://tea"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	resource := sender.responses[0].Result.(map[string][]mcp.Resource)["resources"][0]
+	content := sender.responses[1].Result.(mcp.ResourceResponse).Contents[0]
+	if resource.Description == "" || resource.MimeType != content.MimeType {
+		t.Errorf("Expected description and matching MIME type, got %+v", resource)
+	}
+	if resource.Size != int64(len(content.Text)) {
+		t.Errorf("Expected size %d, got %d", len(content.Text), resource.Size)
+	}
+
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(string(encoded), fmt.Sprintf(`"mimeType":"application/json","size":%d`, resource.Size)) {
+		t.Errorf("Expected mimeType and size on the wire, got %s", encoded)
+	}
+}
+
+func TestRawToolCallParams(t *testing.T) {
+	handler := &progressToolHandler{TeaHandler: &handlers.TeaHandler{}}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		params        string
+		wantErr       bool
+		notifications int
+	}{
+		{"valid", "tools/call", `{"name":"getTeaInfo","arguments":{"name":"gyokuro"}}`, false, 0},
+		{"progress token", "tools/call", `{"name":"getTeaNames","_meta":{"progressToken":7}}`, false, 2},
+		{"missing name", "tools/call", `{"arguments":{}}`, true, 0},
+		{"name not a string", "tools/call", `{"name":42}`, true, 0},
+		{"arguments not an object", "tools/call", `{"name":"getTeaNames","arguments":["x"]}`, true, 0},
+		{"generic method", "resources/read", `{"uri":"menu://tea"}`, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &recordingSender{}
+			ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+			req := mcp.Request{JSONRPC: mcp.JSONRPCVersion, Method: tt.method, ID: mcp.NewIntID(1), Params: json.RawMessage(tt.params)}
+			if err := server.HandleRequest(ctx, req); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			response := sender.responses[0]
+			if gotErr := response.Error != nil; gotErr != tt.wantErr {
+				t.Fatalf("Expected error %v, got %+v", tt.wantErr, response)
+			}
+			if tt.wantErr && response.Error.Code != mcp.ErrorCodeInvalidParams {
+				t.Errorf("Expected invalid params error, got %+v", response.Error)
+			}
+			if len(sender.notifications) != tt.notifications {
+				t.Errorf("Expected %d notifications, got %d", tt.notifications, len(sender.notifications))
+			}
+		})
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	for _, tt := range []struct {
+		name         string
+		opts         []Option
+		instructions string
+	}{
+		{"unset", nil, ""},
+		{"set", []Option{WithInstructions(handlers.TeaInstructions)}, handlers.TeaInstructions},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, tt.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			sender := &recordingSender{}
+			if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+				JSONRPC: mcp.JSONRPCVersion,
+				ID:      mcp.NewIntID(1),
+				Method:  "initialize",
+			}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			encoded, err := json.Marshal(sender.responses[0].Result)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var result mcp.InitializeResponse
+			if err := json.Unmarshal(encoded, &result); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Instructions != tt.instructions {
+				t.Errorf("Expected instructions %q, got %q", tt.instructions, result.Instructions)
+			}
+			if tt.instructions == "" && strings.Contains(string(encoded), "instructions") {
+				t.Errorf("Expected instructions to be omitted, got %s", encoded)
+			}
+		})
+	}
+}
+
+type recordingNotificationHandler struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (r *recordingNotificationHandler) HandleNotification(_ context.Context, notification mcp.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods = append(r.methods, notification.Method)
+	if notification.Method == "notifications/fail" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func TestNotificationHandler(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	notifications := &recordingNotificationHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithNotificationHandler(notifications))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	initializeSession(t, server, "session", nil)
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session")
+	for _, method := range []string{mcp.NotificationRootsListChanged, "notifications/custom"} {
+		if err := server.HandleNotification(ctx, mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: method}); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", method, err)
+		}
+	}
+
+	// Handler failures are reported to the transport for logging
+	err = server.HandleNotification(ctx, mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: "notifications/fail"})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+
+	want := []string{mcp.NotificationInitialized, mcp.NotificationRootsListChanged, "notifications/custom", "notifications/fail"}
+	if !slices.Equal(notifications.methods, want) {
+		t.Errorf("Expected notifications %v, got %v", want, notifications.methods)
+	}
+
+	// The server still processes protocol notifications itself
+	if state := server.clientState(ctx); state == nil || state.phase != phaseInitialized {
+		t.Errorf("Expected session to be initialized, got %+v", state)
+	}
+}
+
+type failingToolHandler struct {
+	handlers.TeaHandler
+	err error
+}
+
+func (h *failingToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	return mcp.ToolResponse{}, h.err
+}
+
+func TestMCPErrors(t *testing.T) {
+	notFound := mcp.NewResourceNotFoundError("tea://missing")
+	if !errors.Is(fmt.Errorf("wrapped: %w", notFound), mcp.ErrResourceNotFound) {
+		t.Error("Expected resource-not-found error to match ErrResourceNotFound")
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		method   string
+		params   map[string]any
+		wantCode int
+		wantData any
+	}{
+		{"unknown resource", nil, "resources/read", map[string]any{"uri": "tea://missing"}, mcp.ErrorCodeResourceNotFound, map[string]string{"uri": "tea://missing"}},
+		{"typed handler error", fmt.Errorf("quota: %w", mcp.NewError(-32001, "Quota exceeded", "retry later")), "tools/call", map[string]any{"name": "getTeaNames"}, -32001, "retry later"},
+		{"plain handler error", errors.New("boom"), "tools/call", map[string]any{"name": "getTeaNames"}, mcp.ErrorCodeInvalidParams, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &failingToolHandler{err: tt.err}
+			server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			sender := &recordingSender{}
+			if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+				JSONRPC: mcp.JSONRPCVersion,
+				ID:      mcp.NewIntID(1),
+				Method:  tt.method,
+				Params:  tt.params,
+			}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			resp := sender.responses[0]
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Fatalf("Expected error code %d, got %+v", tt.wantCode, resp.Error)
+			}
+			if fmt.Sprint(resp.Error.Data) != fmt.Sprint(tt.wantData) {
+				t.Errorf("Expected error data %v, got %v", tt.wantData, resp.Error.Data)
+			}
+		})
+	}
+}
+
+func TestStrictValidation(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	lenient, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	strict, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithStrictValidation())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		wantCode int
+	}{
+		{"valid request", `{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`, 0},
+		{"valid notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, 0},
+		{"unknown field", `{"jsonrpc":"2.0","id":1,"method":"ping","extra":true}`, mcp.ErrorCodeInvalidRequest},
+		{"null id", `{"jsonrpc":"2.0","id":null,"method":"ping"}`, mcp.ErrorCodeInvalidRequest},
+		{"object id", `{"jsonrpc":"2.0","id":{},"method":"ping"}`, mcp.ErrorCodeInvalidRequest},
+		{"array params", `{"jsonrpc":"2.0","id":"a","method":"tools/call","params":["getTeaNames"]}`, mcp.ErrorCodeInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := lenient.ValidateMessage([]byte(tt.message)); err != nil {
+				t.Errorf("Expected lenient server to accept message, got %v", err)
+			}
+
+			err := strict.ValidateMessage([]byte(tt.message))
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.wantCode {
+				t.Errorf("Expected error code %d, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	const large = "12345678901234567890"
+
+	var req mcp.Request
+	if err := json.Unmarshal([]byte(`{"jsonrpc":"2.0","id":`+large+`,"method":"ping"}`), &req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	encoded, err := json.Marshal(mcp.Response{JSONRPC: mcp.JSONRPCVersion, ID: req.ID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(string(encoded), `"id":`+large) {
+		t.Errorf("Expected large integer ID to survive the round-trip, got %s", encoded)
+	}
+
+	var escaped mcp.RequestID
+	if err := json.Unmarshal([]byte(`"\u0061"`), &escaped); err != nil || escaped != mcp.NewStringID("a") {
+		t.Errorf("Expected escaped string ID to equal its unescaped form, got %v %v", escaped, err)
+	}
+	if mcp.NewStringID("1") == mcp.NewIntID(1) {
+		t.Error("Expected string and numeric IDs to be distinct")
+	}
+	if id := mcp.NewStringID("abc"); id.String() != "abc" || !id.IsString() {
+		t.Errorf("Expected string ID abc, got %v", id)
+	}
+
+	// Integral numbers are the same ID however they are written
+	tests := []struct {
+		literal string
+		want    string
+	}{
+		{`1`, `1`},
+		{`1.0`, `1`},
+		{`1e0`, `1`},
+		{`10E-1`, `1`},
+		{`-0`, `0`},
+		{`1.5`, `1.5`},
+		{`1e999999999`, `1e999999999`},
+		{large + `.0`, large + `.0`},
+	}
+	for _, tt := range tests {
+		var id mcp.RequestID
+		if err := json.Unmarshal([]byte(tt.literal), &id); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", tt.literal, err)
+		}
+		if id.String() != tt.want {
+			t.Errorf("Expected %s to decode to %s, got %v", tt.literal, tt.want, id)
+		}
+	}
+	var one mcp.RequestID
+	if err := json.Unmarshal([]byte(`1.0`), &one); err != nil || one != mcp.NewIntID(1) {
+		t.Errorf("Expected 1.0 to equal the ID 1, got %v %v", one, err)
+	}
+
+	var zero mcp.RequestID
+	if err := json.Unmarshal([]byte(`null`), &zero); err != nil || !zero.IsZero() {
+		t.Errorf("Expected null to decode to the zero ID, got %v %v", zero, err)
+	}
+	for _, invalid := range []string{`true`, `{}`, `[1]`} {
+		var id mcp.RequestID
+		if err := json.Unmarshal([]byte(invalid), &id); !errors.Is(err, mcp.ErrInvalidRequestID) {
+			t.Errorf("Expected ErrInvalidRequestID for %s, got %v", invalid, err)
+		}
+	}
+
+	// Cancellation matches large numeric IDs exactly
+	handler := &blockingToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{})}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	initializeSession(t, server, "session-1", nil)
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, &recordingSender{}), mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: req.ID, Params: map[string]any{"name": "getTeaNames"},
 		})
+	}()
+	<-handler.started
+
+	if err := server.HandleNotification(ctx, mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  mcp.NotificationCancelled,
+		Params:  json.RawMessage(`{"requestId":` + large + `}`),
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected cancellation to match the large numeric ID")
+	}
+}
+
+type provenanceToolHandler struct {
+	handlers.TeaHandler
+	meta map[string]any
+}
+
+func (h *provenanceToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	h.meta[ProvenanceMetaKey] = Provenance{Origin: "upstream"}
+	return mcp.ToolResponse{Content: []mcp.ContentItem{mcp.NewTextContent("ok")}, Meta: h.meta}, nil
+}
+
+func TestProvenance(t *testing.T) {
+	shared := map[string]any{"cache": "hit"}
+	handler := &provenanceToolHandler{meta: shared}
+	server, err := NewMCPServer("Tea Server", "2.0.0", handler, handler, handler, WithProvenance(true))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sender := &recordingSender{}
+	if err := server.HandleRequest(context.WithValue(context.Background(), mcp.ResponseSenderKey, sender), mcp.Request{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      mcp.NewIntID(1),
+		Method:  "tools/call",
+		Params:  map[string]any{"name": "getTeaNames"},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	response, ok := sender.responses[0].Result.(mcp.ToolResponse)
+	if !ok {
+		t.Fatalf("Expected tool response, got %+v", sender.responses[0])
+	}
+	provenance, ok := response.Meta[ProvenanceMetaKey].(Provenance)
+	if !ok {
+		t.Fatalf("Expected provenance in _meta, got %+v", response.Meta)
+	}
+	if provenance.Server != "Tea Server" || provenance.Version != "2.0.0" || provenance.Tool != "getTeaNames" || provenance.Origin != "upstream" {
+		t.Errorf("Unexpected provenance %+v", provenance)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, provenance.Timestamp); err != nil {
+		t.Errorf("Expected RFC 3339 timestamp, got %q", provenance.Timestamp)
+	}
+	if response.Meta["cache"] != "hit" {
+		t.Errorf("Expected handler metadata to be kept, got %+v", response.Meta)
+	}
+	if shared[ProvenanceMetaKey].(Provenance).Server != "" {
+		t.Error("Expected the handler's metadata map not to be modified")
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	before := []mcp.Tool{{Name: "brew"}, {Name: "steep", Description: "v1"}, {Name: "pour"}}
+	after := []mcp.Tool{{Name: "brew"}, {Name: "steep", Description: "v2"}, {Name: "sip"}}
+	changes := mcp.DiffList(before, after, func(tool mcp.Tool) string { return tool.Name })
+	want := mcp.ListChanges{Added: []string{"sip"}, Removed: []string{"pour"}, Modified: []string{"steep"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, changes)
+	}
+
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	init, _ := server.Initialize(context.Background())
+	if init.Capabilities.Experimental[mcp.ExperimentalListChanges] == nil {
+		t.Errorf("Expected the listChanges extension to be advertised, got %+v", init.Capabilities)
+	}
+
+	initializeSession(t, server, "aware", map[string]any{
+		"protocolVersion": mcp.ProtocolVersion,
+		"capabilities":    map[string]any{"experimental": map[string]any{mcp.ExperimentalListChanges: map[string]any{}}},
+	})
+	initializeSession(t, server, "plain", nil)
+	aware := &recordingSender{}
+	plain := &recordingSender{}
+	server.RegisterSession("aware", aware)
+	server.RegisterSession("plain", plain)
+
+	if err := server.NotifyToolsListChanges(context.Background(), changes); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(aware.notifications) != 1 || !reflect.DeepEqual(aware.notifications[0].Params, changes) {
+		t.Errorf("Expected changes for the negotiating client, got %+v", aware.notifications)
+	}
+	if len(plain.notifications) != 1 || plain.notifications[0].Params != nil || plain.notifications[0].Method != mcp.NotificationToolsListChanged {
+		t.Errorf("Expected a bare notification for other clients, got %+v", plain.notifications)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	path := t.TempDir() + "/settings.json"
+	handler := &handlers.TeaHandler{}
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithSettingsFile(path), WithLogPolicy(LogPolicy{SampleEvery: 5}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if settings := server.Settings(); settings.LogPolicy.SampleEvery != 5 || len(settings.DisabledTools) != 0 {
+		t.Errorf("Expected settings from the options, got %+v", settings)
+	}
+
+	clientSender := &recordingSender{}
+	server.RegisterSession("client", clientSender)
+
+	if err := server.SetSettings(context.Background(), Settings{LogPolicy: LogPolicy{MethodLevels: map[string]string{"tools/call": "loud"}}}); err == nil {
+		t.Error("Expected invalid log policy to be rejected")
+	}
+	if err := server.SetSettings(context.Background(), Settings{DisabledTools: []string{"getTeaNames"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(clientSender.notifications) != 1 || clientSender.notifications[0].Method != mcp.NotificationToolsListChanged {
+		t.Errorf("Expected a tools list_changed notification, got %+v", clientSender.notifications)
+	}
+
+	sender := &recordingSender{}
+	ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+	for i, req := range []mcp.Request{
+		{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(1), Method: "tools/list"},
+		{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(2), Method: "tools/call", Params: map[string]any{"name": "getTeaNames"}},
+	} {
+		if err := server.HandleRequest(ctx, req); err != nil {
+			t.Fatalf("Request %d: expected no error, got %v", i, err)
+		}
+	}
+	tools := sender.responses[0].Result.(map[string][]mcp.Tool)["tools"]
+	if slices.ContainsFunc(tools, func(tool mcp.Tool) bool { return tool.Name == "getTeaNames" }) {
+		t.Error("Expected disabled tool to be hidden from tools/list")
+	}
+	if sender.responses[1].Error == nil || !strings.Contains(sender.responses[1].Error.Message, mcp.ErrToolNotFound.Error()) {
+		t.Errorf("Expected call of disabled tool to fail, got %+v", sender.responses[1])
+	}
+
+	// A restarted server applies the persisted overrides
+	restarted, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithSettingsFile(path), WithLogPolicy(LogPolicy{SampleEvery: 5}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if settings := restarted.Settings(); settings.LogPolicy.SampleEvery != 0 || !slices.Equal(settings.DisabledTools, []string{"getTeaNames"}) {
+		t.Errorf("Expected persisted settings, got %+v", settings)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("Failed to write settings file: %v", err)
+	}
+	if _, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithSettingsFile(path)); err == nil {
+		t.Error("Expected error for corrupt settings file")
+	}
+}
+
+func TestMemoryPressure(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	for _, cfg := range []MemoryPressure{{}, {Threshold: 1, LimitRatio: 1.5}} {
+		if _, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithMemoryPressure(cfg)); err == nil {
+			t.Errorf("Expected error for invalid memory pressure config %+v", cfg)
+		}
+	}
+
+	var changes []MemoryStatus
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithMemoryPressure(MemoryPressure{
+		Threshold:     100 << 20,
+		LimitRatio:    0.5,
+		CheckInterval: time.Nanosecond,
+		OnChange:      func(ctx context.Context, status MemoryStatus) { changes = append(changes, status) },
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if server.memory.inUse() == 0 {
+		t.Error("Expected the runtime to report memory in use")
+	}
+	inUse := uint64(10 << 20)
+	server.memory.inUse = func() uint64 { return inUse }
+	server.memory.memoryLimit = func() int64 { return 120 << 20 }
+	initializeSession(t, server, "session-1", nil)
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	requests := []mcp.Request{
+		{JSONRPC: mcp.JSONRPCVersion, Method: "tools/call", ID: mcp.NewIntID(1), Params: map[string]any{"name": "getTeaNames"}},
+		{JSONRPC: mcp.JSONRPCVersion, Method: "resources/read", ID: mcp.NewIntID(2), Params: map[string]any{"uri": "menu://tea"}},
+		{JSONRPC: mcp.JSONRPCVersion, Method: "tools/list", ID: mcp.NewIntID(3)},
+	}
+	send := func() []mcp.Response {
+		t.Helper()
+		sender := &recordingSender{}
+		for _, req := range requests {
+			if err := server.HandleRequest(context.WithValue(ctx, mcp.ResponseSenderKey, sender), req); err != nil {
+				t.Fatalf("Expected no error for %s, got %v", req.Method, err)
+			}
+		}
+		return sender.responses
+	}
+
+	for _, resp := range send() {
+		if resp.Error != nil {
+			t.Errorf("Expected success below the threshold, got %+v", resp.Error)
+		}
+	}
+
+	// Half the 120 MiB soft limit is below the absolute threshold, so it applies
+	inUse = 70 << 20
+	responses := send()
+	for _, resp := range responses[:2] {
+		if resp.Error == nil || !strings.Contains(resp.Error.Message, mcp.ErrMemoryPressure.Error()) {
+			t.Errorf("Expected memory pressure error, got %+v", resp)
+		}
+	}
+	if responses[2].Error != nil {
+		t.Errorf("Expected tools/list to be served under pressure, got %+v", responses[2].Error)
+	}
+	if status, ok := server.MemoryStatus(); !ok || !status.UnderPressure || status.Threshold != 60<<20 {
+		t.Errorf("Expected pressure at a 60 MiB threshold, got %+v", status)
+	}
+
+	inUse = 10 << 20
+	for _, resp := range send() {
+		if resp.Error != nil {
+			t.Errorf("Expected success after recovery, got %+v", resp.Error)
+		}
+	}
+	if len(changes) != 2 || !changes[0].UnderPressure || changes[1].UnderPressure {
+		t.Errorf("Expected pressure and recovery to be reported once each, got %+v", changes)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	if _, err := NewMCPServer("Test", "1.0.0", handler, handler, handler, WithMiddleware(nil)); err == nil {
+		t.Error("Expected error for nil middleware, got nil")
+	}
+
+	type tokenKey struct{}
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, req mcp.Request) error {
+				calls = append(calls, name+" "+req.Method)
+				defer func() { calls = append(calls, name+" done") }()
+				return next(ctx, req)
+			}
+		}
+	}
+	auth := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req mcp.Request) error {
+			switch ctx.Value(tokenKey{}) {
+			case nil:
+				return fmt.Errorf("auth: %w", mcp.NewError(-32001, "Unauthorized", "missing token"))
+			case "broken":
+				return errors.New("token store unavailable")
+			}
+			return next(ctx, req)
+		}
+	}
+	// Accepts the tool's old name
+	rename := func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req mcp.Request) error {
+			if params, ok := req.Params.(map[string]any); ok && params["name"] == "teaNames" {
+				req.Params = map[string]any{"name": "getTeaNames"}
+			}
+			return next(ctx, req)
+		}
+	}
+
+	server, err := NewMCPServer("Test", "1.0.0", handler, handler, handler,
+		WithMiddleware(trace("outer"), trace("inner")), WithMiddleware(auth, rename))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	call := func(token any) mcp.Response {
+		t.Helper()
+		sender := &recordingSender{}
+		ctx := context.WithValue(context.Background(), mcp.ResponseSenderKey, sender)
+		if token != nil {
+			ctx = context.WithValue(ctx, tokenKey{}, token)
+		}
+		if err := server.HandleRequest(ctx, mcp.Request{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      mcp.NewIntID(1),
+			Method:  "tools/call",
+			Params:  map[string]any{"name": "teaNames"},
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(sender.responses) != 1 {
+			t.Fatalf("Expected 1 response, got %d", len(sender.responses))
+		}
+		return sender.responses[0]
+	}
+
+	if resp := call("secret"); resp.Error != nil {
+		t.Errorf("Expected renamed tool call to succeed, got %+v", resp.Error)
+	}
+	want := []string{"outer tools/call", "inner tools/call", "inner done", "outer done"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, calls)
+	}
+
+	if resp := call(nil); resp.Error == nil || resp.Error.Code != -32001 || resp.Error.Data != "missing token" {
+		t.Errorf("Expected unauthorized error, got %+v", resp.Error)
+	}
+	if resp := call("broken"); resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInternalError {
+		t.Errorf("Expected internal error, got %+v", resp.Error)
 	}
 }
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// session holds the server-side state of a connected client.
+type session struct {
+	id     string
+	sender mcp.SessionSender
+}
+
+// RegisterSession makes a client session known to the server.
+//
+// Transports call this when a channel capable of carrying server-initiated
+// messages is established (stdout for stdio, a GET SSE stream for HTTP).
+// The sender delivers notifications and server-initiated requests, such as
+// sampling or elicitation, to that client. Registering an existing ID
+// replaces its sender.
+func (s *Server) RegisterSession(id string, sender mcp.SessionSender) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	s.sessions[id] = &session{id: id, sender: sender}
+	s.logger.Debug("Session registered", "session", id)
+}
+
+// UnregisterSession removes a client session once its channel is closed.
+func (s *Server) UnregisterSession(id string) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	delete(s.sessions, id)
+	s.logger.Debug("Session unregistered", "session", id)
+}
+
+// NotifySession sends a notification to a single client session, e.g. on the
+// GET event stream of an HTTP session. It returns mcp.ErrSessionNotFound if
+// the session has no registered channel for server-initiated messages.
+func (s *Server) NotifySession(sessionID, method string, params any) error {
+	s.sessionsMu.RLock()
+	sess, ok := s.sessions[sessionID]
+	s.sessionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", mcp.ErrSessionNotFound, sessionID)
+	}
+
+	return s.transcripts.notifier(sessionID, sess.sender).SendNotification(mcp.Notification{
+		JSONRPC: mcp.JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// NotifyToolsListChanged informs all connected clients that the tool list has changed.
+//
+// Clients are expected to re-fetch the list via tools/list.
+func (s *Server) NotifyToolsListChanged(ctx context.Context) error {
+	return s.broadcast(ctx, mcp.NotificationToolsListChanged, nil)
+}
+
+// NotifyResourcesListChanged informs all connected clients that the resource list has changed.
+func (s *Server) NotifyResourcesListChanged(ctx context.Context) error {
+	return s.broadcast(ctx, mcp.NotificationResourcesListChanged, nil)
+}
+
+// NotifyPromptsListChanged informs all connected clients that the prompt list has changed.
+func (s *Server) NotifyPromptsListChanged(ctx context.Context) error {
+	return s.broadcast(ctx, mcp.NotificationPromptsListChanged, nil)
+}
+
+// NotifyToolsListChanges is like NotifyToolsListChanged, but clients that
+// negotiated mcp.ExperimentalListChanges also receive which tools changed.
+func (s *Server) NotifyToolsListChanges(ctx context.Context, changes mcp.ListChanges) error {
+	return s.broadcastListChanges(ctx, mcp.NotificationToolsListChanged, changes)
+}
+
+// NotifyResourcesListChanges is like NotifyResourcesListChanged, but clients
+// that negotiated mcp.ExperimentalListChanges also receive which resources changed.
+func (s *Server) NotifyResourcesListChanges(ctx context.Context, changes mcp.ListChanges) error {
+	return s.broadcastListChanges(ctx, mcp.NotificationResourcesListChanged, changes)
+}
+
+// NotifyPromptsListChanges is like NotifyPromptsListChanged, but clients that
+// negotiated mcp.ExperimentalListChanges also receive which prompts changed.
+func (s *Server) NotifyPromptsListChanges(ctx context.Context, changes mcp.ListChanges) error {
+	return s.broadcastListChanges(ctx, mcp.NotificationPromptsListChanged, changes)
+}
+
+// notifier returns the channel for notifications related to the current request.
+//
+// The request's own response stream is preferred (stdout, a POST SSE stream),
+// falling back to the session's registered stream for transports whose
+// response channel cannot carry notifications (plain JSON over HTTP).
+func (s *Server) notifier(ctx context.Context) mcp.NotificationSender {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	if sender, ok := ctx.Value(mcp.ResponseSenderKey).(mcp.NotificationSender); ok {
+		return s.transcripts.notifier(sessionID, sender)
+	}
+
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+	if sess, ok := s.sessions[sessionID]; ok {
+		return s.transcripts.notifier(sessionID, sess.sender)
+	}
+	return nil
+}
+
+// broadcast sends a notification to every registered session.
+func (s *Server) broadcast(ctx context.Context, method string, params any) error {
+	return s.broadcastEach(ctx, method, func(string) any { return params })
+}
+
+// broadcastListChanges sends a list_changed notification to every registered
+// session, with changes as params for the sessions that negotiated them.
+func (s *Server) broadcastListChanges(ctx context.Context, method string, changes mcp.ListChanges) error {
+	return s.broadcastEach(ctx, method, func(sessionID string) any {
+		if changes.IsEmpty() || !s.clientSupportsListChanges(sessionID) {
+			return nil
+		}
+		return changes
+	})
+}
+
+// broadcastEach sends a notification to every registered session, with the
+// params returned for the session's ID.
+func (s *Server) broadcastEach(ctx context.Context, method string, params func(sessionID string) any) error {
+	s.sessionsMu.RLock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessionsMu.RUnlock()
+
+	var errs []error
+	for _, sess := range sessions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		notification := mcp.Notification{
+			JSONRPC: mcp.JSONRPCVersion,
+			Method:  method,
+			Params:  params(sess.id),
+		}
+		if err := s.transcripts.notifier(sess.id, sess.sender).SendNotification(notification); err != nil {
+			s.logger.Warn("Failed to send notification", "method", method, "session", sess.id, "error", err)
+			errs = append(errs, fmt.Errorf("session %s: %w", sess.id, err))
+		}
+	}
+
+	s.logger.Debug("Broadcast notification", "method", method, "sessions", len(sessions))
+	return errors.Join(errs...)
+}
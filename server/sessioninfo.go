@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// SessionInfoToolName is the name of the built-in session introspection tool.
+const SessionInfoToolName = "mcp.sessionInfo"
+
+// WithSessionInfoTool adds the built-in mcp.sessionInfo diagnostic tool.
+//
+// The tool reports what the server knows about the calling session: its ID,
+// the negotiated protocol version, client info and capabilities, the client
+// log level and locale, and the limits applied to its requests. It helps
+// users debugging agent behavior see the session from the server's side.
+func WithSessionInfoTool(enabled bool) Option {
+	return func(cfg *serverConfig) {
+		cfg.sessionInfoTool = enabled
+	}
+}
+
+// SessionInfo is the structured output of the mcp.sessionInfo tool.
+type SessionInfo struct {
+	SessionID          string          `json:"sessionId"`
+	ProtocolVersion    string          `json:"protocolVersion,omitempty"`
+	Phase              string          `json:"phase"`
+	ClientInfo         *mcp.ClientInfo `json:"clientInfo,omitempty"`
+	ClientCapabilities map[string]any  `json:"clientCapabilities,omitempty"`
+	LogLevel           string          `json:"logLevel"`
+	Locale             string          `json:"locale,omitempty"`
+	Timezone           string          `json:"timezone"`
+	Limits             SessionLimits   `json:"limits"`
+}
+
+// SessionLimits describes the limits applied to a session's requests.
+type SessionLimits struct {
+	// RequestTimeout is the server's request timeout, e.g. "30s".
+	RequestTimeout string `json:"requestTimeout"`
+
+	// ToolConcurrency maps tool names to their current adaptive concurrency
+	// limit and in-flight calls. It is omitted unless adaptive concurrency is enabled.
+	ToolConcurrency map[string]ToolConcurrency `json:"toolConcurrency,omitempty"`
+}
+
+// ToolConcurrency is the adaptive concurrency state of a single tool.
+type ToolConcurrency struct {
+	Limit    int `json:"limit"`
+	InFlight int `json:"inFlight"`
+}
+
+func sessionInfoTool() mcp.Tool {
+	return mcp.Tool{
+		Name:        SessionInfoToolName,
+		Description: "Show what the server knows about the current session: negotiated protocol version, client info, log level, locale and applied limits",
+		InputSchema: mcp.InputSchema{Type: "object"},
+		OutputSchema: &mcp.OutputSchema{
+			Type: "object",
+			Properties: map[string]any{
+				"sessionId":          map[string]any{"type": "string"},
+				"protocolVersion":    map[string]any{"type": "string"},
+				"phase":              map[string]any{"type": "string"},
+				"clientInfo":         map[string]any{"type": "object"},
+				"clientCapabilities": map[string]any{"type": "object"},
+				"logLevel":           map[string]any{"type": "string"},
+				"locale":             map[string]any{"type": "string"},
+				"timezone":           map[string]any{"type": "string"},
+				"limits":             map[string]any{"type": "object"},
+			},
+			Required: []string{"sessionId", "phase", "logLevel", "timezone", "limits"},
+		},
+	}
+}
+
+// sessionInfo collects the introspection data for the session behind ctx.
+func (s *Server) sessionInfo(ctx context.Context) SessionInfo {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	locale := mcp.LocaleFromContext(ctx)
+
+	info := SessionInfo{
+		SessionID: sessionID,
+		Phase:     phaseUninitialized.String(),
+		LogLevel:  string(s.clientLogLevel(ctx)),
+		Locale:    locale.Language,
+		Timezone:  locale.Timezone().String(),
+		Limits: SessionLimits{
+			RequestTimeout: s.config.requestTimeout.String(),
+		},
+	}
+
+	if state := s.clientState(ctx); state != nil {
+		info.Phase = state.phase.String()
+		info.ProtocolVersion = state.protocolVersion
+		info.ClientCapabilities = state.capabilities
+		if state.initialized() {
+			clientInfo := state.info
+			info.ClientInfo = &clientInfo
+		}
+	}
+
+	if s.toolLimiter != nil {
+		info.Limits.ToolConcurrency = s.toolLimiter.snapshot()
+	}
+
+	return info
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// Settings are the runtime settings operators can change on a running
+// server without a restart, e.g. through the admin listener of the HTTP
+// transport.
+type Settings struct {
+	// LogPolicy is the per-request log policy, see SetLogPolicy.
+	LogPolicy LogPolicy `json:"logPolicy"`
+
+	// DisabledTools lists tools that are hidden from tools/list and whose
+	// calls are rejected as if the tool did not exist.
+	DisabledTools []string `json:"disabledTools,omitempty"`
+}
+
+// WithSettingsFile persists runtime settings overrides to the JSON file at path.
+//
+// Every change through SetSettings or SetLogPolicy is written to the file,
+// and a server created with an existing file applies its settings on top of
+// the options it was started with, so overrides survive restarts.
+func WithSettingsFile(path string) Option {
+	return func(cfg *serverConfig) {
+		cfg.settingsFile = path
+	}
+}
+
+// settingsStore holds the runtime settings not covered by logPolicy.
+type settingsStore struct {
+	// saveMu serializes writes of the settings file.
+	saveMu sync.Mutex
+
+	mu            sync.RWMutex
+	disabledTools map[string]struct{}
+}
+
+// loadSettings reads the settings file, returning nil settings if it does not exist yet.
+func loadSettings(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return &settings, nil
+}
+
+// Settings returns the current runtime settings.
+func (s *Server) Settings() Settings {
+	settings := Settings{LogPolicy: s.LogPolicy()}
+
+	s.settings.mu.RLock()
+	defer s.settings.mu.RUnlock()
+	for name := range s.settings.disabledTools {
+		settings.DisabledTools = append(settings.DisabledTools, name)
+	}
+	slices.Sort(settings.DisabledTools)
+	return settings
+}
+
+// SetSettings replaces the runtime settings and persists them if a settings
+// file is configured. Clients are notified when the tool list changes.
+func (s *Server) SetSettings(ctx context.Context, settings Settings) error {
+	sampleEvery, levels, err := settings.LogPolicy.compile()
+	if err != nil {
+		return err
+	}
+
+	before, listErr := s.listTools(ctx)
+
+	s.logPolicy.mu.Lock()
+	s.logPolicy.sampleEvery = sampleEvery
+	s.logPolicy.methodLevels = levels
+	s.logPolicy.mu.Unlock()
+	s.setDisabledTools(settings.DisabledTools)
+
+	if err := s.saveSettings(); err != nil {
+		return err
+	}
+
+	// Without both lists the changes are unknown, so nothing is announced
+	after, err := s.listTools(ctx)
+	if listErr != nil || err != nil {
+		return nil
+	}
+	if changes := mcp.DiffList(before, after, func(t mcp.Tool) string { return t.Name }); !changes.IsEmpty() {
+		if err := s.NotifyToolsListChanges(ctx, changes); err != nil {
+			s.logger.Warn("Failed to notify clients of changed tools", "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) setDisabledTools(names []string) {
+	disabled := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		disabled[name] = struct{}{}
+	}
+
+	s.settings.mu.Lock()
+	defer s.settings.mu.Unlock()
+	s.settings.disabledTools = disabled
+}
+
+// toolDisabled reports whether the tool was disabled through the runtime settings.
+func (s *Server) toolDisabled(name string) bool {
+	s.settings.mu.RLock()
+	defer s.settings.mu.RUnlock()
+	_, disabled := s.settings.disabledTools[name]
+	return disabled
+}
+
+// saveSettings writes the current settings to the settings file, if configured.
+//
+// The file is replaced atomically, so a crash never leaves a partial file behind.
+func (s *Server) saveSettings() error {
+	path := s.config.settingsFile
+	if path == "" {
+		return nil
+	}
+
+	s.settings.saveMu.Lock()
+	defer s.settings.saveMu.Unlock()
+
+	data, err := json.MarshalIndent(s.Settings(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
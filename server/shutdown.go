@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// ShutdownReport summarizes the work of a server, written as a single JSON
+// line by AnnounceShutdown when the server terminates.
+type ShutdownReport struct {
+	Event   string `json:"event"`
+	Time    string `json:"time"`
+	Server  string `json:"server"`
+	Version string `json:"version"`
+
+	// RequestsServed counts all requests received.
+	RequestsServed uint64 `json:"requestsServed"`
+
+	// Errors counts the error responses sent.
+	Errors uint64 `json:"errors"`
+
+	// SessionsClosed counts the sessions ended, see EndSession.
+	SessionsClosed uint64 `json:"sessionsClosed"`
+
+	// RequestsDrained counts the requests in flight at Shutdown that completed in time.
+	RequestsDrained uint64 `json:"requestsDrained"`
+
+	// RequestsAborted counts the requests in flight at Shutdown that were cancelled
+	// because they did not complete in time.
+	RequestsAborted uint64 `json:"requestsAborted"`
+}
+
+// serverStats are the counters of the shutdown report.
+type serverStats struct {
+	requests        atomic.Uint64
+	errors          atomic.Uint64
+	sessionsClosed  atomic.Uint64
+	requestsDrained atomic.Uint64
+	requestsAborted atomic.Uint64
+	shuttingDown    atomic.Bool
+}
+
+// Shutdown stops the server from accepting requests and drains the requests
+// in flight.
+//
+// New requests are rejected with mcp.ErrServerShutdown. Requests still in
+// flight when ctx is done are cancelled. Call it before stopping the
+// transport, so that in-flight requests can still send their responses.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.stats.shuttingDown.Store(true)
+
+	s.inFlightMu.Lock()
+	inFlight := len(s.inFlight)
+	s.inFlightMu.Unlock()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.inFlightMu.Lock()
+		remaining := len(s.inFlight)
+		if remaining == 0 || ctx.Err() != nil {
+			for _, req := range s.inFlight {
+				req.cancel(mcp.ErrServerShutdown)
+			}
+			s.inFlightMu.Unlock()
+
+			s.stats.requestsAborted.Add(uint64(remaining))
+			s.stats.requestsDrained.Add(uint64(max(inFlight-remaining, 0)))
+			if remaining > 0 {
+				s.logger.Warn("Aborted requests still in flight at shutdown", "count", remaining)
+			}
+			return
+		}
+		s.inFlightMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// ShutdownReport returns the counters of the shutdown report.
+func (s *Server) ShutdownReport() ShutdownReport {
+	return ShutdownReport{
+		Event:           "shutdown",
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		Server:          s.serverInfo.Name,
+		Version:         s.serverInfo.Version,
+		RequestsServed:  s.stats.requests.Load(),
+		Errors:          s.stats.errors.Load(),
+		SessionsClosed:  s.stats.sessionsClosed.Load(),
+		RequestsDrained: s.stats.requestsDrained.Load(),
+		RequestsAborted: s.stats.requestsAborted.Load(),
+	}
+}
+
+// AnnounceShutdown writes the shutdown report as a single JSON line to the
+// readiness output, the counterpart of the event written by AnnounceReady.
+func (s *Server) AnnounceShutdown() error {
+	line, err := json.Marshal(s.ShutdownReport())
+	if err != nil {
+		return fmt.Errorf("failed to marshal shutdown report: %w", err)
+	}
+
+	_, err = fmt.Fprintln(s.config.readyOutput, string(line))
+	return err
+}
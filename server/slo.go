@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSLOWindow     = 5 * time.Minute
+	defaultSLOMinSamples = 10
+
+	// maxSLOSamples bounds the memory used per tracked method.
+	maxSLOSamples = 10000
+)
+
+// SLO defines a latency objective for a single MCP method.
+//
+// For example, SLO{Method: "tools/call", Percentile: 0.99, Threshold: 2 * time.Second}
+// requires 99% of tool calls within the window to complete in under two seconds.
+type SLO struct {
+	// Method is the JSON-RPC method the objective applies to.
+	Method string
+
+	// Percentile is the latency percentile to evaluate, between 0 and 1.
+	Percentile float64
+
+	// Threshold is the maximum allowed latency at the given percentile.
+	Threshold time.Duration
+
+	// Window is the sliding window over which latencies are evaluated.
+	// Defaults to 5 minutes.
+	Window time.Duration
+
+	// MinSamples is the number of requests required within the window
+	// before the objective is evaluated. Defaults to 10.
+	MinSamples int
+}
+
+// SLOStatus describes the current state of an SLO.
+type SLOStatus struct {
+	// SLO is the objective this status belongs to.
+	SLO SLO `json:"-"`
+
+	// Method is the JSON-RPC method the objective applies to.
+	Method string `json:"method"`
+
+	// Percentile is the evaluated latency percentile.
+	Percentile float64 `json:"percentile"`
+
+	// Threshold is the maximum allowed latency.
+	Threshold string `json:"threshold"`
+
+	// Observed is the latency at the percentile within the current window.
+	Observed string `json:"observed"`
+
+	// Samples is the number of requests within the current window.
+	Samples int `json:"samples"`
+
+	// Breached reports whether the objective is currently violated.
+	Breached bool `json:"breached"`
+}
+
+// AlertSink is called whenever an SLO transitions between healthy and breached.
+//
+// The callback runs synchronously on the request path and should return quickly,
+// for example by handing the alert off to a paging system asynchronously.
+type AlertSink func(ctx context.Context, status SLOStatus)
+
+// WithSLO adds a latency objective that is tracked for every request of its method.
+func WithSLO(slo SLO) Option {
+	return func(cfg *serverConfig) {
+		if slo.Window <= 0 {
+			slo.Window = defaultSLOWindow
+		}
+		if slo.MinSamples <= 0 {
+			slo.MinSamples = defaultSLOMinSamples
+		}
+		cfg.slos = append(cfg.slos, slo)
+	}
+}
+
+// WithAlertSink sets the callback notified when an SLO is breached or recovers.
+func WithAlertSink(sink AlertSink) Option {
+	return func(cfg *serverConfig) {
+		cfg.alertSink = sink
+	}
+}
+
+// sloTracker evaluates one SLO over a sliding window of request latencies.
+type sloTracker struct {
+	slo SLO
+
+	mu       sync.Mutex
+	samples  []latencySample
+	breached bool
+}
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// record adds a sample and reports the new status and whether the breach state changed.
+func (t *sloTracker) record(now time.Time, duration time.Duration) (SLOStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples = append(t.samples, latencySample{at: now, duration: duration})
+	if len(t.samples) > maxSLOSamples {
+		t.samples = t.samples[len(t.samples)-maxSLOSamples:]
+	}
+
+	status := t.evaluate(now)
+	changed := status.Breached != t.breached
+	t.breached = status.Breached
+	return status, changed
+}
+
+func (t *sloTracker) status(now time.Time) SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.evaluate(now)
+}
+
+// evaluate prunes samples outside the window and computes the status. Callers hold t.mu.
+func (t *sloTracker) evaluate(now time.Time) SLOStatus {
+	cutoff := now.Add(-t.slo.Window)
+	first := 0
+	for first < len(t.samples) && t.samples[first].at.Before(cutoff) {
+		first++
+	}
+	t.samples = t.samples[first:]
+
+	status := SLOStatus{
+		SLO:        t.slo,
+		Method:     t.slo.Method,
+		Percentile: t.slo.Percentile,
+		Threshold:  t.slo.Threshold.String(),
+		Samples:    len(t.samples),
+	}
+	if len(t.samples) == 0 {
+		status.Observed = time.Duration(0).String()
+		return status
+	}
+
+	durations := make([]time.Duration, len(t.samples))
+	for i, sample := range t.samples {
+		durations[i] = sample.duration
+	}
+	slices.Sort(durations)
+
+	index := int(math.Ceil(t.slo.Percentile*float64(len(durations)))) - 1
+	index = max(0, min(index, len(durations)-1))
+	observed := durations[index]
+
+	status.Observed = observed.String()
+	status.Breached = len(t.samples) >= t.slo.MinSamples && observed > t.slo.Threshold
+	return status
+}
+
+func newSLOTrackers(slos []SLO) []*sloTracker {
+	trackers := make([]*sloTracker, 0, len(slos))
+	for _, slo := range slos {
+		trackers = append(trackers, &sloTracker{slo: slo})
+	}
+	return trackers
+}
+
+// recordLatency feeds a request's latency into the SLOs of its method.
+func (s *Server) recordLatency(ctx context.Context, method string, duration time.Duration) {
+	for _, tracker := range s.sloTrackers {
+		if tracker.slo.Method != method {
+			continue
+		}
+
+		status, changed := tracker.record(time.Now(), duration)
+		if !changed {
+			continue
+		}
+
+		if status.Breached {
+			s.logger.Warn("SLO breached", "method", method, "percentile", status.Percentile, "threshold", status.Threshold, "observed", status.Observed)
+		} else {
+			s.logger.Info("SLO recovered", "method", method, "percentile", status.Percentile, "threshold", status.Threshold, "observed", status.Observed)
+		}
+		if s.config.alertSink != nil {
+			s.config.alertSink(ctx, status)
+		}
+	}
+}
+
+// SLOStatus returns the current status of all configured SLOs.
+func (s *Server) SLOStatus() []SLOStatus {
+	now := time.Now()
+	statuses := make([]SLOStatus, 0, len(s.sloTrackers))
+	for _, tracker := range s.sloTrackers {
+		statuses = append(statuses, tracker.status(now))
+	}
+	return statuses
+}
+
+// Ready reports whether the server should receive traffic.
+//
+// The server is not ready while any configured SLO is breached, so that
+// load balancers stop routing to degraded instances.
+func (s *Server) Ready() bool {
+	for _, status := range s.SLOStatus() {
+		if status.Breached {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// WithStrictValidation makes ValidateMessage reject messages that are
+// accepted by default: unknown top-level fields, null or non-scalar IDs and
+// params that are not a JSON object. Conformance test suites enable it to
+// surface client bugs that lenient parsing hides.
+//
+// Duplicate IDs of in-flight requests are rejected in either mode.
+func WithStrictValidation() Option {
+	return func(cfg *serverConfig) {
+		cfg.strictValidation = true
+	}
+}
+
+// messageFields are the top-level members of JSON-RPC 2.0 messages.
+var messageFields = map[string]bool{
+	"jsonrpc": true,
+	"id":      true,
+	"method":  true,
+	"params":  true,
+	"result":  true,
+	"error":   true,
+}
+
+// ValidateMessage checks a raw inbound JSON-RPC message before transports
+// dispatch it. It always returns nil unless WithStrictValidation is set.
+// Otherwise the returned error carries the code, message and data of the
+// error response to send.
+func (s *Server) ValidateMessage(raw []byte) *mcp.Error {
+	if !s.config.strictValidation {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return mcp.NewError(mcp.ErrorCodeParseError, "Parse error", err.Error())
+	}
+
+	for name := range fields {
+		if !messageFields[name] {
+			return mcp.NewError(mcp.ErrorCodeInvalidRequest, "Invalid request", fmt.Sprintf("unknown field %q", name))
+		}
+	}
+
+	if id, ok := fields["id"]; ok {
+		switch firstByte(id) {
+		case 'n':
+			return mcp.NewError(mcp.ErrorCodeInvalidRequest, "Invalid request", "id must not be null")
+		case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		default:
+			return mcp.NewError(mcp.ErrorCodeInvalidRequest, "Invalid request", "id must be a string or number")
+		}
+	}
+
+	if params, ok := fields["params"]; ok && firstByte(params) != '{' {
+		return mcp.NewError(mcp.ErrorCodeInvalidParams, "Invalid params", "params must be an object")
+	}
+
+	return nil
+}
+
+// firstByte returns the first non-whitespace byte of a JSON value.
+func firstByte(value json.RawMessage) byte {
+	trimmed := bytes.TrimLeft(value, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
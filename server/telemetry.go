@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/retry"
+)
+
+// TelemetrySchemaVersion is the version of the TelemetryReport schema.
+const TelemetrySchemaVersion = 1
+
+// DefaultTelemetryTimeout bounds sending a report with the default client,
+// including retries.
+const DefaultTelemetryTimeout = time.Minute
+
+// Telemetry configures opt-in anonymous usage reporting.
+//
+// Reports contain only aggregate counters: the transport type, the protocol
+// versions negotiated and how often each MCP method was called. They never
+// contain session IDs, client info, addresses, tool names or parameters.
+// Methods outside the MCP specification are counted as "other".
+type Telemetry struct {
+	// Endpoint is the URL reports are POSTed to as JSON.
+	Endpoint string
+
+	// Interval is the time between reports. Defaults to 24h.
+	Interval time.Duration
+
+	// Client sends the reports. Defaults to a client retrying transient
+	// failures with the defaults of the retry package, see
+	// DefaultTelemetryTimeout.
+	Client *http.Client
+}
+
+// TelemetryReport is the JSON document sent to the telemetry endpoint.
+type TelemetryReport struct {
+	SchemaVersion    int               `json:"schemaVersion"`
+	Transport        string            `json:"transport"`
+	IntervalSeconds  int64             `json:"intervalSeconds"`
+	ProtocolVersions map[string]uint64 `json:"protocolVersions"`
+	Methods          map[string]uint64 `json:"methods"`
+}
+
+// WithTelemetry enables anonymous usage reporting. It is off unless this
+// option is given. Reports are sent once StartTelemetry is called.
+func WithTelemetry(cfg Telemetry) Option {
+	return func(c *serverConfig) {
+		if cfg.Interval <= 0 {
+			cfg.Interval = 24 * time.Hour
+		}
+		if cfg.Client == nil {
+			cfg.Client = &http.Client{
+				Timeout:   DefaultTelemetryTimeout,
+				Transport: retry.NewTransport(retry.Policy{Name: "telemetry"}, nil),
+			}
+		}
+		c.telemetry = &cfg
+	}
+}
+
+// telemetryMethods are the methods counted by name, everything else is "other".
+var telemetryMethods = map[string]bool{
+	"initialize":                 true,
+	"ping":                       true,
+	"tools/list":                 true,
+	"tools/call":                 true,
+	"resources/list":             true,
+	"resources/read":             true,
+	"resources/templates/list":   true,
+	"prompts/list":               true,
+	"prompts/get":                true,
+	mcp.MethodLoggingSetLevel:    true,
+	mcp.MethodCompletionComplete: true,
+}
+
+// telemetryCollector aggregates the counters of the current reporting interval.
+type telemetryCollector struct {
+	cfg Telemetry
+
+	mu               sync.Mutex
+	transport        string
+	protocolVersions map[string]uint64
+	methods          map[string]uint64
+}
+
+func newTelemetryCollector(cfg *Telemetry) *telemetryCollector {
+	if cfg == nil {
+		return nil
+	}
+	return &telemetryCollector{
+		cfg:              *cfg,
+		protocolVersions: make(map[string]uint64),
+		methods:          make(map[string]uint64),
+	}
+}
+
+func (t *telemetryCollector) recordMethod(method string) {
+	if t == nil {
+		return
+	}
+	if !telemetryMethods[method] {
+		method = "other"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.methods[method]++
+}
+
+func (t *telemetryCollector) recordProtocolVersion(version string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersions[version]++
+}
+
+// flush returns the report of the current interval and resets the counters.
+func (t *telemetryCollector) flush() TelemetryReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := TelemetryReport{
+		SchemaVersion:    TelemetrySchemaVersion,
+		Transport:        t.transport,
+		IntervalSeconds:  int64(t.cfg.Interval / time.Second),
+		ProtocolVersions: maps.Clone(t.protocolVersions),
+		Methods:          maps.Clone(t.methods),
+	}
+	clear(t.protocolVersions)
+	clear(t.methods)
+	return report
+}
+
+// StartTelemetry sends a usage report every interval until ctx is done, if
+// telemetry is enabled, see WithTelemetry. The transport names the transport
+// serving the server in the reports, e.g. "stdio" or "http". Call it once,
+// when the server starts serving.
+func (s *Server) StartTelemetry(ctx context.Context, transport string) {
+	s.telemetry.start(ctx, transport, s.logger)
+}
+
+// start reports every interval until ctx is done.
+func (t *telemetryCollector) start(ctx context.Context, transport string, logger *slog.Logger) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.transport = transport
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(t.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.send(ctx, t.flush()); err != nil {
+					logger.Debug("Failed to send telemetry report", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (t *telemetryCollector) send(ctx context.Context, report TelemetryReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Makes the report safe to retry, and lets the endpoint drop duplicates
+	req.Header.Set("Idempotency-Key", rand.Text())
+
+	resp, err := t.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// toolCallRequest holds tools/call params decoded on the fast path.
+type toolCallRequest struct {
+	Name      *string        `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+	Meta      *struct {
+		ProgressToken any `json:"progressToken"`
+	} `json:"_meta"`
+}
+
+// decodeParams decodes raw params received from a transport.
+//
+// tools/call dominates traffic, so its params are decoded straight into
+// toolCallRequest instead of a generic map, which saves an intermediate
+// allocation per call and rejects malformed params up front. All other
+// methods are decoded generically. Input sanitization needs the generic
+// form, so it disables the fast path.
+func (s *Server) decodeParams(method string, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if method == "tools/call" && !s.config.sanitizeInput {
+		var params toolCallRequest
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+		}
+		return &params, nil
+	}
+
+	var params any
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("%w: %w", mcp.ErrInvalidParams, err)
+	}
+	return params, nil
+}
+
+func (p *toolCallRequest) toolCallParams() (mcp.ToolCallParams, error) {
+	if p.Name == nil {
+		return mcp.ToolCallParams{}, fmt.Errorf("%w: name parameter is required and must be a string", mcp.ErrInvalidParams)
+	}
+
+	args := p.Arguments
+	if args == nil {
+		args = make(map[string]any)
+	}
+	return mcp.ToolCallParams{Name: *p.Name, Arguments: args}, nil
+}
+
+func (p *toolCallRequest) progressToken() any {
+	if p.Meta == nil {
+		return nil
+	}
+	return p.Meta.ProgressToken
+}
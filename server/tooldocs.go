@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// ToolDocsURIPrefix is the URI prefix of the generated tool documentation resources.
+const ToolDocsURIPrefix = "doc://tools/"
+
+// WithToolDocs exposes a markdown documentation resource per tool.
+//
+// Each tool gets a doc://tools/{name} resource rendered from its description,
+// input and output schemas, including an example call built from the schema's
+// examples, defaults and enums. Agents and humans can fetch usage docs on
+// demand without a separate docs pipeline.
+func WithToolDocs(enabled bool) Option {
+	return func(cfg *serverConfig) {
+		cfg.toolDocs = enabled
+	}
+}
+
+// listTools returns the handler's tools plus the built-in ones that are
+// enabled, without the tools disabled through the runtime settings.
+func (s *Server) listTools(ctx context.Context) ([]mcp.Tool, error) {
+	var tools []mcp.Tool
+	if s.toolHandler != nil {
+		var err error
+		if tools, err = s.toolHandler.ListTools(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if s.config.sessionInfoTool {
+		tools = append(tools, sessionInfoTool())
+	}
+	return slices.DeleteFunc(tools, func(tool mcp.Tool) bool { return s.toolDisabled(tool.Name) }), nil
+}
+
+func (s *Server) toolDocResources(ctx context.Context) ([]mcp.Resource, error) {
+	tools, err := s.listTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]mcp.Resource, 0, len(tools))
+	for _, tool := range tools {
+		resources = append(resources, mcp.Resource{
+			URI:         ToolDocsURIPrefix + tool.Name,
+			Name:        tool.Name + " documentation",
+			Description: "Usage documentation of the " + tool.Name + " tool",
+			MimeType:    "text/markdown",
+		})
+	}
+	return resources, nil
+}
+
+func toolDocTemplate() mcp.ResourceTemplate {
+	return mcp.ResourceTemplate{
+		URITemplate: ToolDocsURIPrefix + "{name}",
+		Name:        "Tool documentation",
+		Description: "Usage documentation of a tool, rendered from its schemas",
+		MimeType:    "text/markdown",
+	}
+}
+
+func (s *Server) readToolDoc(ctx context.Context, uri string) (mcp.ResourceResponse, error) {
+	tools, err := s.listTools(ctx)
+	if err != nil {
+		return mcp.ResourceResponse{}, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	name := strings.TrimPrefix(uri, ToolDocsURIPrefix)
+	for _, tool := range tools {
+		if tool.Name == name {
+			return mcp.ResourceResponse{
+				Contents: []mcp.ResourceContent{mcp.NewTextResourceContent(uri, "text/markdown", renderToolDoc(tool))},
+			}, nil
+		}
+	}
+	return mcp.ResourceResponse{}, mcp.NewResourceNotFoundError(uri)
+}
+
+// renderToolDoc renders the markdown documentation of a tool.
+func renderToolDoc(tool mcp.Tool) string {
+	var b strings.Builder
+
+	if tool.Title != "" {
+		fmt.Fprintf(&b, "# %s (`%s`)\n\n", tool.Title, tool.Name)
+	} else {
+		fmt.Fprintf(&b, "# %s\n\n", tool.Name)
+	}
+	if tool.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", tool.Description)
+	}
+
+	b.WriteString("## Arguments\n\n")
+	names := slices.Sorted(maps.Keys(tool.InputSchema.Properties))
+	if len(names) == 0 {
+		b.WriteString("This tool takes no arguments.\n\n")
+	} else {
+		b.WriteString("| Name | Type | Required | Description |\n|------|------|----------|-------------|\n")
+		for _, name := range names {
+			prop, _ := tool.InputSchema.Properties[name].(map[string]any)
+			required := "no"
+			if slices.Contains(tool.InputSchema.Required, name) {
+				required = "yes"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", name, schemaType(prop), required, tableCell(prop["description"]))
+		}
+		b.WriteString("\n")
+	}
+
+	if args := exampleArguments(tool.InputSchema); len(args) > 0 {
+		b.WriteString("## Example\n\n")
+		writeJSONBlock(&b, mcp.ToolCallParams{Name: tool.Name, Arguments: args})
+	}
+
+	b.WriteString("## Output\n\n")
+	if tool.OutputSchema != nil {
+		b.WriteString("Returns structured content matching this schema:\n\n")
+		writeJSONBlock(&b, tool.OutputSchema)
+	} else {
+		b.WriteString("Returns a list of content items.\n\n")
+	}
+
+	b.WriteString("## Input schema\n\n")
+	writeJSONBlock(&b, tool.InputSchema)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// exampleArguments builds example arguments from the examples, defaults and enums in the schema.
+func exampleArguments(schema mcp.InputSchema) map[string]any {
+	args := make(map[string]any)
+	for name, value := range schema.Properties {
+		prop, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		if examples, ok := prop["examples"].([]any); ok && len(examples) > 0 {
+			args[name] = examples[0]
+		} else if def, ok := prop["default"]; ok {
+			args[name] = def
+		} else if enum, ok := prop["enum"].([]any); ok && len(enum) > 0 {
+			args[name] = enum[0]
+		} else if enum, ok := prop["enum"].([]string); ok && len(enum) > 0 {
+			args[name] = enum[0]
+		}
+	}
+	return args
+}
+
+func schemaType(prop map[string]any) string {
+	if t, ok := prop["type"].(string); ok {
+		return t
+	}
+	return "any"
+}
+
+func tableCell(value any) string {
+	s, _ := value.(string)
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
+
+func writeJSONBlock(b *strings.Builder, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data = []byte(err.Error())
+	}
+	fmt.Fprintf(b, "```json\n%s\n```\n\n", data)
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// Defaults applied to unset Transcripts fields.
+const (
+	DefaultTranscriptEntries  = 1000
+	DefaultTranscriptSessions = 100
+)
+
+// redactedValue replaces redacted arguments in transcripts.
+const redactedValue = "[REDACTED]"
+
+// Directions of transcript entries.
+const (
+	// DirectionIn marks messages the client sent to the server.
+	DirectionIn = "in"
+
+	// DirectionOut marks messages the server sent to the client.
+	DirectionOut = "out"
+)
+
+// Transcripts configures the recording of session transcripts, see WithTranscripts.
+type Transcripts struct {
+	// MaxEntries caps the messages retained per session, dropping the oldest.
+	// Defaults to DefaultTranscriptEntries.
+	MaxEntries int
+
+	// MaxSessions caps the sessions retained, dropping the least recently
+	// started. Defaults to DefaultTranscriptSessions.
+	MaxSessions int
+
+	// KeepArguments records the arguments of tool calls, prompts and
+	// completions as sent. By default their values are replaced with
+	// "[REDACTED]", so transcripts can be shared without leaking secrets.
+	KeepArguments bool
+}
+
+// Transcript is the recorded message exchange of a session.
+type Transcript struct {
+	SessionID string            `json:"sessionId"`
+	Entries   []TranscriptEntry `json:"entries"`
+}
+
+// TranscriptEntry is a single message of a transcript.
+type TranscriptEntry struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+
+	// Kind is "request", "response" or "notification".
+	Kind    string          `json:"kind"`
+	Method  string          `json:"method,omitempty"`
+	Message json.RawMessage `json:"message"`
+}
+
+// WithTranscripts records the messages exchanged with every session, so a
+// transcript can be exported for bug reports, see Server.Transcript.
+func WithTranscripts(cfg Transcripts) Option {
+	return func(c *serverConfig) {
+		if cfg.MaxEntries <= 0 {
+			cfg.MaxEntries = DefaultTranscriptEntries
+		}
+		if cfg.MaxSessions <= 0 {
+			cfg.MaxSessions = DefaultTranscriptSessions
+		}
+		c.transcripts = &cfg
+	}
+}
+
+// transcriptRecorder retains the transcripts of recent sessions. A nil
+// recorder records nothing.
+type transcriptRecorder struct {
+	cfg Transcripts
+
+	mu          sync.Mutex
+	transcripts map[string]*Transcript
+	// order lists the session IDs of transcripts, oldest first.
+	order []string
+}
+
+func newTranscriptRecorder(cfg *Transcripts) *transcriptRecorder {
+	if cfg == nil {
+		return nil
+	}
+	return &transcriptRecorder{cfg: *cfg, transcripts: make(map[string]*Transcript)}
+}
+
+// record appends a message of a session to its transcript. Messages without
+// a session cannot be attributed and are not recorded.
+func (r *transcriptRecorder) record(sessionID, direction string, message any) {
+	if r == nil || sessionID == "" {
+		return
+	}
+
+	entry := TranscriptEntry{Time: time.Now().UTC(), Direction: direction}
+	switch m := message.(type) {
+	case mcp.Request:
+		entry.Kind, entry.Method = "request", m.Method
+		if !r.cfg.KeepArguments {
+			m.Params = redactArguments(m.Method, m.Params)
+		}
+		message = m
+	case mcp.Notification:
+		entry.Kind, entry.Method = "notification", m.Method
+	case mcp.Response:
+		entry.Kind = "response"
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("failed to marshal message: %v", err)})
+	}
+	entry.Message = data
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transcript, ok := r.transcripts[sessionID]
+	if !ok {
+		if len(r.order) >= r.cfg.MaxSessions {
+			delete(r.transcripts, r.order[0])
+			r.order = r.order[1:]
+		}
+		transcript = &Transcript{SessionID: sessionID}
+		r.transcripts[sessionID] = transcript
+		r.order = append(r.order, sessionID)
+	}
+	transcript.Entries = append(transcript.Entries, entry)
+	if len(transcript.Entries) > r.cfg.MaxEntries {
+		transcript.Entries = transcript.Entries[len(transcript.Entries)-r.cfg.MaxEntries:]
+	}
+}
+
+// recordContext appends a message of the session behind ctx to its transcript.
+func (r *transcriptRecorder) recordContext(ctx context.Context, direction string, message any) {
+	sessionID, _ := ctx.Value(mcp.SessionIDKey).(string)
+	r.record(sessionID, direction, message)
+}
+
+// redactArguments replaces the argument values of requests that carry user
+// input, keeping the argument names.
+func redactArguments(method string, params any) any {
+	if p, ok := params.(*toolCallRequest); ok {
+		redacted := *p
+		redacted.Arguments = redactValues(p.Arguments)
+		return &redacted
+	}
+
+	var field string
+	switch method {
+	case "tools/call", "prompts/get":
+		field = "arguments"
+	case mcp.MethodCompletionComplete:
+		field = "argument"
+	default:
+		return params
+	}
+
+	paramsMap, ok := params.(map[string]any)
+	if !ok {
+		return params
+	}
+	args, ok := paramsMap[field].(map[string]any)
+	if !ok {
+		return params
+	}
+
+	redacted := redactValues(args)
+	if field == "argument" {
+		// Completions name the argument, only its value is user input
+		redacted["name"] = args["name"]
+	}
+
+	copied := make(map[string]any, len(paramsMap))
+	for k, v := range paramsMap {
+		copied[k] = v
+	}
+	copied[field] = redacted
+	return copied
+}
+
+func redactValues(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	redacted := make(map[string]any, len(args))
+	for name := range args {
+		redacted[name] = redactedValue
+	}
+	return redacted
+}
+
+// recordingNotifier records the notifications sent to a session.
+type recordingNotifier struct {
+	mcp.NotificationSender
+	transcripts *transcriptRecorder
+	sessionID   string
+}
+
+func (n *recordingNotifier) SendNotification(notification mcp.Notification) error {
+	n.transcripts.record(n.sessionID, DirectionOut, notification)
+	return n.NotificationSender.SendNotification(notification)
+}
+
+// notifier wraps sender to record the notifications sent through it.
+func (r *transcriptRecorder) notifier(sessionID string, sender mcp.NotificationSender) mcp.NotificationSender {
+	if r == nil || sender == nil || sessionID == "" {
+		return sender
+	}
+	return &recordingNotifier{NotificationSender: sender, transcripts: r, sessionID: sessionID}
+}
+
+// Transcript returns the recorded transcript of a session. It reports false
+// if transcripts are disabled or the session is unknown.
+func (s *Server) Transcript(sessionID string) (Transcript, bool) {
+	r := s.transcripts
+	if r == nil {
+		return Transcript{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transcript, ok := r.transcripts[sessionID]
+	if !ok {
+		return Transcript{}, false
+	}
+	return Transcript{SessionID: sessionID, Entries: slices.Clone(transcript.Entries)}, true
+}
+
+// TranscriptSessions returns the IDs of the sessions with a recorded
+// transcript, oldest first.
+func (s *Server) TranscriptSessions() []string {
+	r := s.transcripts
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.order)
+}
+
+// Markdown renders the transcript for pasting into bug reports.
+func (t Transcript) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Transcript of session %s\n", t.SessionID)
+
+	for _, entry := range t.Entries {
+		heading := entry.Kind
+		if entry.Method != "" {
+			heading += " `" + entry.Method + "`"
+		}
+		direction := "client → server"
+		if entry.Direction == DirectionOut {
+			direction = "server → client"
+		}
+		fmt.Fprintf(&b, "\n## %s %s: %s\n\n", entry.Time.Format(time.RFC3339Nano), direction, heading)
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, entry.Message, "", "  "); err != nil {
+			indented.Reset()
+			indented.Write(entry.Message)
+		}
+		fmt.Fprintf(&b, "```json\n%s\n```\n", indented.String())
+	}
+	return b.String()
+}
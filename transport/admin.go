@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// minAdminTokenLength is the minimum length of an admin token, so that it
+// cannot be guessed.
+const minAdminTokenLength = 16
+
+// WithAdminToken serves the /admin endpoints on the admin port and requires
+// "Authorization: Bearer <token>" on each of them. Requests without a token
+// are rejected with 401 Unauthorized, requests with another token with 403
+// Forbidden.
+//
+// Without an admin token, the /admin endpoints, which expose session
+// transcripts and change the server's settings, are not served at all.
+func WithAdminToken(token string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.adminToken = token
+	}
+}
+
+func validateAdminToken(token string) error {
+	if token != "" && len(token) < minAdminTokenLength {
+		return fmt.Errorf("admin token too short: %d characters (must be at least %d)", len(token), minAdminTokenLength)
+	}
+	return nil
+}
+
+// adminAuth wraps an admin handler in the admin token check. Without a token
+// it fails closed and serves nothing.
+func (t *HTTPTransport) adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	if t.adminToken == "" {
+		return http.NotFound
+	}
+	// Comparing digests keeps the comparison constant-time regardless of length
+	want := sha256.Sum256([]byte(t.adminToken))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		got := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+			log.Printf("Rejected admin request from %s with invalid token", clientIP(r))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
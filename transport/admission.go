@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSessionRetryAfter is the Retry-After sent when a session is rejected.
+const DefaultSessionRetryAfter = 30 * time.Second
+
+// AdmissionAction is what the HTTP transport does when a session limit is reached.
+type AdmissionAction int
+
+const (
+	// AdmissionReject rejects the new session with 503 (total limit) or 429
+	// (per-IP limit) and a Retry-After header.
+	AdmissionReject AdmissionAction = iota
+
+	// AdmissionEvictIdle closes the least recently active session, of the same
+	// client IP if the per-IP limit is reached, to make room for the new one.
+	AdmissionEvictIdle
+)
+
+// SessionAdmission limits the number of concurrent sessions of the HTTP transport.
+//
+// A session occupies a slot while its GET event stream is open. Requests
+// carrying the session's Mcp-Session-Id count as activity for AdmissionEvictIdle.
+type SessionAdmission struct {
+	// MaxSessions caps the total number of sessions. 0 means unlimited.
+	MaxSessions int
+
+	// MaxSessionsPerIP caps the number of sessions per client IP. 0 means unlimited.
+	MaxSessionsPerIP int
+
+	// OnLimit is the action taken when a limit is reached. Defaults to AdmissionReject.
+	OnLimit AdmissionAction
+
+	// RetryAfter is sent to rejected clients. Defaults to DefaultSessionRetryAfter.
+	RetryAfter time.Duration
+}
+
+// WithSessionAdmission limits concurrent sessions to protect against connection exhaustion.
+func WithSessionAdmission(cfg SessionAdmission) HTTPOption {
+	return func(t *HTTPTransport) {
+		if cfg.RetryAfter <= 0 {
+			cfg.RetryAfter = DefaultSessionRetryAfter
+		}
+		t.admission = &admission{cfg: cfg, streams: make(map[*admittedStream]struct{})}
+	}
+}
+
+func (cfg SessionAdmission) validate() error {
+	if cfg.MaxSessions < 0 {
+		return fmt.Errorf("invalid maximum sessions: %d (must not be negative)", cfg.MaxSessions)
+	}
+	if cfg.MaxSessionsPerIP < 0 {
+		return fmt.Errorf("invalid maximum sessions per IP: %d (must not be negative)", cfg.MaxSessionsPerIP)
+	}
+	switch cfg.OnLimit {
+	case AdmissionReject, AdmissionEvictIdle:
+		return nil
+	default:
+		return fmt.Errorf("invalid admission action: %d", cfg.OnLimit)
+	}
+}
+
+// admission tracks the open session streams against the configured limits.
+type admission struct {
+	cfg SessionAdmission
+
+	mu      sync.Mutex
+	streams map[*admittedStream]struct{}
+}
+
+type admittedStream struct {
+	ip         string
+	sessionID  string
+	lastActive time.Time
+	evict      context.CancelFunc
+}
+
+// admit reserves a slot for a stream from ip, evicting or rejecting per the
+// configured action. On rejection it writes the response and returns nil.
+// Evicted streams have their evict function called.
+func (a *admission) admit(w http.ResponseWriter, ip string, evict context.CancelFunc) *admittedStream {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stream := &admittedStream{ip: ip, lastActive: time.Now(), evict: evict}
+
+	if a.cfg.MaxSessionsPerIP > 0 && a.count(ip) >= a.cfg.MaxSessionsPerIP {
+		if a.cfg.OnLimit != AdmissionEvictIdle {
+			a.reject(w, http.StatusTooManyRequests, "Too many sessions from this address")
+			return nil
+		}
+		a.evictIdle(ip)
+	}
+	if a.cfg.MaxSessions > 0 && len(a.streams) >= a.cfg.MaxSessions {
+		if a.cfg.OnLimit != AdmissionEvictIdle {
+			a.reject(w, http.StatusServiceUnavailable, "Too many sessions")
+			return nil
+		}
+		a.evictIdle("")
+	}
+
+	a.streams[stream] = struct{}{}
+	return stream
+}
+
+func (a *admission) reject(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(a.cfg.RetryAfter.Round(time.Second)/time.Second)))
+	http.Error(w, message, status)
+}
+
+// count returns the number of streams from ip. Callers must hold a.mu.
+func (a *admission) count(ip string) int {
+	n := 0
+	for stream := range a.streams {
+		if stream.ip == ip {
+			n++
+		}
+	}
+	return n
+}
+
+// evictIdle closes the least recently active stream, of ip unless it is empty.
+// Callers must hold a.mu.
+func (a *admission) evictIdle(ip string) {
+	var oldest *admittedStream
+	for stream := range a.streams {
+		if ip != "" && stream.ip != ip {
+			continue
+		}
+		if oldest == nil || stream.lastActive.Before(oldest.lastActive) {
+			oldest = stream
+		}
+	}
+	if oldest != nil {
+		delete(a.streams, oldest)
+		oldest.evict()
+	}
+}
+
+// bind associates the stream with its session ID for activity tracking.
+func (a *admission) bind(stream *admittedStream, sessionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stream.sessionID = sessionID
+}
+
+func (a *admission) release(stream *admittedStream) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.streams, stream)
+}
+
+// touch marks the streams of the session as active.
+func (a *admission) touch(sessionID string) {
+	if a == nil || sessionID == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for stream := range a.streams {
+		if stream.sessionID == sessionID {
+			stream.lastActive = now
+		}
+	}
+}
+
+// clientIP returns the IP of the client, without port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// bindError wraps a failure to listen on addr in ErrListen, adding a hint
+// on how to resolve the common causes.
+func bindError(addr string, err error) error {
+	return fmt.Errorf("%w on %s: %w%s", ErrListen, addr, err, bindHint(addr, err))
+}
+
+func bindHint(addr string, err error) string {
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		if pid, name, ok := portOwner(port); ok {
+			return fmt.Sprintf(" (port %d is in use by %s, PID %d; stop it or choose another port)", port, name, pid)
+		}
+		return fmt.Sprintf(" (port %d is in use by another process, e.g. another instance of this server; stop it or choose another port)", port)
+	case errors.Is(err, syscall.EACCES) && port > 0 && port < 1024:
+		return fmt.Sprintf(" (port %d is privileged: run as root, grant the binary CAP_NET_BIND_SERVICE or choose a port from 1024 up)", port)
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return " (the address is not assigned to any interface of this host; check the bind address)"
+	}
+	return ""
+}
+
+// portOwner returns the process listening on the TCP port. It reads /proc,
+// so it only finds owners on Linux, and only those whose file descriptors
+// are visible to the current user.
+func portOwner(port int) (pid int, name string, ok bool) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		for _, inode := range listeningSockets(table, port) {
+			inodes["socket:["+inode+"]"] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, "", false
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		if target, err := os.Readlink(fd); err != nil || !inodes[target] {
+			continue
+		}
+		dir := filepath.Dir(filepath.Dir(fd))
+		pid, _ = strconv.Atoi(filepath.Base(dir))
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		return pid, strings.TrimSpace(string(comm)), true
+	}
+	return 0, "", false
+}
+
+// listeningSockets returns the inodes of the sockets listening on the port
+// in a /proc/net/tcp table.
+func listeningSockets(table string, port int) []string {
+	f, err := os.Open(table)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	const stateListen = "0A"
+	var inodes []string
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // skip the header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != stateListen {
+			continue
+		}
+		_, localPort, found := strings.Cut(fields[1], ":")
+		if p, err := strconv.ParseUint(localPort, 16, 16); found && err == nil && int(p) == port {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
@@ -0,0 +1,45 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestBindDiagnostics(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	_, err = net.Listen("tcp", l.Addr().String())
+	if err == nil {
+		t.Fatal("Expected binding a port in use to fail")
+	}
+	err = bindError(l.Addr().String(), err)
+	if !errors.Is(err, ErrListen) || !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("Expected ErrListen wrapping EADDRINUSE, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("port %d is in use", port)) {
+		t.Errorf("Expected port in use hint, got %v", err)
+	}
+	if runtime.GOOS == "linux" && !strings.Contains(err.Error(), fmt.Sprintf("PID %d", os.Getpid())) {
+		t.Errorf("Expected the test process as port owner, got %v", err)
+	}
+
+	err = bindError("0.0.0.0:80", &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EACCES)})
+	if !strings.Contains(err.Error(), "CAP_NET_BIND_SERVICE") {
+		t.Errorf("Expected privileged port hint, got %v", err)
+	}
+	err = bindError("0.0.0.0:8080", &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EACCES)})
+	if strings.Contains(err.Error(), "(") {
+		t.Errorf("Expected no hint for permission errors on unprivileged ports, got %v", err)
+	}
+}
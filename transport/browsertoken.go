@@ -0,0 +1,175 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultBrowserTokenTTL is how long a browser session token stays valid.
+const DefaultBrowserTokenTTL = 5 * time.Minute
+
+// BrowserTokens configures the token handshake for browser-based clients.
+//
+// A page served from one of Origins POSTs to /mcp/token and receives a
+// short-lived token scoped to its origin. Every /mcp request carrying an
+// Origin header must then present a token issued for that origin, either as
+// "Authorization: Bearer <token>" or, for EventSource and WebSocket clients
+// that cannot set headers, as the "token" query parameter. Requests without
+// an Origin header, i.e. non-browser clients, are not affected.
+//
+// The handshake relies on browsers setting the Origin header, which page
+// scripts cannot forge, so no long-lived credential is ever exposed to them.
+type BrowserTokens struct {
+	// Origins lists the origins allowed to obtain tokens, e.g. "https://playground.example.com".
+	Origins []string
+
+	// TTL is how long an issued token is valid. Defaults to DefaultBrowserTokenTTL.
+	TTL time.Duration
+
+	// Secret signs the tokens. A random secret is generated if empty; set it
+	// to share tokens between replicas.
+	Secret []byte
+}
+
+// WithBrowserTokens enables the token handshake for browser-based clients.
+func WithBrowserTokens(cfg BrowserTokens) HTTPOption {
+	return func(t *HTTPTransport) {
+		if cfg.TTL == 0 {
+			cfg.TTL = DefaultBrowserTokenTTL
+		}
+		if len(cfg.Secret) == 0 {
+			cfg.Secret = []byte(rand.Text())
+		}
+		t.browserTokens = &cfg
+	}
+}
+
+func (cfg *BrowserTokens) validate() error {
+	if len(cfg.Origins) == 0 {
+		return fmt.Errorf("browser tokens require at least one allowed origin")
+	}
+	if cfg.TTL < 0 {
+		return fmt.Errorf("invalid browser token TTL: %v (must be positive)", cfg.TTL)
+	}
+	return nil
+}
+
+// browserTokenClaims is the signed payload of a browser token.
+type browserTokenClaims struct {
+	Origin    string `json:"origin"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// issue returns a token for origin valid until expiresAt.
+func (cfg *BrowserTokens) issue(origin string, expiresAt time.Time) string {
+	payload, _ := json.Marshal(browserTokenClaims{Origin: origin, ExpiresAt: expiresAt.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(cfg.sign(encoded))
+}
+
+func (cfg *BrowserTokens) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, cfg.Secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// verify checks that token is unexpired, correctly signed and issued for origin.
+func (cfg *BrowserTokens) verify(token, origin string, now time.Time) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: malformed token", ErrInvalidBrowserToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, cfg.sign(payload)) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidBrowserToken)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("%w: malformed payload", ErrInvalidBrowserToken)
+	}
+	var claims browserTokenClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return fmt.Errorf("%w: malformed payload", ErrInvalidBrowserToken)
+	}
+	if claims.Origin != origin {
+		return fmt.Errorf("%w: issued for another origin", ErrInvalidBrowserToken)
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return fmt.Errorf("%w: expired", ErrInvalidBrowserToken)
+	}
+	return nil
+}
+
+// authorize checks the token of a browser request. Requests without an
+// Origin header and transports without browser tokens are always authorized.
+func (cfg *BrowserTokens) authorize(r *http.Request) error {
+	if cfg == nil {
+		return nil
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	token := r.URL.Query().Get("token")
+	if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = auth
+	}
+	if token == "" {
+		return fmt.Errorf("%w: missing token", ErrInvalidBrowserToken)
+	}
+	return cfg.verify(token, origin, time.Now())
+}
+
+// requireBrowserToken rejects browser requests to next without a valid token.
+func (t *HTTPTransport) requireBrowserToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			if err := t.browserTokens.authorize(r); err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// handleBrowserToken issues a session token to pages of an allowed origin.
+func (t *HTTPTransport) handleBrowserToken(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !slices.Contains(t.browserTokens.Origins, origin) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	expiresAt := time.Now().Add(t.browserTokens.TTL)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Header().Set("Cache-Control", "no-store")
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"token":     t.browserTokens.issue(origin, expiresAt),
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Printf("Failed to encode token response: %v", err)
+	}
+}
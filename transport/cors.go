@@ -0,0 +1,282 @@
+package transport
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// CORSRule is the cross-origin policy of the routes under a path prefix.
+type CORSRule struct {
+	// PathPrefix selects the routes the rule applies to, e.g. "/mcp" or
+	// "/admin/". The rule with the longest matching prefix applies.
+	PathPrefix string
+
+	// AllowedOrigins lists the origins browsers may call the routes from,
+	// e.g. "https://app.example.com", or "*" for any origin. Empty denies
+	// cross-origin requests.
+	AllowedOrigins []string
+
+	// AllowedMethods lists the methods allowed in preflight responses.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers allowed in preflight responses.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers browser scripts may read.
+	ExposedHeaders []string
+
+	// MaxAge is how long browsers may cache a preflight response. 0 omits
+	// the header, leaving browsers at their short default.
+	MaxAge time.Duration
+
+	// AllowCredentials lets browsers send cookies and HTTP authentication
+	// with cross-origin requests and expose the responses to the page, e.g.
+	// for deployments behind a single sign-on proxy. It requires listing
+	// AllowedOrigins explicitly, since it cannot be combined with "*".
+	AllowCredentials bool
+}
+
+// CORSStats counts the cross-origin traffic of a CORS rule. A high share of
+// preflights indicates browsers are not caching them, see CORSRule.MaxAge.
+type CORSStats struct {
+	// Preflights counts the preflight requests answered.
+	Preflights uint64 `json:"preflights"`
+
+	// Requests counts the allowed requests carrying an Origin header.
+	Requests uint64 `json:"requests"`
+
+	// Denied counts preflights and requests from origins that are not allowed.
+	Denied uint64 `json:"denied"`
+}
+
+// DefaultCORSRules returns the rules used unless WithCORSRules is set: the
+// MCP and health endpoints may be called from any origin, while the admin and
+// profiling endpoints, which change server state or expose internals, may not
+// be called from browsers at all. Requests to the MCP endpoint from origins
+// that are not allowed are rejected before CORS applies, see WithAllowedOrigins.
+func DefaultCORSRules() []CORSRule {
+	return []CORSRule{
+		{
+			PathPrefix:     "/",
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodGet, http.MethodOptions},
+			AllowedHeaders: []string{"Content-Type", "Accept"},
+		},
+		{
+			PathPrefix:     "/mcp",
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Accept", "Accept-Language", "Last-Event-ID", "Mcp-Session-Id", "MCP-Protocol-Version"},
+			ExposedHeaders: []string{"Mcp-Session-Id", "MCP-Protocol-Version"},
+			MaxAge:         24 * time.Hour,
+		},
+		{PathPrefix: "/admin/"},
+		{PathPrefix: "/debug/"},
+	}
+}
+
+// WithCORSRules replaces the default CORS rules, see DefaultCORSRules.
+// Routes matching no rule deny cross-origin requests.
+func WithCORSRules(rules ...CORSRule) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.cors = newCORSPolicy(rules)
+	}
+}
+
+func (r CORSRule) validate() error {
+	if !strings.HasPrefix(r.PathPrefix, "/") {
+		return fmt.Errorf("invalid CORS path prefix %q (must start with /)", r.PathPrefix)
+	}
+	for _, origin := range r.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("invalid CORS origin %q (must be * or scheme://host[:port])", origin)
+		}
+	}
+	for _, method := range r.AllowedMethods {
+		// Methods are tokens like header names
+		if !httpguts.ValidHeaderFieldName(method) {
+			return fmt.Errorf("invalid CORS method: %q", method)
+		}
+	}
+	for _, header := range slices.Concat(r.AllowedHeaders, r.ExposedHeaders) {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return fmt.Errorf("invalid CORS header name: %q", header)
+		}
+	}
+	if r.MaxAge < 0 {
+		return fmt.Errorf("invalid CORS max age: %v (must not be negative)", r.MaxAge)
+	}
+	if r.AllowCredentials && slices.Contains(r.AllowedOrigins, "*") {
+		return fmt.Errorf("invalid CORS rule for %q: credentials cannot be allowed for any origin", r.PathPrefix)
+	}
+	return nil
+}
+
+// corsPolicy applies the CORS rules, most specific first.
+type corsPolicy struct {
+	rules []*corsRule
+}
+
+type corsRule struct {
+	CORSRule
+	anyOrigin bool
+
+	preflights atomic.Uint64
+	requests   atomic.Uint64
+	denied     atomic.Uint64
+}
+
+func newCORSPolicy(rules []CORSRule) *corsPolicy {
+	p := &corsPolicy{}
+	for _, r := range rules {
+		r.AllowedOrigins = slices.Clone(r.AllowedOrigins)
+		for i, origin := range r.AllowedOrigins {
+			r.AllowedOrigins[i] = strings.TrimSuffix(origin, "/")
+		}
+		p.rules = append(p.rules, &corsRule{CORSRule: r, anyOrigin: slices.Contains(r.AllowedOrigins, "*")})
+	}
+	slices.SortStableFunc(p.rules, func(a, b *corsRule) int {
+		return cmp.Compare(len(b.PathPrefix), len(a.PathPrefix))
+	})
+	return p
+}
+
+func (p *corsPolicy) validate() error {
+	seen := make(map[string]bool, len(p.rules))
+	for _, r := range p.rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+		if seen[r.PathPrefix] {
+			return fmt.Errorf("duplicate CORS path prefix %q", r.PathPrefix)
+		}
+		seen[r.PathPrefix] = true
+	}
+	return nil
+}
+
+// match returns the rule of the path, or nil if no rule applies.
+func (p *corsPolicy) match(path string) *corsRule {
+	for _, r := range p.rules {
+		if strings.HasPrefix(path, r.PathPrefix) {
+			return r
+		}
+	}
+	return nil
+}
+
+// allows reports whether the rule admits requests from origin.
+func (r *corsRule) allows(origin string) bool {
+	return r != nil && (r.anyOrigin || slices.Contains(r.AllowedOrigins, origin))
+}
+
+func (p *corsPolicy) stats() map[string]CORSStats {
+	stats := make(map[string]CORSStats, len(p.rules))
+	for _, r := range p.rules {
+		stats[r.PathPrefix] = CORSStats{
+			Preflights: r.preflights.Load(),
+			Requests:   r.requests.Load(),
+			Denied:     r.denied.Load(),
+		}
+	}
+	return stats
+}
+
+// CORSStats returns the cross-origin traffic counters per rule, keyed by path prefix.
+func (t *HTTPTransport) CORSStats() map[string]CORSStats {
+	return t.cors.stats()
+}
+
+func (t *HTTPTransport) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := t.cors.match(r.URL.Path)
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if rule != nil && !rule.anyOrigin && len(rule.AllowedOrigins) > 0 {
+			// Responses differ per origin, so caches must not share them
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if origin != "" && !rule.allows(origin) {
+			if rule != nil {
+				rule.denied.Add(1)
+			}
+			if preflight {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			// Without CORS headers the browser withholds the response from the page
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rule == nil || (origin == "" && !rule.anyOrigin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case preflight:
+			rule.preflights.Add(1)
+		case origin != "":
+			rule.requests.Add(1)
+		}
+
+		if rule.anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if len(rule.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ExposedHeaders, ", "))
+		}
+		if rule.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions {
+			if len(rule.AllowedMethods) > 0 {
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+			}
+			if len(rule.AllowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(rule.AllowedHeaders, ", "))
+			}
+			if rule.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(rule.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAdminCORS reports the cross-origin traffic counters per rule.
+func handleAdminCORS(w http.ResponseWriter, r *http.Request, t *HTTPTransport) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(t.CORSStats()); err != nil {
+		log.Printf("Failed to encode CORS stats: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package transport
+
+import "errors"
+
+var (
+	// ErrSessionClosed is returned when writing to an SSE session that has been closed.
+	ErrSessionClosed = errors.New("session closed")
+
+	// ErrResponseAlreadySent is returned when a second response is written to a plain HTTP request.
+	ErrResponseAlreadySent = errors.New("response already sent")
+
+	// ErrInvalidUTF8 is returned for inbound messages that are not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("message is not valid UTF-8")
+
+	// ErrNoEventStream is returned when a server-initiated message targets an
+	// HTTP session without an open GET event stream.
+	ErrNoEventStream = errors.New("session has no open event stream")
+
+	// ErrEventNotFound is returned by an EventStore for event IDs it does not retain.
+	ErrEventNotFound = errors.New("event not found")
+
+	// ErrListen is returned by Start when a network transport cannot bind its address.
+	ErrListen = errors.New("failed to listen")
+
+	// ErrTransportClosed is returned when connecting to a transport that has stopped.
+	ErrTransportClosed = errors.New("transport closed")
+
+	// ErrInvalidBrowserToken is returned for browser requests without a valid session token.
+	ErrInvalidBrowserToken = errors.New("invalid browser token")
+
+	// ErrSessionLimit is returned when a new HTTP session exceeds the session
+	// limit and no idle session can be evicted.
+	ErrSessionLimit = errors.New("session limit reached")
+
+	// ErrMessageTooLarge is returned for inbound messages exceeding the
+	// transport's maximum message size.
+	ErrMessageTooLarge = errors.New("message too large")
+)
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// DefaultEventStoreSize is the number of events the default event store
+// retains per session.
+const DefaultEventStoreSize = 1000
+
+// Event is an SSE event retained for replay.
+type Event struct {
+	// Type is the SSE event type, empty for JSON-RPC messages.
+	Type string
+
+	// Data is the JSON-encoded payload of the event.
+	Data []byte
+}
+
+// EventStore retains the events sent on the SSE streams of a session, so
+// that a client reconnecting with a Last-Event-ID header receives the events
+// it missed instead of losing them, e.g. responses to requests still running
+// when a mobile client dropped the connection.
+//
+// Implementations must be safe for concurrent use. A shared store, such as
+// one backed by Redis, lets streams resume on another replica.
+type EventStore interface {
+	// StoreEvent retains an event sent on a stream of a session and returns
+	// its ID, which is sent to the client as the SSE event ID.
+	StoreEvent(sessionID, streamID string, event Event) (string, error)
+
+	// EventsAfter returns the stream the event lastEventID of the session was
+	// sent on, and the events sent on that stream after it, in order. It
+	// returns ErrEventNotFound if the event is unknown or no longer retained.
+	EventsAfter(sessionID, lastEventID string) (streamID string, events []StoredEvent, err error)
+
+	// DeleteSession discards the events of a session that has ended.
+	DeleteSession(sessionID string)
+}
+
+// StoredEvent is an event returned by EventStore.EventsAfter, with its ID.
+type StoredEvent struct {
+	ID string
+	Event
+}
+
+// WithEventStore sets the store used to replay missed SSE events. A nil
+// store disables replay. Defaults to a MemoryEventStore retaining
+// DefaultEventStoreSize events per session.
+func WithEventStore(store EventStore) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.eventStore = store
+	}
+}
+
+// MemoryEventStore is an EventStore that retains the most recent events of
+// every session in memory.
+type MemoryEventStore struct {
+	maxEvents int
+
+	mu       sync.Mutex
+	sessions map[string]*eventLog
+}
+
+// eventLog holds the retained events of a session, oldest first. Event IDs
+// are the sequence numbers of the events within the session.
+type eventLog struct {
+	seq    uint64
+	events []memoryEvent
+}
+
+type memoryEvent struct {
+	seq    uint64
+	stream string
+	event  Event
+}
+
+// NewMemoryEventStore creates an event store retaining up to maxEvents
+// events per session. Older events are discarded, so clients that stay
+// disconnected too long resume without replay.
+func NewMemoryEventStore(maxEvents int) *MemoryEventStore {
+	if maxEvents <= 0 {
+		maxEvents = DefaultEventStoreSize
+	}
+	return &MemoryEventStore{
+		maxEvents: maxEvents,
+		sessions:  make(map[string]*eventLog),
+	}
+}
+
+func (s *MemoryEventStore) StoreEvent(sessionID, streamID string, event Event) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, ok := s.sessions[sessionID]
+	if !ok {
+		history = &eventLog{}
+		s.sessions[sessionID] = history
+	}
+
+	history.seq++
+	history.events = append(history.events, memoryEvent{seq: history.seq, stream: streamID, event: event})
+	if len(history.events) > s.maxEvents {
+		history.events = history.events[len(history.events)-s.maxEvents:]
+	}
+	return strconv.FormatUint(history.seq, 10), nil
+}
+
+func (s *MemoryEventStore) EventsAfter(sessionID, lastEventID string) (string, []StoredEvent, error) {
+	seq, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %q", ErrEventNotFound, lastEventID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, ok := s.sessions[sessionID]
+	if !ok || len(history.events) == 0 || seq < history.events[0].seq || seq > history.seq {
+		return "", nil, fmt.Errorf("%w: %q", ErrEventNotFound, lastEventID)
+	}
+
+	// Sequence numbers are contiguous, so the event's position follows from its number
+	i := seq - history.events[0].seq
+	last := history.events[i]
+	var events []StoredEvent
+	for _, e := range history.events[i+1:] {
+		if e.stream == last.stream {
+			events = append(events, StoredEvent{ID: strconv.FormatUint(e.seq, 10), Event: e.event})
+		}
+	}
+	return last.stream, events, nil
+}
+
+func (s *MemoryEventStore) DeleteSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
@@ -0,0 +1,478 @@
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// Default settings of the gRPC transport.
+const (
+	DefaultGRPCAddr           = "localhost:50051"
+	DefaultGRPCWriteTimeout   = 30 * time.Second
+	DefaultGRPCRequestTimeout = 30 * time.Second
+
+	// GRPCSessionMethod is the full name of the bidirectional streaming
+	// method serving MCP sessions, see GRPC.
+	GRPCSessionMethod = "/mcp.v1.MCP/Session"
+
+	grpcSessionIDPrefix = "grpc_"
+)
+
+// gRPC status codes sent in the grpc-status trailer.
+const (
+	grpcStatusOK            = 0
+	grpcStatusUnimplemented = 12
+	grpcStatusUnavailable   = 14
+)
+
+// errMalformedMessage is returned for gRPC messages that are not a valid
+// mcp.v1.Message.
+var errMalformedMessage = errors.New("malformed protobuf message")
+
+// errCompressedMessage is returned for compressed gRPC messages, since the
+// transport does not advertise any compression.
+var errCompressedMessage = errors.New("compressed messages are not supported")
+
+// GRPC serves MCP sessions over gRPC, for infrastructure where gRPC is the
+// only protocol allowed between services. It is experimental.
+//
+// Every call of the bidirectional streaming method GRPCSessionMethod is its
+// own MCP session. Each gRPC message in either direction carries a single
+// JSON-RPC message, as defined by proto/mcp/v1/mcp.proto:
+//
+//	syntax = "proto3";
+//	package mcp.v1;
+//
+//	service MCP {
+//	  rpc Session(stream Message) returns (stream Message);
+//	}
+//
+//	message Message {
+//	  bytes payload = 1; // JSON-RPC message encoded as UTF-8 JSON
+//	}
+//
+// The transport serves cleartext HTTP/2 (h2c) and does not support message
+// compression; terminate TLS in front of it, e.g. in a service mesh sidecar.
+type GRPC struct {
+	addr           string
+	writeTimeout   time.Duration
+	requestTimeout time.Duration
+	maxMessageSize int
+
+	mu       sync.Mutex
+	listener net.Listener
+	server   *http.Server
+	cancel   context.CancelFunc
+	closed   bool
+	stopped  chan struct{}
+
+	nextID atomic.Uint64
+}
+
+// NewGRPC creates a new gRPC transport configured by the given options.
+//
+// Unset options fall back to defaults, see DefaultGRPCAddr and the
+// DefaultGRPC*Timeout constants.
+func NewGRPC(opts ...GRPCOption) (*GRPC, error) {
+	t := &GRPC{
+		addr:           DefaultGRPCAddr,
+		writeTimeout:   DefaultGRPCWriteTimeout,
+		requestTimeout: DefaultGRPCRequestTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+		stopped:        make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid gRPC transport options: %w", err)
+	}
+
+	return t, nil
+}
+
+// Addr returns the address the transport listens on, or nil before Start has bound it.
+func (t *GRPC) Addr() net.Addr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
+func (t *GRPC) Start(ctx context.Context, srv *server.Server) error {
+	listener, err := net.Listen("tcp", t.addr)
+	if err != nil {
+		return bindError(t.addr, err)
+	}
+
+	// Streams end when the transport stops, see Stop
+	serveCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	httpServer := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.handleStream(serveCtx, srv, w, r)
+		}),
+		Protocols: &protocols,
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		cancel()
+		_ = listener.Close()
+		return nil
+	}
+	t.listener = listener
+	t.server = httpServer
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	log.Printf("Starting gRPC transport on %s...", listener.Addr())
+
+	if err := srv.AnnounceReady(ctx, "grpc", listenerPort(listener)); err != nil {
+		log.Printf("Failed to announce readiness: %v", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = t.Stop() })
+	defer stop()
+
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return errors.Join(fmt.Errorf("gRPC server on %s failed: %w", listener.Addr(), err), t.Stop())
+	}
+	<-t.stopped
+	log.Println("gRPC transport shutting down")
+	return nil
+}
+
+// Stop ends all open streams with status UNAVAILABLE, once their in-flight
+// requests are cancelled, and closes the listener.
+func (t *GRPC) Stop() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	httpServer, cancel := t.server, t.cancel
+	t.mu.Unlock()
+	defer close(t.stopped)
+
+	if httpServer == nil {
+		return nil
+	}
+	cancel()
+
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), t.writeTimeout)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		_ = httpServer.Close()
+		return fmt.Errorf("failed to shut down gRPC server: %w", err)
+	}
+	return nil
+}
+
+// handleStream serves a call of the session method until the client closes
+// its side of the stream, the stream is reset or the transport stops.
+func (t *GRPC) handleStream(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "Expected a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &grpcStream{
+		writer:       w,
+		controller:   http.NewResponseController(w),
+		sessionID:    fmt.Sprintf("%s%d", grpcSessionIDPrefix, t.nextID.Add(1)),
+		writeTimeout: t.writeTimeout,
+	}
+	if r.URL.Path != GRPCSessionMethod {
+		stream.finish(grpcStatusUnimplemented, "unknown method "+r.URL.Path)
+		return
+	}
+	if encoding := r.Header.Get("Grpc-Encoding"); encoding != "" && encoding != "identity" {
+		stream.finish(grpcStatusUnimplemented, errCompressedMessage.Error())
+		return
+	}
+	_ = stream.controller.Flush()
+
+	log.Printf("gRPC client connected from %s (session %s)", r.RemoteAddr, stream.sessionID)
+
+	srv.RegisterSession(stream.sessionID, stream)
+	defer srv.UnregisterSession(stream.sessionID)
+	defer srv.EndSession(stream.sessionID)
+
+	// Requests of a closed stream have no one to respond to
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stopStream := context.AfterFunc(ctx, cancel)
+	defer stopStream()
+
+	var requests sync.WaitGroup
+
+	messages := make(chan []byte)
+	readErrs := make(chan error, 1)
+	go func() {
+		for {
+			payload, err := readGRPCMessage(r.Body, t.maxMessageSize)
+			switch {
+			case errors.Is(err, ErrMessageTooLarge):
+				log.Printf("Rejecting message larger than %d bytes from gRPC session %s", t.maxMessageSize, stream.sessionID)
+				err = stream.SendError(mcp.NewIntID(-1), mcp.ErrorCodeInvalidRequest, "Request too large",
+					fmt.Sprintf("%v: limit is %d bytes", err, t.maxMessageSize))
+			case errors.Is(err, errMalformedMessage):
+				err = stream.SendError(mcp.NewIntID(-1), mcp.ErrorCodeParseError, "Parse error", err.Error())
+			case err != nil:
+				readErrs <- err
+				return
+			default:
+				select {
+				case messages <- payload:
+				case <-streamCtx.Done():
+					return
+				}
+				continue
+			}
+			if err != nil {
+				log.Printf("Error handling message: %v", err)
+			}
+		}
+	}()
+
+	status, statusMessage := grpcStatusOK, ""
+loop:
+	for {
+		select {
+		case payload := <-messages:
+			if err := dispatchMessage(streamCtx, srv, stream.sessionID, stream, &requests, t.requestTimeout, payload); err != nil {
+				log.Printf("Error handling message: %v", err)
+			}
+		case err := <-readErrs:
+			switch {
+			case errors.Is(err, io.EOF):
+				// The client is done sending; answer its requests before ending the call
+			case errors.Is(err, errCompressedMessage):
+				status, statusMessage = grpcStatusUnimplemented, err.Error()
+			default:
+				log.Printf("Error reading from gRPC session %s: %v", stream.sessionID, err)
+			}
+			break loop
+		case <-streamCtx.Done():
+			status, statusMessage = grpcStatusUnavailable, "server shutting down"
+			break loop
+		}
+	}
+
+	requests.Wait()
+	stream.finish(status, statusMessage)
+	log.Printf("gRPC session %s ended", stream.sessionID)
+}
+
+// grpcStream is a call of the gRPC session method. It sends responses,
+// notifications and server-initiated requests as gRPC messages.
+type grpcStream struct {
+	writer       http.ResponseWriter
+	controller   *http.ResponseController
+	sessionID    string
+	writeTimeout time.Duration
+
+	// mu serializes writes, since requests are handled concurrently and
+	// every gRPC message must be written uninterrupted.
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *grpcStream) writeMessage(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSessionClosed
+	}
+	_ = s.controller.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	if _, err := s.writer.Write(appendGRPCMessage(nil, data)); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// finish ends the call with the status, sent in the trailers.
+func (s *grpcStream) finish(status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.writer.Header().Set("Grpc-Status", strconv.Itoa(status))
+	if message != "" {
+		s.writer.Header().Set("Grpc-Message", message)
+	}
+}
+
+func (s *grpcStream) SendResponse(response mcp.Response) error {
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return s.writeMessage(jsonBytes)
+}
+
+func (s *grpcStream) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return s.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error: &mcp.ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	})
+}
+
+func (s *grpcStream) SendNotification(notification mcp.Notification) error {
+	jsonBytes, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return s.writeMessage(jsonBytes)
+}
+
+func (s *grpcStream) SendRequest(request mcp.Request) error {
+	jsonBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return s.writeMessage(jsonBytes)
+}
+
+// appendGRPCMessage appends the length-prefixed gRPC message of an
+// mcp.v1.Message with the payload to b.
+func appendGRPCMessage(b, payload []byte) []byte {
+	// Field 1, wire type 2 (length-delimited)
+	const payloadTag = 1<<3 | 2
+
+	size := 1 + varintLen(uint64(len(payload))) + len(payload)
+	b = append(b, 0) // uncompressed
+	b = binary.BigEndian.AppendUint32(b, uint32(size))
+	b = append(b, payloadTag)
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+func varintLen(v uint64) int {
+	return len(binary.AppendUvarint(nil, v))
+}
+
+// readGRPCMessage reads a length-prefixed gRPC message and returns the
+// payload of the mcp.v1.Message it carries. Messages larger than max, or
+// malformed ones, are skipped and reported as ErrMessageTooLarge or
+// errMalformedMessage, so reading can continue with the next message.
+func readGRPCMessage(r io.Reader, max int) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errCompressedMessage
+	}
+
+	size := int64(binary.BigEndian.Uint32(prefix[1:]))
+	// Leave room for the field's tag and length
+	if size > int64(max)+1+binary.MaxVarintLen64 {
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return nil, noEOF(err)
+		}
+		return nil, ErrMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, noEOF(err)
+	}
+	payload, err := decodeMessagePayload(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > max {
+		return nil, ErrMessageTooLarge
+	}
+	return payload, nil
+}
+
+// noEOF turns an end of input within a message into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeMessagePayload returns the payload field of a protobuf-encoded
+// mcp.v1.Message, skipping unknown fields.
+func decodeMessagePayload(data []byte) ([]byte, error) {
+	var payload []byte
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errMalformedMessage
+		}
+		data = data[n:]
+
+		switch field, wireType := tag>>3, tag&7; wireType {
+		case 0: // varint
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return nil, errMalformedMessage
+			}
+			data = data[n:]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return nil, errMalformedMessage
+			}
+			data = data[8:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, errMalformedMessage
+			}
+			if field == 1 {
+				payload = data[n : n+int(length)]
+			}
+			data = data[n+int(length):]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return nil, errMalformedMessage
+			}
+			data = data[4:]
+		default:
+			return nil, errMalformedMessage
+		}
+	}
+	return payload, nil
+}
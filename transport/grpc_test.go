@@ -0,0 +1,303 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestNewGRPCOptions(t *testing.T) {
+	transport, err := NewGRPC()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.addr != DefaultGRPCAddr {
+		t.Errorf("Expected default address %q, got %q", DefaultGRPCAddr, transport.addr)
+	}
+
+	invalid := []GRPCOption{
+		WithGRPCAddr("no-port"),
+		WithGRPCWriteTimeout(0),
+		WithGRPCRequestTimeout(-time.Second),
+		WithGRPCMaxMessageSize(0),
+	}
+	for _, opt := range invalid {
+		if _, err := NewGRPC(opt); err == nil {
+			t.Error("Expected error for invalid option, got nil")
+		}
+	}
+}
+
+func TestGRPCMessageFraming(t *testing.T) {
+	payload := []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	got, err := readGRPCMessage(bytes.NewReader(appendGRPCMessage(nil, payload)), 1024)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Expected payload to round-trip, got %q (%v)", got, err)
+	}
+
+	// Unknown fields are skipped, as protobuf requires
+	withUnknown := []byte{0, 0, 0, 0, 0, 2 << 3, 42, 1<<3 | 2, 2, '{', '}'} // field 2 = 42, then the payload
+	withUnknown[4] = byte(len(withUnknown) - 5)
+	if got, err := readGRPCMessage(bytes.NewReader(withUnknown), 1024); err != nil || string(got) != "{}" {
+		t.Errorf("Expected payload next to unknown field, got %q (%v)", got, err)
+	}
+
+	// Rejected messages are consumed, so reading continues with the next one
+	stream := appendGRPCMessage(nil, bytes.Repeat([]byte("x"), 100))
+	stream = append(stream, 0, 0, 0, 0, 2, 1<<3|2, 5)
+	stream = appendGRPCMessage(stream, payload)
+	r := bytes.NewReader(stream)
+	for _, want := range []error{ErrMessageTooLarge, errMalformedMessage, nil} {
+		if _, err := readGRPCMessage(r, 64); !errors.Is(err, want) {
+			t.Errorf("Expected %v, got %v", want, err)
+		}
+	}
+	if _, err := readGRPCMessage(r, 64); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF at the end of the stream, got %v", err)
+	}
+
+	if _, err := readGRPCMessage(bytes.NewReader([]byte{1, 0, 0, 0, 0}), 64); !errors.Is(err, errCompressedMessage) {
+		t.Errorf("Expected errCompressedMessage, got %v", err)
+	}
+}
+
+func TestGRPCTransport(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewGRPC(WithGRPCAddr("127.0.0.1:0"), WithGRPCMaxMessageSize(1024))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	var addr net.Addr
+	for deadline := time.Now().Add(time.Second); addr == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Transport did not start listening")
+		}
+		time.Sleep(time.Millisecond)
+		addr = transport.Addr()
+	}
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	open := func(method string) (io.WriteCloser, *http.Response) {
+		t.Helper()
+		body, writer := io.Pipe()
+		req, _ := http.NewRequest(http.MethodPost, "http://"+addr.String()+method, body)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to open stream: %v", err)
+		}
+		return writer, resp
+	}
+
+	writer, resp := open(GRPCSessionMethod)
+	call := func(message string) mcp.Response {
+		t.Helper()
+		if _, err := writer.Write(appendGRPCMessage(nil, []byte(message))); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		payload, err := readGRPCMessage(resp.Body, DefaultMaxMessageSize)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		var response mcp.Response
+		if err := json.Unmarshal(payload, &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	response := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	if response.Error != nil {
+		t.Fatalf("Expected successful initialize, got %+v", response.Error)
+	}
+	response = call(`{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+	if response.Error != nil || response.ID != mcp.NewStringID("ping") {
+		t.Errorf("Expected ping response, got %+v", response)
+	}
+	response = call(`{"jsonrpc":"2.0","id":2,"method":"ping","params":{"padding":"` + strings.Repeat("x", 2048) + `"}}`)
+	if response.Error == nil || response.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("Expected request too large error, got %+v", response)
+	}
+	response = call(`{"jsonrpc":"2.0","id":3,`)
+	if response.Error == nil || response.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("Expected parse error, got %+v", response)
+	}
+
+	// Closing the client's side ends the call with status OK
+	_ = writer.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Fatalf("Failed to read to the end of the stream: %v", err)
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "0" {
+		t.Errorf("Expected grpc-status 0, got %q", status)
+	}
+
+	writer, resp = open("/mcp.v1.MCP/Unknown")
+	_ = writer.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if status := resp.Trailer.Get("Grpc-Status"); status != "12" {
+		t.Errorf("Expected grpc-status 12 for an unknown method, got %q", status)
+	}
+
+	// Stopping ends open calls with status UNAVAILABLE
+	writer, resp = open(GRPCSessionMethod)
+	defer writer.Close()
+	cancel()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if status := resp.Trailer.Get("Grpc-Status"); status != "14" {
+		t.Errorf("Expected grpc-status 14 on shutdown, got %q", status)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+}
+
+// grpcMessageType returns the type of mcp.v1.Message as declared in
+// proto/mcp/v1/mcp.proto, so a stock gRPC client can encode it.
+func grpcMessageType(t *testing.T) protoreflect.MessageType {
+	t.Helper()
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("mcp/v1/mcp.proto"),
+		Package: proto.String("mcp.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Message"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("payload"),
+				JsonName: proto.String("payload"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum(),
+			}},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build the message descriptor: %v", err)
+	}
+	return dynamicpb.NewMessageType(file.Messages().ByName("Message"))
+}
+
+func TestGRPCConformance(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewGRPC(WithGRPCAddr("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var addr net.Addr
+	for deadline := time.Now().Add(time.Second); addr == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Transport did not start listening")
+		}
+		time.Sleep(time.Millisecond)
+		addr = transport.Addr()
+	}
+
+	conn, err := grpc.NewClient(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	messageType := grpcMessageType(t)
+	payloadField := messageType.Descriptor().Fields().ByName("payload")
+	sessionDesc := &grpc.StreamDesc{StreamName: "Session", ClientStreams: true, ServerStreams: true}
+
+	callCtx, cancelCall := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelCall()
+	stream, err := conn.NewStream(callCtx, sessionDesc, GRPCSessionMethod)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	call := func(message string) mcp.Response {
+		t.Helper()
+		request := messageType.New()
+		request.Set(payloadField, protoreflect.ValueOfBytes([]byte(message)))
+		if err := stream.SendMsg(request.Interface()); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		reply := messageType.New().Interface()
+		if err := stream.RecvMsg(reply); err != nil {
+			t.Fatalf("Failed to receive: %v", err)
+		}
+		var response mcp.Response
+		if err := json.Unmarshal(reply.ProtoReflect().Get(payloadField).Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	response := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	if response.Error != nil {
+		t.Fatalf("Expected successful initialize, got %+v", response.Error)
+	}
+	response = call(`{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+	if response.Error != nil || response.ID != mcp.NewStringID("ping") {
+		t.Errorf("Expected ping response, got %+v", response)
+	}
+
+	// Closing the client's side ends the call with status OK
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("Failed to close the stream: %v", err)
+	}
+	if err := stream.RecvMsg(messageType.New().Interface()); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the call to end with status OK, got %v", err)
+	}
+
+	unknown, err := conn.NewStream(callCtx, sessionDesc, "/mcp.v1.MCP/Unknown")
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if err := unknown.RecvMsg(messageType.New().Interface()); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected status UNIMPLEMENTED for an unknown method, got %v", err)
+	}
+}
@@ -3,16 +3,25 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cbrgm/go-mcp-server/mcp"
 	"github.com/cbrgm/go-mcp-server/server"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 const (
@@ -27,15 +36,48 @@ const (
 )
 
 type HTTPTransport struct {
-	port            int
-	server          *http.Server
-	sessions        map[string]*SSESession
-	mu              sync.RWMutex
-	readTimeout     time.Duration
-	writeTimeout    time.Duration
-	idleTimeout     time.Duration
-	shutdownTimeout time.Duration
-	requestTimeout  time.Duration
+	port               int
+	bindAddr           string
+	servers            []*http.Server
+	listeners          []Listener
+	adminPort          int
+	adminToken         string
+	basePath           string
+	statusPage         bool
+	mcpSessions        map[string]*httpSession
+	sessionIdleTimeout time.Duration
+	sessionLimit       int
+	sessionsExpired    atomic.Uint64
+	sessionsEvicted    atomic.Uint64
+	mu                 sync.RWMutex
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	idleTimeout        time.Duration
+	shutdownTimeout    time.Duration
+	requestTimeout     time.Duration
+	maxMessageSize     int
+	acmeDomains        []string
+	acmeCacheDir       string
+	acmeEmail          string
+	acmeChallengeAddr  string
+	allowedHosts       []string
+	allowedOrigins     []string
+	trustedProxies     []string
+	responseHeaders    map[string]string
+	admission          *admission
+	pollSessions       map[string]*pollSession
+	longPollTimeout    time.Duration
+	sseKeepAlive       time.Duration
+	browserTokens      *BrowserTokens
+	eventStore         EventStore
+	cors               *corsPolicy
+	nextStreamID       atomic.Uint64
+
+	// draining is set while Stop lets in-flight requests finish.
+	draining atomic.Bool
+
+	// cancelHandlers cancels the requests still running after the drain.
+	cancelHandlers context.CancelFunc
 }
 
 type HTTPResponseSender struct {
@@ -49,7 +91,7 @@ func (h *HTTPResponseSender) SendResponse(response mcp.Response) error {
 	defer h.mu.Unlock()
 
 	if h.sent {
-		return fmt.Errorf("response already sent")
+		return ErrResponseAlreadySent
 	}
 
 	h.writer.Header().Set("Content-Type", contentTypeJSON)
@@ -59,7 +101,7 @@ func (h *HTTPResponseSender) SendResponse(response mcp.Response) error {
 	return err
 }
 
-func (h *HTTPResponseSender) SendError(id any, code int, message string, data any) error {
+func (h *HTTPResponseSender) SendError(id mcp.RequestID, code int, message string, data any) error {
 	errorResp := &mcp.ErrorResponse{
 		Code:    code,
 		Message: message,
@@ -81,62 +123,330 @@ func (s *SSEResponseSender) SendResponse(response mcp.Response) error {
 	return s.session.sendEvent("", response)
 }
 
-func (s *SSEResponseSender) SendError(id any, code int, message string, data any) error {
+func (s *SSEResponseSender) SendError(id mcp.RequestID, code int, message string, data any) error {
 	return s.session.sendError(id, code, message, data)
 }
 
+func (s *SSEResponseSender) SendNotification(notification mcp.Notification) error {
+	return s.session.SendNotification(notification)
+}
+
+func (s *SSEResponseSender) SendRequest(request mcp.Request) error {
+	return s.session.SendRequest(request)
+}
+
 type SSESession struct {
-	ID      string
-	writer  http.ResponseWriter
-	flusher http.Flusher
-	eventID int
-	mu      sync.Mutex
-	closed  bool
+	ID           string
+	writer       http.ResponseWriter
+	flusher      http.Flusher
+	controller   *http.ResponseController
+	writeTimeout time.Duration
+	eventID      int
+	streamID     string
+	events       EventStore
+	lastWrite    time.Time
+	mu           sync.Mutex
+	closed       bool
+}
+
+// NewHTTP creates a new HTTP transport configured by the given options.
+//
+// Unset options fall back to defaults: port 8080, 30s read, write and request
+// timeouts, a 120s idle timeout and a 5s shutdown timeout.
+//
+// Example usage:
+//
+//	transport, err := NewHTTP(
+//	    WithPort(3000),
+//	    WithRequestTimeout(60*time.Second),
+//	    WithAllowedHosts("localhost"),
+//	)
+func NewHTTP(opts ...HTTPOption) (*HTTPTransport, error) {
+	t := &HTTPTransport{
+		port:               DefaultHTTPPort,
+		bindAddr:           DefaultHTTPBindAddr,
+		statusPage:         true,
+		mcpSessions:        make(map[string]*httpSession),
+		sessionIdleTimeout: DefaultSessionIdleTimeout,
+		pollSessions:       make(map[string]*pollSession),
+		longPollTimeout:    DefaultLongPollTimeout,
+		sseKeepAlive:       DefaultSSEKeepAlive,
+		acmeChallengeAddr:  DefaultACMEChallengeAddr,
+		readTimeout:        DefaultHTTPReadTimeout,
+		writeTimeout:       DefaultHTTPWriteTimeout,
+		idleTimeout:        DefaultHTTPIdleTimeout,
+		shutdownTimeout:    DefaultHTTPShutdownTimeout,
+		requestTimeout:     DefaultHTTPRequestTimeout,
+		maxMessageSize:     DefaultMaxMessageSize,
+		eventStore:         NewMemoryEventStore(DefaultEventStoreSize),
+		cors:               newCORSPolicy(DefaultCORSRules()),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid HTTP transport options: %w", err)
+	}
+
+	return t, nil
 }
 
-func NewHTTP(port int, readTimeout, writeTimeout, idleTimeout, shutdownTimeout, requestTimeout time.Duration) *HTTPTransport {
-	return &HTTPTransport{
-		port:            port,
-		sessions:        make(map[string]*SSESession),
-		readTimeout:     readTimeout,
-		writeTimeout:    writeTimeout,
-		idleTimeout:     idleTimeout,
-		shutdownTimeout: shutdownTimeout,
-		requestTimeout:  requestTimeout,
+// NewHTTPWithListener creates an HTTP transport serving on a listener bound
+// by the caller instead of on the configured port, see Listener.NetListener.
+// Further listeners can be added with WithListener.
+//
+// Example usage:
+//
+//	l, err := net.Listen("tcp", "127.0.0.1:0")
+//	if err != nil {
+//	    return err
+//	}
+//	transport, err := NewHTTPWithListener(l, WithRequestTimeout(60*time.Second))
+func NewHTTPWithListener(l net.Listener, opts ...HTTPOption) (*HTTPTransport, error) {
+	if l == nil {
+		return nil, fmt.Errorf("invalid HTTP transport options: nil listener")
 	}
+	return NewHTTP(append([]HTTPOption{WithListener(Listener{NetListener: l})}, opts...)...)
 }
 
 func (t *HTTPTransport) Start(ctx context.Context, srv *server.Server) error {
-	mux := http.NewServeMux()
+	listeners := t.effectiveListeners()
 
-	handler := t.corsMiddleware(t.securityMiddleware(mux))
+	shared := func(next http.Handler) http.Handler {
+		return t.forwardedMiddleware(t.hostValidationMiddleware(t.originValidationMiddleware(t.corsMiddleware(t.securityMiddleware(next)))))
+	}
 
-	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			t.handlePost(ctx, srv, w, r)
-		case http.MethodGet:
-			t.handleGet(ctx, srv, w, r)
-		case http.MethodOptions:
-			w.WriteHeader(http.StatusOK)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	manager := t.acmeManager()
+	scheme := "http"
+	if manager != nil {
+		scheme = "https"
+		log.Printf("ACME enabled for domains: %s", strings.Join(t.acmeDomains, ", "))
+	}
+
+	// Bind every address before serving, so a failing listener leaves nothing running
+	netListeners := make([]net.Listener, 0, len(listeners))
+	for _, l := range listeners {
+		listener, err := l.listen()
+		if err != nil {
+			for _, bound := range netListeners {
+				_ = bound.Close()
+			}
+			for _, l := range listeners {
+				if l.NetListener != nil {
+					_ = l.NetListener.Close()
+				}
+			}
+			return bindError(l.Addr, err)
+		}
+		netListeners = append(netListeners, listener)
+	}
+	t.port = listenerPort(netListeners[0])
+
+	var challengeListener net.Listener
+	if manager != nil && t.acmeChallengeAddr != "" {
+		var err error
+		if challengeListener, err = net.Listen("tcp", t.acmeChallengeAddr); err != nil {
+			for _, bound := range netListeners {
+				_ = bound.Close()
+			}
+			return bindError(t.acmeChallengeAddr, err)
+		}
+	}
+
+	// A server failing after binding ends the transport, rather than leaving
+	// the process running without it
+	serveErrs := make(chan error, len(listeners)+1)
+
+	// Requests may outlive ctx to finish during the drain, see Stop
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+
+	t.mu.Lock()
+	t.draining.Store(false)
+	t.cancelHandlers = cancelHandlers
+	for i, l := range listeners {
+		handler := t.stripBasePath(l.handler(t.newMux(handlerCtx, srv, l.Endpoints), shared))
+		httpServer := &http.Server{
+			Addr:         l.addr(),
+			Handler:      handler,
+			ReadTimeout:  t.readTimeout,
+			WriteTimeout: t.writeTimeout,
+			IdleTimeout:  t.idleTimeout,
+		}
+		if manager != nil {
+			httpServer.TLSConfig = manager.TLSConfig()
+		}
+		t.servers = append(t.servers, httpServer)
+
+		listener := netListeners[i]
+		log.Printf("Starting HTTP transport on %s (%s endpoints)...", listener.Addr(), l.Endpoints)
+		if port := listenerPort(listener); port != 0 && l.Endpoints.servesMCP() {
+			log.Printf("MCP endpoint: %s://localhost:%d%s/mcp", scheme, port, t.basePath)
 		}
-	})
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
+		go func() {
+			var err error
+			if httpServer.TLSConfig != nil {
+				err = httpServer.ServeTLS(listener, "", "")
+			} else {
+				err = httpServer.Serve(listener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				serveErrs <- fmt.Errorf("HTTP server on %s failed: %w", listener.Addr(), err)
+			}
+		}()
+	}
+	if challengeListener != nil {
+		challengeServer := &http.Server{
+			Handler:      manager.HTTPHandler(nil),
+			ReadTimeout:  t.readTimeout,
+			WriteTimeout: t.writeTimeout,
+			IdleTimeout:  t.idleTimeout,
+		}
+		t.servers = append(t.servers, challengeServer)
+
+		log.Printf("Answering ACME HTTP-01 challenges on %s", challengeListener.Addr())
+		go func() {
+			if err := challengeServer.Serve(challengeListener); err != nil && err != http.ErrServerClosed {
+				serveErrs <- fmt.Errorf("ACME challenge server on %s failed: %w", challengeListener.Addr(), err)
+			}
+		}()
+	}
+	t.mu.Unlock()
+
+	if t.sessionIdleTimeout > 0 {
+		go t.expireSessions(ctx, srv)
+	}
+
+	if err := srv.AnnounceReady(ctx, "http", t.port); err != nil {
+		log.Printf("Failed to announce readiness: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Println("HTTP transport shutting down")
+		return t.Stop()
+	case err := <-serveErrs:
+		log.Printf("HTTP transport shutting down: %v", err)
+		return errors.Join(err, t.Stop())
+	}
+}
+
+// acmeManager returns the manager obtaining certificates via ACME, or nil if
+// ACME is not enabled.
+func (t *HTTPTransport) acmeManager() *autocert.Manager {
+	if len(t.acmeDomains) == 0 {
+		return nil
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(t.acmeDomains...),
+		Cache:      autocert.DirCache(t.acmeCacheDir),
+		Email:      t.acmeEmail,
+	}
+}
+
+// effectiveListeners returns the configured listeners, or the default
+// listener on the bind address and port, plus the admin listener if enabled.
+func (t *HTTPTransport) effectiveListeners() []Listener {
+	listeners := slices.Clone(t.listeners)
+	if len(listeners) == 0 {
+		endpoints := EndpointsAll
+		if t.adminPort != 0 {
+			endpoints = EndpointsMCP
+		}
+		listeners = []Listener{{Addr: net.JoinHostPort(t.bindAddr, strconv.Itoa(t.port)), Endpoints: endpoints}}
+	}
+	if t.adminPort != 0 {
+		listeners = append(listeners, Listener{Addr: net.JoinHostPort(t.bindAddr, strconv.Itoa(t.adminPort)), Endpoints: EndpointsOps})
+	}
+	return listeners
+}
+
+// stripBasePath serves the handler under the base path, which it strips so
+// routing, CORS rules and middleware see the paths relative to it. Requests
+// outside the base path are not found.
+func (t *HTTPTransport) stripBasePath(next http.Handler) http.Handler {
+	if t.basePath == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, t.basePath)
+		if !ok || (rest != "" && rest[0] != '/') {
 			http.NotFound(w, r)
 			return
 		}
-		t.handleStatusPage(w, r)
+		if rest == "" {
+			rest = "/"
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
 	})
+}
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept")
+// newMux registers the endpoints a listener serves.
+func (t *HTTPTransport) newMux(ctx context.Context, srv *server.Server, endpoints Endpoints) *http.ServeMux {
+	mux := http.NewServeMux()
 
+	if endpoints.servesMCP() {
+		mux.HandleFunc("/mcp", t.rejectWhileDraining(t.requireBrowserToken(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost:
+				t.handlePost(ctx, srv, w, r)
+			case http.MethodGet:
+				if wantsLongPoll(r) {
+					t.handlePoll(ctx, srv, w, r)
+					return
+				}
+				t.handleGet(ctx, srv, w, r)
+			case http.MethodDelete:
+				t.handleDelete(srv, w, r)
+			case http.MethodOptions:
+				w.WriteHeader(http.StatusOK)
+			default:
+				w.Header().Set("Allow", "GET, POST, DELETE, OPTIONS")
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+
+		mux.HandleFunc("/mcp/poll", t.rejectWhileDraining(t.requireBrowserToken(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				t.handlePoll(ctx, srv, w, r)
+			case http.MethodOptions:
+				w.WriteHeader(http.StatusOK)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+
+		if t.browserTokens != nil {
+			mux.HandleFunc("/mcp/token", t.handleBrowserToken)
+		}
+	}
+
+	if !endpoints.servesOps() {
+		return mux
+	}
+
+	if t.statusPage {
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			t.handleStatusPage(w, r)
+		})
+	}
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -149,61 +459,260 @@ func (t *HTTPTransport) Start(ctx context.Context, srv *server.Server) error {
 		}
 	})
 
-	t.server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", t.port),
-		Handler:      handler,
-		ReadTimeout:  t.readTimeout,
-		WriteTimeout: t.writeTimeout,
-		IdleTimeout:  t.idleTimeout,
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		body := map[string]any{"status": "ready"}
+		if !srv.Ready() {
+			status = http.StatusServiceUnavailable
+			body["status"] = "degraded"
+		}
+		if slos := srv.SLOStatus(); len(slos) > 0 {
+			body["slos"] = slos
+		}
+
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			log.Printf("Failed to encode readiness response: %v", err)
+		}
+	})
+
+	// Profiling is only safe on listeners dedicated to operations
+	if endpoints == EndpointsOps {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	// The admin endpoints expose transcripts and change the server at
+	// runtime, so they always require authentication
+	if endpoints == EndpointsOps && t.adminToken != "" {
+		mux.HandleFunc("/admin/logging", t.adminAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleAdminLogging(w, r, srv)
+		}))
+		mux.HandleFunc("/admin/settings", t.adminAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleAdminSettings(ctx, w, r, srv)
+		}))
+		mux.HandleFunc("/admin/transcripts/", t.adminAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleAdminTranscripts(w, r, srv)
+		}))
+		mux.HandleFunc("/admin/cors", t.adminAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleAdminCORS(w, r, t)
+		}))
+		mux.HandleFunc("/admin/sessions", t.adminAuth(func(w http.ResponseWriter, r *http.Request) {
+			handleAdminSessions(w, r, t)
+		}))
+	}
+
+	return mux
+}
+
+// handleAdminSettings reads (GET) or updates (PUT) the server's runtime settings.
+// Fields missing from a PUT body keep their current value.
+func handleAdminSettings(ctx context.Context, w http.ResponseWriter, r *http.Request, srv *server.Server) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		settings := srv.Settings()
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&settings); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid settings: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := srv.SetSettings(ctx, settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	log.Printf("Starting HTTP transport on port %d...", t.port)
-	log.Printf("MCP endpoint: http://localhost:%d/mcp", t.port)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(srv.Settings()); err != nil {
+		log.Printf("Failed to encode settings: %v", err)
+	}
+}
+
+// handleAdminTranscripts lists the sessions with a recorded transcript
+// (GET /admin/transcripts/) or exports the transcript of one session
+// (GET /admin/transcripts/{session}), as JSON or with ?format=markdown as
+// a Markdown file.
+func handleAdminTranscripts(w http.ResponseWriter, r *http.Request, srv *server.Server) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	go func() {
-		if err := t.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+	sessionID := strings.TrimPrefix(r.URL.Path, "/admin/transcripts/")
+	if sessionID == "" {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		if err := json.NewEncoder(w).Encode(map[string][]string{"sessions": srv.TranscriptSessions()}); err != nil {
+			log.Printf("Failed to encode transcript sessions: %v", err)
 		}
-	}()
+		return
+	}
+
+	transcript, ok := srv.Transcript(sessionID)
+	if !ok {
+		http.Error(w, "Transcript not found", http.StatusNotFound)
+		return
+	}
 
-	<-ctx.Done()
-	log.Println("HTTP transport shutting down")
-	return t.Stop()
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "transcript-"+sessionID+".json"))
+		if err := json.NewEncoder(w).Encode(transcript); err != nil {
+			log.Printf("Failed to encode transcript: %v", err)
+		}
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "transcript-"+sessionID+".md"))
+		if _, err := io.WriteString(w, transcript.Markdown()); err != nil {
+			log.Printf("Failed to write transcript: %v", err)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported format %q, expected json or markdown", format), http.StatusBadRequest)
+	}
 }
 
+// handleAdminLogging reads (GET) or replaces (PUT) the server's per-request log policy.
+func handleAdminLogging(w http.ResponseWriter, r *http.Request, srv *server.Server) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var policy server.LogPolicy
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&policy); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid log policy: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := srv.SetLogPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(srv.LogPolicy()); err != nil {
+		log.Printf("Failed to encode log policy: %v", err)
+	}
+}
+
+// Stop shuts the transport down gracefully. New MCP requests are refused
+// with 503 Service Unavailable, open event streams receive a final shutdown
+// event and are closed, and in-flight requests may finish within the
+// shutdown timeout. Requests still running then are cancelled and their
+// connections closed.
 func (t *HTTPTransport) Stop() error {
+	t.draining.Store(true)
+
+	// Written outside the lock, since a slow client may block the write
+	t.mu.RLock()
+	var streams []*SSESession
+	for _, session := range t.mcpSessions {
+		for _, stream := range session.streams {
+			streams = append(streams, stream.sse)
+		}
+	}
+	t.mu.RUnlock()
+	for _, stream := range streams {
+		if err := stream.sendEvent("shutdown", map[string]string{
+			"sessionId": stream.ID,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}); err != nil && !errors.Is(err, ErrSessionClosed) {
+			log.Printf("Failed to send shutdown event to session %s: %v", stream.ID, err)
+		}
+	}
+
 	t.mu.Lock()
-	for _, session := range t.sessions {
+	for _, session := range t.mcpSessions {
+		session.closeStreams()
+	}
+	for _, session := range t.pollSessions {
+		session.expiry.Stop()
 		session.close()
 	}
-	t.sessions = make(map[string]*SSESession)
+	servers := t.servers
+	t.servers = nil
+	cancelHandlers := t.cancelHandlers
+	t.cancelHandlers = nil
 	t.mu.Unlock()
 
-	if t.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), t.shutdownTimeout)
-		defer cancel()
-		return t.server.Shutdown(ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), t.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, httpServer := range servers {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down %s: %w", httpServer.Addr, err))
+			_ = httpServer.Close()
+		}
+	}
+	if cancelHandlers != nil {
+		cancelHandlers()
+	}
+
+	t.mu.Lock()
+	t.mcpSessions = make(map[string]*httpSession)
+	t.pollSessions = make(map[string]*pollSession)
+	t.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// rejectWhileDraining refuses MCP requests while Stop drains the transport.
+func (t *HTTPTransport) rejectWhileDraining(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if t.draining.Load() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r)
 	}
-	return nil
 }
 
 func (t *HTTPTransport) handlePost(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request) {
-	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if err := validateContentType(r.Header.Get("Content-Type")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	protocolVersion := r.Header.Get(headerMCPProtocolVersion)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(t.maxMessageSize)))
+	if err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			log.Printf("Rejecting request body larger than %d bytes", maxErr.Limit)
+			t.sendErrorStatus(w, http.StatusRequestEntityTooLarge, mcp.NewIntID(-1), mcp.ErrorCodeInvalidRequest, "Request too large",
+				fmt.Sprintf("%v: limit is %d bytes", ErrMessageTooLarge, maxErr.Limit))
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
 
-	// TODO: add back when uprading to the most recent MCP spec
-	// protocolVersion := r.Header.Get("MCP-Protocol-Version")
-	var req mcp.Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		t.sendError(w, -1, mcp.ErrorCodeParseError, "Parse error", err.Error())
+	msg, id, rejected := decodeMessage(srv, body)
+	if rejected != nil {
+		t.sendError(w, id, rejected.Code, rejected.Message, rejected.Data)
+		return
+	}
+	req := msg.request()
+
+	// Clients send the negotiated version on every request after initialization
+	if req.Method != "initialize" && protocolVersion != "" && !mcp.IsSupportedProtocolVersion(protocolVersion) {
+		t.sendError(w, req.ID, mcp.ErrorCodeInvalidRequest,
+			fmt.Sprintf("Unsupported protocol version: %s", protocolVersion), nil)
 		return
 	}
 
-	// TODO: add back when uprading to the most recent MCP spec
-	// if req.Method != "initialize" && protocolVersion != "" && protocolVersion != mcp.ProtocolVersion {
-	// 	t.sendError(w, req.ID, mcp.ErrorCodeInvalidRequest,
-	// 		fmt.Sprintf("Unsupported protocol version: %s", protocolVersion), nil)
-	// 	return
-	// }
 	acceptHeader := r.Header.Get("Accept")
 	wantsSSE := strings.Contains(acceptHeader, "text/event-stream")
 	wantsJSON := strings.Contains(acceptHeader, "application/json")
@@ -218,50 +727,165 @@ func (t *HTTPTransport) handlePost(ctx context.Context, srv *server.Server, w ht
 		return
 	}
 
+	// initialize opens a new session, every other message must belong to one
+	var sessionID string
+	if req.Method == "initialize" && !msg.isResponse() && !req.ID.IsZero() {
+		session, err := t.createSession(srv)
+		if err != nil {
+			log.Printf("Rejecting new session: %v", err)
+			w.Header().Set("Retry-After", strconv.Itoa(int(DefaultSessionRetryAfter/time.Second)))
+			http.Error(w, "Too many sessions", http.StatusServiceUnavailable)
+			return
+		}
+		sessionID = session.ID
+		w.Header().Set(headerMCPSessionID, sessionID)
+	} else {
+		session := t.requireSession(w, r)
+		if session == nil {
+			return
+		}
+		sessionID = session.ID
+		t.admission.touch(sessionID)
+	}
+	msgCtx := context.WithValue(ctx, mcp.SessionIDKey, sessionID)
+
+	// Handle responses to server-initiated requests (no response expected)
+	if msg.isResponse() {
+		if err := srv.HandleResponse(msgCtx, msg.response()); err != nil {
+			log.Printf("Error handling response %v: %v", msg.ID, err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	// Handle notifications (no response expected)
-	if req.ID == nil {
-		log.Printf("Received notification: %s", req.Method)
-		w.WriteHeader(http.StatusNoContent)
+	if req.ID.IsZero() {
+		if err := srv.HandleNotification(msgCtx, msg.notification()); err != nil {
+			log.Printf("Error handling notification %q: %v", req.Method, err)
+		}
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
 	// If client wants SSE and this is a request, start SSE stream
-	if wantsSSE && req.ID != nil {
-		t.handleSSERequest(ctx, srv, w, r, req)
+	if wantsSSE {
+		t.handleSSERequest(ctx, srv, w, r, sessionID, req)
 		return
 	}
 
 	// Handle regular JSON response
-	t.handleJSONRequest(ctx, srv, w, req)
+	t.handleJSONRequest(ctx, srv, w, r, sessionID, req)
+}
+
+// withRequestLocale adds the language preferred via Accept-Language to ctx.
+func withRequestLocale(ctx context.Context, r *http.Request) context.Context {
+	tag := mcp.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if tag == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, mcp.LocaleKey, mcp.Locale{Language: tag})
+}
+
+// validateContentType ensures a POST body is JSON encoded as UTF-8.
+//
+// JSON exchanged between systems must be UTF-8 (RFC 8259), so a charset
+// parameter is optional but must name UTF-8 when present.
+func validateContentType(contentType string) error {
+	if contentType == "" {
+		return errors.New("missing Content-Type header, expected application/json")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("malformed Content-Type header: %w", err)
+	}
+	if mediaType != "application/json" {
+		return fmt.Errorf("unsupported Content-Type %q, expected application/json", mediaType)
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "utf8") {
+		return fmt.Errorf("unsupported charset %q, expected utf-8", charset)
+	}
+
+	return nil
 }
 
 func (t *HTTPTransport) handleGet(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request) {
-	_ = srv // Server not used for GET but kept for consistency
 	// GET is used to open SSE streams or resume connections
-	session := t.startSSEStream(w, r)
+	mcpSession := t.requireSession(w, r)
+	if mcpSession == nil {
+		return
+	}
+
+	streamCtx, evict := context.WithCancel(r.Context())
+	defer evict()
+
+	var admitted *admittedStream
+	if t.admission != nil {
+		if admitted = t.admission.admit(w, clientIP(r), evict); admitted == nil {
+			return
+		}
+		defer t.admission.release(admitted)
+	}
+
+	session := t.startSSEStream(w, r, mcpSession.ID)
 	if session == nil {
 		return
 	}
+	if admitted != nil {
+		t.admission.bind(admitted, session.ID)
+	}
 
-	// Keep the connection alive until context is canceled
-	<-ctx.Done()
+	// The standalone stream carries server-initiated notifications and requests
+	if !t.openStream(srv, mcpSession, session, evict) {
+		// Deleted while the stream was being opened
+		return
+	}
+	defer t.closeStream(srv, mcpSession, session)
 
-	// Clean up session
-	t.mu.Lock()
-	delete(t.sessions, session.ID)
-	t.mu.Unlock()
+	var keepAlive <-chan time.Time
+	if t.sseKeepAlive > 0 {
+		ticker := time.NewTicker(t.sseKeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	// Keep the connection alive until the server shuts down, the client
+	// disconnects or the session is evicted or deleted
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-streamCtx.Done():
+			return
+		case <-keepAlive:
+			if err := session.keepAlive(t.sseKeepAlive); err != nil {
+				log.Printf("Closing event stream of session %s: %v", session.ID, err)
+				return
+			}
+		}
+	}
 }
 
-func (t *HTTPTransport) handleJSONRequest(ctx context.Context, srv *server.Server, w http.ResponseWriter, req mcp.Request) {
+func (t *HTTPTransport) handleJSONRequest(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request, sessionID string, req mcp.Request) {
 	reqCtx, cancel := context.WithTimeout(ctx, t.requestTimeout)
 	defer cancel()
 
 	httpSender := &HTTPResponseSender{writer: w}
 	reqCtx = context.WithValue(reqCtx, mcp.ResponseSenderKey, httpSender)
+	reqCtx = withRequestLocale(reqCtx, r)
+	reqCtx = context.WithValue(reqCtx, mcp.SessionIDKey, sessionID)
 
 	if err := srv.HandleRequest(reqCtx, req); err != nil {
 		log.Printf("Error handling request: %v", err)
-		if !httpSender.sent {
+		if httpSender.sent {
+			return
+		}
+		switch {
+		case errors.Is(err, mcp.ErrUnauthorized):
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case errors.Is(err, mcp.ErrTimeout):
+			t.sendError(w, req.ID, mcp.ErrorCodeInternalError, "Request timed out", err.Error())
+		default:
 			t.sendError(w, req.ID, mcp.ErrorCodeInternalError, "Internal error", err.Error())
 		}
 		return
@@ -272,8 +896,8 @@ func (t *HTTPTransport) handleJSONRequest(ctx context.Context, srv *server.Serve
 	}
 }
 
-func (t *HTTPTransport) handleSSERequest(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request, req mcp.Request) {
-	session := t.startSSEStream(w, r)
+func (t *HTTPTransport) handleSSERequest(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request, sessionID string, req mcp.Request) {
+	session := t.startSSEStream(w, r, sessionID)
 	if session == nil {
 		return
 	}
@@ -283,7 +907,8 @@ func (t *HTTPTransport) handleSSERequest(ctx context.Context, srv *server.Server
 
 	sseSender := &SSEResponseSender{session: session}
 	reqCtx = context.WithValue(reqCtx, mcp.ResponseSenderKey, sseSender)
-	reqCtx = context.WithValue(reqCtx, mcp.SessionIDKey, session.ID)
+	reqCtx = context.WithValue(reqCtx, mcp.SessionIDKey, sessionID)
+	reqCtx = withRequestLocale(reqCtx, r)
 
 	if err := srv.HandleRequest(reqCtx, req); err != nil {
 		log.Printf("Error handling SSE request: %v", err)
@@ -293,7 +918,8 @@ func (t *HTTPTransport) handleSSERequest(ctx context.Context, srv *server.Server
 	}
 }
 
-func (t *HTTPTransport) startSSEStream(w http.ResponseWriter, r *http.Request) *SSESession {
+// startSSEStream answers the request with an event stream of the given session.
+func (t *HTTPTransport) startSSEStream(w http.ResponseWriter, r *http.Request, sessionID string) *SSESession {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
@@ -303,33 +929,28 @@ func (t *HTTPTransport) startSSEStream(w http.ResponseWriter, r *http.Request) *
 	w.Header().Set("Content-Type", contentTypeSSE)
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	lastEventID := r.Header.Get("Last-Event-ID")
-	eventID := 0
-	if lastEventID != "" {
-		if id, err := strconv.Atoi(lastEventID); err == nil {
-			eventID = id + 1
-		}
-	}
-
-	sessionID := r.Header.Get("Mcp-Session-Id")
-	if sessionID == "" {
-		sessionID = fmt.Sprintf("%s%d", sessionIDPrefix, time.Now().UnixNano())
-	}
 	session := &SSESession{
-		ID:      sessionID,
-		writer:  w,
-		flusher: flusher,
-		eventID: eventID,
+		ID:           sessionID,
+		writer:       w,
+		flusher:      flusher,
+		controller:   http.NewResponseController(w),
+		writeTimeout: t.writeTimeout,
+		streamID:     strconv.FormatUint(t.nextStreamID.Add(1), 10),
+		events:       t.eventStore,
 	}
 
-	t.mu.Lock()
-	t.sessions[sessionID] = session
-	t.mu.Unlock()
-
 	w.Header().Set(headerMCPSessionID, sessionID)
 
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		if t.eventStore != nil {
+			t.resumeStream(session, lastEventID)
+		} else if id, err := strconv.Atoi(lastEventID); err == nil {
+			// Without a store only the event numbering continues
+			session.eventID = id + 1
+		}
+	}
+
 	if err := session.sendEvent("connected", map[string]string{
 		"sessionId": sessionID,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
@@ -340,7 +961,12 @@ func (t *HTTPTransport) startSSEStream(w http.ResponseWriter, r *http.Request) *
 	return session
 }
 
-func (t *HTTPTransport) sendError(w http.ResponseWriter, id any, code int, message string, data any) {
+func (t *HTTPTransport) sendError(w http.ResponseWriter, id mcp.RequestID, code int, message string, data any) {
+	t.sendErrorStatus(w, http.StatusBadRequest, id, code, message, data)
+}
+
+// sendErrorStatus writes a JSON-RPC error response with the HTTP status.
+func (t *HTTPTransport) sendErrorStatus(w http.ResponseWriter, status int, id mcp.RequestID, code int, message string, data any) {
 	errorResp := mcp.Response{
 		JSONRPC: mcp.JSONRPCVersion,
 		ID:      id,
@@ -352,18 +978,41 @@ func (t *HTTPTransport) sendError(w http.ResponseWriter, id any, code int, messa
 	}
 
 	w.Header().Set("Content-Type", contentTypeJSON)
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(errorResp); err != nil {
 		log.Printf("Failed to encode error response: %v", err)
 	}
 }
 
+// resumeStream continues the stream that the event lastEventID was sent on,
+// replaying the events the client missed. Unknown or expired event IDs start
+// a new stream without replay.
+func (t *HTTPTransport) resumeStream(session *SSESession, lastEventID string) {
+	streamID, events, err := t.eventStore.EventsAfter(session.ID, lastEventID)
+	if err != nil {
+		log.Printf("Cannot resume stream of session %s: %v", session.ID, err)
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.streamID = streamID
+	for _, event := range events {
+		if err := session.writeEvent(event.ID, event.Type, event.Data); err != nil {
+			log.Printf("Failed to replay event %s: %v", event.ID, err)
+			return
+		}
+	}
+	log.Printf("Resumed stream of session %s, replayed %d events", session.ID, len(events))
+}
+
 func (s *SSESession) sendEvent(eventType string, data any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.closed {
-		return fmt.Errorf("session closed")
+		return ErrSessionClosed
 	}
 
 	dataBytes, err := json.Marshal(data)
@@ -371,7 +1020,59 @@ func (s *SSESession) sendEvent(eventType string, data any) error {
 		return err
 	}
 
-	if _, err := fmt.Fprintf(s.writer, "id: %d\n", s.eventID); err != nil {
+	id := strconv.Itoa(s.eventID)
+	if s.events != nil {
+		// Stored before writing, so events lost to a dropped connection can be replayed
+		if id, err = s.events.StoreEvent(s.ID, s.streamID, Event{Type: eventType, Data: dataBytes}); err != nil {
+			return fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+
+	if err := s.writeEvent(id, eventType, dataBytes); err != nil {
+		return err
+	}
+	s.eventID++
+
+	return nil
+}
+
+// keepAlive writes a comment to the stream if nothing was written for the
+// given interval. Clients ignore comments, but intermediaries see traffic.
+func (s *SSESession) keepAlive(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSessionClosed
+	}
+	if time.Since(s.lastWrite) < interval {
+		return nil
+	}
+
+	s.extendWriteDeadline()
+	if _, err := fmt.Fprint(s.writer, ": ping\n\n"); err != nil {
+		return fmt.Errorf("failed to write keepalive: %w", err)
+	}
+	s.flusher.Flush()
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// extendWriteDeadline gives the next write a full write timeout. The
+// server's write timeout otherwise counts from the start of the response,
+// which would end long-lived streams. The caller must hold s.mu.
+func (s *SSESession) extendWriteDeadline() {
+	if s.controller == nil {
+		return
+	}
+	// Not every ResponseWriter supports deadlines, e.g. in tests
+	_ = s.controller.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+}
+
+// writeEvent writes a single event to the stream. The caller must hold s.mu.
+func (s *SSESession) writeEvent(id, eventType string, dataBytes []byte) error {
+	s.extendWriteDeadline()
+	if _, err := fmt.Fprintf(s.writer, "id: %s\n", id); err != nil {
 		return fmt.Errorf("failed to write event ID: %w", err)
 	}
 	if eventType != "" {
@@ -392,12 +1093,11 @@ func (s *SSESession) sendEvent(eventType string, data any) error {
 	}
 
 	s.flusher.Flush()
-	s.eventID++
-
+	s.lastWrite = time.Now()
 	return nil
 }
 
-func (s *SSESession) sendError(id any, code int, message string, data any) error {
+func (s *SSESession) sendError(id mcp.RequestID, code int, message string, data any) error {
 	errorResp := mcp.Response{
 		JSONRPC: mcp.JSONRPCVersion,
 		ID:      id,
@@ -411,37 +1111,27 @@ func (s *SSESession) sendError(id any, code int, message string, data any) error
 	return s.sendEvent("", errorResp)
 }
 
+func (s *SSESession) SendNotification(notification mcp.Notification) error {
+	return s.sendEvent("", notification)
+}
+
+// SendRequest sends a server-initiated request; the client POSTs its response back.
+func (s *SSESession) SendRequest(request mcp.Request) error {
+	return s.sendEvent("", request)
+}
+
 func (s *SSESession) close() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.closed = true
 }
 
-func (t *HTTPTransport) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Last-Event-ID, Mcp-Session-Id, MCP-Protocol-Version")
-		w.Header().Set("Access-Control-Allow-Credentials", "false")
-		w.Header().Set("Access-Control-Max-Age", "86400")
-
-		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, MCP-Protocol-Version")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 func (t *HTTPTransport) handleStatusPage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", contentTypeHTML)
 	w.WriteHeader(http.StatusOK)
 
 	t.mu.RLock()
-	activeSessions := len(t.sessions)
+	activeSessions := len(t.mcpSessions)
 	t.mu.RUnlock()
 
 	html := `<!DOCTYPE html>
@@ -585,17 +1275,25 @@ func (t *HTTPTransport) handleStatusPage(w http.ResponseWriter, r *http.Request)
         <div class="endpoints">
             <h3>Endpoints</h3>
             <div class="endpoint">
-                <div><span class="method">POST</span>/mcp</div>
+                <div><span class="method">POST</span>%[4]s/mcp</div>
                 <span>JSON-RPC 2.0</span>
             </div>
             <div class="endpoint">
-                <div><span class="method">GET</span>/mcp</div>
+                <div><span class="method">GET</span>%[4]s/mcp</div>
                 <span>Server-Sent Events</span>
             </div>
             <div class="endpoint">
-                <div><span class="method">GET</span>/health</div>
+                <div><span class="method">DELETE</span>%[4]s/mcp</div>
+                <span>End Session</span>
+            </div>
+            <div class="endpoint">
+                <div><span class="method">GET</span>%[4]s/health</div>
                 <span>Health Check</span>
             </div>
+            <div class="endpoint">
+                <div><span class="method">GET</span>%[4]s/readyz</div>
+                <span>Readiness Check</span>
+            </div>
         </div>
 
         <div class="footer">
@@ -609,6 +1307,7 @@ func (t *HTTPTransport) handleStatusPage(w http.ResponseWriter, r *http.Request)
 		t.port,              // Port
 		mcp.ProtocolVersion, // MCP protocol version
 		activeSessions,      // Active sessions
+		t.basePath,          // Base path of the endpoints
 	)
 }
 
@@ -617,6 +1316,47 @@ func (t *HTTPTransport) securityMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
+		for name, value := range t.responseHeaders {
+			if value == "" {
+				w.Header().Del(name)
+				continue
+			}
+			w.Header().Set(name, value)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (t *HTTPTransport) hostValidationMiddleware(next http.Handler) http.Handler {
+	if len(t.allowedHosts) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.isAllowedHost(r.Host) {
+			log.Printf("Rejected request from %s with disallowed Host header: %q", clientIP(r), r.Host)
+			http.Error(w, "Misdirected request", http.StatusMisdirectedRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *HTTPTransport) isAllowedHost(host string) bool {
+	if host == "" {
+		return false
+	}
+
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.Trim(hostname, "[]")
+
+	for _, allowed := range t.allowedHosts {
+		if strings.EqualFold(allowed, host) || strings.EqualFold(strings.Trim(allowed, "[]"), hostname) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1685 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestHostValidationMiddleware(t *testing.T) {
+	transport, err := NewHTTP(WithAllowedHosts("localhost", "127.0.0.1:8080", "[::1]"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := transport.hostValidationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		host       string
+		wantStatus int
+	}{
+		{"hostname without port", "localhost", http.StatusOK},
+		{"hostname with any port", "localhost:9000", http.StatusOK},
+		{"hostname is case insensitive", "LOCALHOST:8080", http.StatusOK},
+		{"exact host and port", "127.0.0.1:8080", http.StatusOK},
+		{"wrong port for exact entry", "127.0.0.1:9000", http.StatusMisdirectedRequest},
+		{"ipv6 loopback", "[::1]:8080", http.StatusOK},
+		{"rebinding domain", "attacker.example.com", http.StatusMisdirectedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d for host %q, got %d", tt.wantStatus, tt.host, rec.Code)
+			}
+		})
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	if _, err := NewHTTP(WithTrustedProxies("10.0.0.0/33")); err == nil {
+		t.Error("Expected error for invalid trusted proxy")
+	}
+
+	transport, err := NewHTTP(WithTrustedProxies("10.0.0.0/8", "192.0.2.1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got *http.Request
+	handler := transport.forwardedMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantIP     string
+		wantScheme string
+		wantHost   string
+	}{
+		{"untrusted peer", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "mcp.example.com"}, "203.0.113.7", "", "example.com"},
+		{"trusted peer", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "mcp.example.com"}, "198.51.100.1", "https", "mcp.example.com"},
+		{"chain of proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.0.2.1, 10.0.0.5"}, "198.51.100.1", "", "example.com"},
+		{"spoofed hop", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"}, "198.51.100.1", "", "example.com"},
+		{"malformed hop", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "not-an-ip"}, "10.1.2.3", "", "example.com"},
+		{"invalid proto and host", "10.1.2.3:1234", map[string]string{"X-Forwarded-Proto": "gopher", "X-Forwarded-Host": "bad host"}, "10.1.2.3", "", "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if ip := clientIP(got); ip != tt.wantIP {
+				t.Errorf("Expected client IP %s, got %s", tt.wantIP, ip)
+			}
+			if got.URL.Scheme != tt.wantScheme || got.Host != tt.wantHost {
+				t.Errorf("Expected scheme %q and host %q, got %q and %q", tt.wantScheme, tt.wantHost, got.URL.Scheme, got.Host)
+			}
+		})
+	}
+}
+
+func TestNewHTTPOptions(t *testing.T) {
+	transport, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.port != DefaultHTTPPort {
+		t.Errorf("Expected default port %d, got %d", DefaultHTTPPort, transport.port)
+	}
+	if transport.requestTimeout != DefaultHTTPRequestTimeout {
+		t.Errorf("Expected default request timeout %v, got %v", DefaultHTTPRequestTimeout, transport.requestTimeout)
+	}
+
+	transport, err = NewHTTP(WithPort(3000), WithRequestTimeout(time.Minute), WithIdleTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.port != 3000 {
+		t.Errorf("Expected port 3000, got %d", transport.port)
+	}
+	if transport.requestTimeout != time.Minute {
+		t.Errorf("Expected request timeout 1m, got %v", transport.requestTimeout)
+	}
+	if transport.idleTimeout != time.Second {
+		t.Errorf("Expected idle timeout 1s, got %v", transport.idleTimeout)
+	}
+
+	tests := []struct {
+		name string
+		opt  HTTPOption
+	}{
+		{"negative port", WithPort(-1)},
+		{"port out of range", WithPort(70000)},
+		{"zero read timeout", WithReadTimeout(0)},
+		{"negative shutdown timeout", WithShutdownTimeout(-time.Second)},
+		{"zero max message size", WithMaxMessageSize(0)},
+		{"ACME without cache dir", WithACME([]string{"example.com"}, "", "")},
+		{"ACME challenge address without port", func(t *HTTPTransport) {
+			WithACME([]string{"example.com"}, "acme-cache", "")(t)
+			WithACMEChallengeAddr("example.com")(t)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTP(tt.opt); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	transport, err := NewHTTP(WithResponseHeaders(map[string]string{
+		"Strict-Transport-Security": "max-age=63072000",
+		"X-Frame-Options":           "SAMEORIGIN",
+		"X-XSS-Protection":          "",
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	handler := transport.securityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	expected := map[string]string{
+		"Strict-Transport-Security": "max-age=63072000",
+		"X-Frame-Options":           "SAMEORIGIN",
+		"X-Content-Type-Options":    "nosniff",
+		"X-XSS-Protection":          "",
+	}
+	for name, value := range expected {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("Expected header %s to be %q, got %q", name, value, got)
+		}
+	}
+
+	if _, err := NewHTTP(WithResponseHeaders(map[string]string{"Bad Header": "x"})); err == nil {
+		t.Error("Expected error for invalid header name")
+	}
+}
+
+func TestValidateContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantErr     bool
+	}{
+		{"plain json", "application/json", false},
+		{"utf-8 charset", "application/json; charset=utf-8", false},
+		{"charset is case insensitive", "Application/JSON; charset=UTF-8", false},
+		{"utf8 alias", "application/json;charset=utf8", false},
+		{"missing", "", true},
+		{"form encoded", "application/x-www-form-urlencoded", true},
+		{"text plain", "text/plain; charset=utf-8", true},
+		{"latin-1 charset", "application/json; charset=iso-8859-1", true},
+		{"utf-16 charset", "application/json; charset=utf-16", true},
+		{"malformed parameters", "application/json; charset", true},
+		{"malformed media type", "application/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContentType(tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateContentType(%q) error = %v, wantErr %v", tt.contentType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandlePostRejectsUnsupportedMediaType(t *testing.T) {
+	transport, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	req := httptest.NewRequest(http.MethodPost, "/mcp", body)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	transport.handlePost(context.Background(), nil, rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, rec.Code)
+	}
+}
+
+func TestHandlePostRejectsInvalidUTF8(t *testing.T) {
+	transport, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body := strings.NewReader("{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\",\"params\":{\"x\":\"\xff\xfe\"}}")
+	req := httptest.NewRequest(http.MethodPost, "/mcp", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	transport.handlePost(context.Background(), nil, rec, req)
+
+	if !strings.Contains(rec.Body.String(), ErrInvalidUTF8.Error()) {
+		t.Errorf("Expected invalid UTF-8 parse error, got %s", rec.Body.String())
+	}
+}
+
+func TestHandlePostRejectsOversizedBody(t *testing.T) {
+	transport, err := NewHTTP(WithMaxMessageSize(64))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping","params":{"padding":"` + strings.Repeat("x", 64) + `"}}`)
+	req := httptest.NewRequest(http.MethodPost, "/mcp", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	transport.handlePost(context.Background(), nil, rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+	var resp mcp.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("Expected JSON-RPC invalid request error, got %s", rec.Body.String())
+	}
+}
+
+func TestListenerEndpoints(t *testing.T) {
+	transport, err := NewHTTP(
+		WithListener(Listener{Addr: "127.0.0.1:0", Endpoints: EndpointsMCP}),
+		WithListener(Listener{
+			Addr:      "127.0.0.1:0",
+			Endpoints: EndpointsOps,
+			Middleware: []func(http.Handler) http.Handler{
+				func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Set("X-Listener", "admin")
+						next.ServeHTTP(w, r)
+					})
+				},
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	shared := func(next http.Handler) http.Handler { return next }
+	tests := []struct {
+		name       string
+		listener   Listener
+		path       string
+		wantStatus int
+	}{
+		{"mcp listener serves mcp", transport.listeners[0], "/mcp", http.StatusOK},
+		{"mcp listener hides health", transport.listeners[0], "/health", http.StatusNotFound},
+		{"ops listener serves health", transport.listeners[1], "/health", http.StatusOK},
+		{"ops listener hides mcp", transport.listeners[1], "/mcp", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.listener.handler(transport.newMux(context.Background(), nil, tt.listener.Endpoints), shared)
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			if tt.path == "/health" {
+				req = httptest.NewRequest(http.MethodGet, tt.path, nil)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d for %s, got %d", tt.wantStatus, tt.path, rec.Code)
+			}
+			if tt.listener.Endpoints == EndpointsOps && rec.Header().Get("X-Listener") != "admin" {
+				t.Error("Expected listener middleware to run")
+			}
+		})
+	}
+
+	if _, err := NewHTTP(WithListener(Listener{Addr: "8080"})); err == nil {
+		t.Error("Expected error for listen address without port")
+	}
+}
+
+func TestNewHTTPWithListener(t *testing.T) {
+	if _, err := NewHTTPWithListener(nil); err == nil {
+		t.Error("Expected error for nil listener")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	transport, err := NewHTTPWithListener(l, WithPort(1))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	// The transport serves on the given listener, not on the configured port
+	url := "http://" + l.Addr().String() + "/health"
+	var resp *http.Response
+	for range 50 {
+		if resp, err = http.Get(url); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to reach the transport on %s: %v", l.Addr(), err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+
+	// Stopping the transport closes the listener
+	if _, err := l.Accept(); err == nil {
+		t.Error("Expected listener to be closed")
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	transport, err := NewHTTP(WithBasePath("/api/ai/"), WithStatusPage(false))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := transport.stripBasePath(transport.corsMiddleware(transport.newMux(context.Background(), nil, EndpointsAll)))
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/api/ai/health", http.StatusOK},
+		{http.MethodOptions, "/api/ai/mcp", http.StatusOK},
+		{http.MethodGet, "/health", http.StatusNotFound},
+		{http.MethodOptions, "/mcp", http.StatusNotFound},
+		{http.MethodGet, "/api/aihealth", http.StatusNotFound},
+		// The status page is disabled
+		{http.MethodGet, "/api/ai/", http.StatusNotFound},
+		{http.MethodGet, "/api/ai", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("Expected status %d for %s %s, got %d", tt.wantStatus, tt.method, tt.path, rec.Code)
+		}
+	}
+
+	// CORS rules apply to the paths relative to the base path
+	req := httptest.NewRequest(http.MethodOptions, "/api/ai/mcp", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPost) {
+		t.Errorf("Expected the /mcp CORS rule to apply, got allowed methods %q", got)
+	}
+
+	// The status page is served at the base path unless disabled
+	transport, err = NewHTTP(WithBasePath("/api/ai"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler = transport.stripBasePath(transport.newMux(context.Background(), nil, EndpointsAll))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ai", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/ai/mcp") {
+		t.Errorf("Expected status page listing /api/ai/mcp, got %d", rec.Code)
+	}
+
+	for _, path := range []string{"api", "/api//ai", "/api/../ai"} {
+		if _, err := NewHTTP(WithBasePath(path)); err == nil {
+			t.Errorf("Expected error for base path %q", path)
+		}
+	}
+}
+
+// failingListener fails to accept connections, e.g. like a listener whose
+// socket was closed underneath the server.
+type failingListener struct {
+	net.Listener
+}
+
+func (l failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("accept failed")
+}
+
+func TestServeFailure(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	transport, err := NewHTTPWithListener(failingListener{l})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(context.Background(), srv) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "accept failed") {
+			t.Errorf("Expected the serve error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Start to return when the server fails")
+	}
+}
+
+// gatedToolHandler blocks in CallTool until released.
+type gatedToolHandler struct {
+	*handlers.TeaHandler
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *gatedToolHandler) CallTool(ctx context.Context, params mcp.ToolCallParams) (mcp.ToolResponse, error) {
+	close(h.started)
+	select {
+	case <-h.release:
+		return h.TeaHandler.CallTool(ctx, params)
+	case <-ctx.Done():
+		return mcp.ToolResponse{}, ctx.Err()
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	transport, err := NewHTTPWithListener(l, WithSSEKeepAlive(0))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := &gatedToolHandler{TeaHandler: &handlers.TeaHandler{}, started: make(chan struct{}), release: make(chan struct{})}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	url := "http://" + l.Addr().String() + "/mcp"
+	do := func(method, sessionID, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if method == http.MethodGet {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		if sessionID != "" {
+			req.Header.Set(headerMCPSessionID, sessionID)
+		}
+		var err error
+		for range 50 {
+			var resp *http.Response
+			if resp, err = http.DefaultClient.Do(req); err == nil {
+				return resp
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Failed to reach the transport: %v", err)
+		return nil
+	}
+
+	resp := do(http.MethodPost, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	_ = resp.Body.Close()
+	sessionID := resp.Header.Get(headerMCPSessionID)
+	_ = do(http.MethodPost, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`).Body.Close()
+
+	stream := do(http.MethodGet, sessionID, "")
+	defer stream.Body.Close()
+
+	called := make(chan *http.Response, 1)
+	go func() {
+		called <- do(http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"getTeaNames","arguments":{}}}`)
+	}()
+	<-handler.started
+
+	// Shutting down tells stream clients and refuses new requests while the call is in flight
+	cancel()
+	events := bufio.NewScanner(stream.Body)
+	for events.Scan() && events.Text() != "event: shutdown" {
+	}
+	if events.Err() != nil || events.Text() != "event: shutdown" {
+		t.Fatalf("Expected shutdown event on the event stream, got %v", events.Err())
+	}
+	rec := httptest.NewRecorder()
+	transport.newMux(ctx, srv, EndpointsAll).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", rec.Code)
+	}
+
+	close(handler.release)
+	resp = <-called
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"result"`) {
+		t.Errorf("Expected the in-flight call to complete, got %d %s", resp.StatusCode, body)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+}
+
+func TestAdminPort(t *testing.T) {
+	transport, err := NewHTTP(WithPort(8080), WithAdminPort(9090))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	listeners := transport.effectiveListeners()
+	if len(listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(listeners))
+	}
+	if listeners[0].Addr != "127.0.0.1:8080" || listeners[0].Endpoints != EndpointsMCP {
+		t.Errorf("Expected MCP-only listener on 127.0.0.1:8080, got %+v", listeners[0])
+	}
+	if listeners[1].Addr != "127.0.0.1:9090" || listeners[1].Endpoints != EndpointsOps {
+		t.Errorf("Expected ops listener on 127.0.0.1:9090, got %+v", listeners[1])
+	}
+
+	rec := httptest.NewRecorder()
+	transport.newMux(context.Background(), nil, EndpointsOps).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected pprof on admin listener, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	transport.newMux(context.Background(), nil, EndpointsAll).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no pprof on shared listener, got status %d", rec.Code)
+	}
+
+	if _, err := NewHTTP(WithPort(8080), WithAdminPort(8080)); err == nil {
+		t.Error("Expected error for admin port equal to port")
+	}
+}
+
+func TestBindAddr(t *testing.T) {
+	tests := []struct {
+		bind     string
+		expected string
+	}{
+		{"", ":8080"},
+		{"0.0.0.0", "0.0.0.0:8080"},
+		{"::1", "[::1]:8080"},
+		{"localhost", "localhost:8080"},
+	}
+	for _, tt := range tests {
+		transport, err := NewHTTP(WithBindAddr(tt.bind))
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", tt.bind, err)
+		}
+		if addr := transport.effectiveListeners()[0].Addr; addr != tt.expected {
+			t.Errorf("Expected listener on %s for bind address %q, got %s", tt.expected, tt.bind, addr)
+		}
+	}
+
+	if _, err := NewHTTP(WithBindAddr("127.0.0.1:8080")); err == nil {
+		t.Error("Expected error for bind address with port")
+	}
+}
+
+func TestACMEChallenge(t *testing.T) {
+	cacheDir := t.TempDir()
+	transport, err := NewHTTP(WithACME([]string{"mcp.example.com"}, cacheDir, ""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.acmeChallengeAddr != DefaultACMEChallengeAddr {
+		t.Errorf("Expected challenge address %q, got %q", DefaultACMEChallengeAddr, transport.acmeChallengeAddr)
+	}
+
+	manager := transport.acmeManager()
+	if manager == nil {
+		t.Fatal("Expected an ACME manager")
+	}
+	// The manager looks up tokens of pending challenges in its cache
+	if err := autocert.DirCache(cacheDir).Put(context.Background(), "token+http-01", []byte("token.key-authorization")); err != nil {
+		t.Fatalf("Failed to store challenge token: %v", err)
+	}
+
+	ts := httptest.NewServer(manager.HTTPHandler(nil))
+	defer ts.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	get := func(host, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Host = host
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := get("mcp.example.com", "/.well-known/acme-challenge/token")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "token.key-authorization" {
+		t.Errorf("Expected the key authorization, got %d %q", resp.StatusCode, body)
+	}
+
+	if resp := get("other.example.com", "/.well-known/acme-challenge/token"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a domain not in the list, got %d", resp.StatusCode)
+	}
+	if resp := get("mcp.example.com", "/.well-known/acme-challenge/unknown"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown token, got %d", resp.StatusCode)
+	}
+
+	resp = get("mcp.example.com", "/mcp")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "https://mcp.example.com/mcp" {
+		t.Errorf("Expected a redirect to HTTPS, got %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if transport, err := NewHTTP(); err != nil || transport.acmeManager() != nil {
+		t.Errorf("Expected no ACME manager without domains, got error %v", err)
+	}
+
+	// Start binds the challenge listener with the others
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = taken.Close() }()
+	transport, err = NewHTTP(WithPort(0), WithACME([]string{"mcp.example.com"}, cacheDir, ""), WithACMEChallengeAddr(taken.Addr().String()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := transport.Start(context.Background(), srv); !errors.Is(err, ErrListen) {
+		t.Errorf("Expected ErrListen for a taken challenge address, got %v", err)
+	}
+}
+
+func TestAdminLogging(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithAdminPort(9090), WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mux := authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsOps))
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, `"sampleEvery":0`},
+		{"put", http.MethodPut, `{"sampleEvery":10,"methodLevels":{"tools/call":"debug"}}`, http.StatusOK, `"tools/call":"DEBUG"`},
+		{"invalid level", http.MethodPut, `{"methodLevels":{"tools/call":"loud"}}`, http.StatusBadRequest, "invalid log level"},
+		{"invalid JSON", http.MethodPut, `{`, http.StatusBadRequest, "Invalid log policy"},
+		{"method not allowed", http.MethodPost, "", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, "/admin/logging", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("Expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	if policy := srv.LogPolicy(); policy.SampleEvery != 10 {
+		t.Errorf("Expected invalid updates to keep the previous policy, got %+v", policy)
+	}
+
+	rec := httptest.NewRecorder()
+	authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsAll)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/logging", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no admin endpoint on shared listener, got status %d", rec.Code)
+	}
+}
+
+func TestAdminSettings(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler, server.WithLogPolicy(server.LogPolicy{SampleEvery: 10}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mux := authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsOps))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/settings", strings.NewReader(`{"disabledTools":["getTeaNames"]}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Fields missing from the body keep their value
+	settings := srv.Settings()
+	if settings.LogPolicy.SampleEvery != 10 || len(settings.DisabledTools) != 1 {
+		t.Errorf("Expected partial update, got %+v", settings)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/settings", strings.NewReader(`{"logPolicy":{"sampleEvery":-1}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid settings, got %d", rec.Code)
+	}
+}
+
+const testAdminToken = "test-admin-token-0123456789"
+
+// authorizeAdmin adds the test admin token to requests.
+func authorizeAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+testAdminToken)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestAdminToken(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := NewHTTP(WithAdminToken("short")); err == nil {
+		t.Error("Expected error for short admin token, got nil")
+	}
+
+	request := func(mux http.Handler, path, auth string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	adminPaths := []string{"/admin/settings", "/admin/logging", "/admin/sessions", "/admin/cors", "/admin/transcripts/"}
+
+	// Without a token, no admin endpoint is served at all
+	open, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mux := open.newMux(context.Background(), srv, EndpointsOps)
+	for _, path := range adminPaths {
+		if rec := request(mux, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 without admin token, got %d", path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	open.adminAuth(func(w http.ResponseWriter, r *http.Request) {})(rec, httptest.NewRequest(http.MethodGet, "/admin/logging", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the admin check to fail closed without a token, got %d", rec.Code)
+	}
+
+	transport, err := NewHTTP(WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mux = transport.newMux(context.Background(), srv, EndpointsOps)
+	for _, path := range adminPaths {
+		rec := request(mux, path, "")
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected status 401 without token, got %d", path, rec.Code)
+		}
+		if rec := request(mux, path, "Basic "+testAdminToken); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected status 401 for other schemes, got %d", path, rec.Code)
+		}
+		if rec := request(mux, path, "Bearer wrong-admin-token-0123456789"); rec.Code != http.StatusForbidden {
+			t.Errorf("%s: expected status 403 for wrong token, got %d", path, rec.Code)
+		}
+		if rec := request(mux, path, "Bearer "+testAdminToken); rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden {
+			t.Errorf("%s: expected valid token to be accepted, got %d", path, rec.Code)
+		}
+	}
+
+	// Health checks stay unauthenticated
+	if rec := request(mux, "/health", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected health without token, got %d", rec.Code)
+	}
+}
+
+func TestAdminTranscripts(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler, server.WithTranscripts(server.Transcripts{}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	mux := authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsOps))
+
+	ctx := context.WithValue(context.Background(), mcp.SessionIDKey, "session-1")
+	if err := srv.HandleNotification(ctx, mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: mcp.NotificationInitialized}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/admin/transcripts/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"session-1"`) {
+		t.Errorf("Expected session list, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/admin/transcripts/session-1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "notifications/initialized") {
+		t.Errorf("Expected JSON transcript, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := get("/admin/transcripts/session-1?format=markdown")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "# Transcript of session session-1") {
+		t.Errorf("Expected Markdown transcript, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="transcript-session-1.md"` {
+		t.Errorf("Expected Markdown attachment, got %q", disposition)
+	}
+	if rec := get("/admin/transcripts/unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown session, got %d", rec.Code)
+	}
+}
+
+func TestSessionAdmission(t *testing.T) {
+	if _, err := NewHTTP(WithSessionAdmission(SessionAdmission{MaxSessions: -1})); err == nil {
+		t.Error("Expected error for negative session limit")
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		transport, err := NewHTTP(WithSessionAdmission(SessionAdmission{MaxSessions: 2, MaxSessionsPerIP: 1, RetryAfter: 10 * time.Second}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		a := transport.admission
+		noop := func() {}
+
+		if a.admit(httptest.NewRecorder(), "10.0.0.1", noop) == nil {
+			t.Fatal("Expected first session to be admitted")
+		}
+
+		rec := httptest.NewRecorder()
+		if a.admit(rec, "10.0.0.1", noop) != nil {
+			t.Fatal("Expected second session from the same IP to be rejected")
+		}
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+			t.Errorf("Expected 429 with Retry-After 10, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+
+		second := a.admit(httptest.NewRecorder(), "10.0.0.2", noop)
+		if second == nil {
+			t.Fatal("Expected session from another IP to be admitted")
+		}
+
+		rec = httptest.NewRecorder()
+		if a.admit(rec, "10.0.0.3", noop) != nil || rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 once the total limit is reached, got %d", rec.Code)
+		}
+
+		a.release(second)
+		if a.admit(httptest.NewRecorder(), "10.0.0.3", noop) == nil {
+			t.Error("Expected session to be admitted after another one was released")
+		}
+	})
+
+	t.Run("evict idle", func(t *testing.T) {
+		transport, err := NewHTTP(WithSessionAdmission(SessionAdmission{MaxSessions: 2, OnLimit: AdmissionEvictIdle}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		a := transport.admission
+
+		evicted := make(map[string]bool)
+		admit := func(id string) {
+			stream := a.admit(httptest.NewRecorder(), "10.0.0.1", func() { evicted[id] = true })
+			if stream == nil {
+				t.Fatalf("Expected session %s to be admitted", id)
+			}
+			a.bind(stream, id)
+		}
+
+		admit("a")
+		admit("b")
+		// Activity on "a" makes "b" the least recently active session
+		time.Sleep(time.Millisecond)
+		a.touch("a")
+		admit("c")
+
+		if !evicted["b"] || evicted["a"] || len(a.streams) != 2 {
+			t.Errorf("Expected only the idle session b to be evicted, got %v with %d sessions", evicted, len(a.streams))
+		}
+	})
+}
+
+func TestLongPoll(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithLongPollTimeout(20 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+	session, err := transport.createSession(srv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	poll := func(target, accept string) (*httptest.ResponseRecorder, pollResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(headerMCPSessionID, session.ID)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var resp pollResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode poll response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	if _, resp := poll("/mcp/poll", "*/*"); len(resp.Messages) != 0 {
+		t.Fatalf("Expected empty poll before any notification, got %+v", resp)
+	}
+
+	if err := srv.NotifyToolsListChanged(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// GET /mcp without text/event-stream selects long-polling
+	rec, resp := poll("/mcp", contentTypeJSON)
+	if rec.Header().Get(headerMCPSessionID) != session.ID {
+		t.Errorf("Expected session header to be echoed, got %q", rec.Header().Get(headerMCPSessionID))
+	}
+	if len(resp.Messages) != 1 || resp.Cursor != 1 {
+		t.Fatalf("Expected one queued notification with cursor 1, got %+v", resp)
+	}
+	if !strings.Contains(rec.Body.String(), "notifications/tools/list_changed") {
+		t.Errorf("Expected tools list_changed notification, got %s", rec.Body.String())
+	}
+
+	if _, resp := poll("/mcp/poll?cursor=0", "*/*"); len(resp.Messages) != 1 {
+		t.Errorf("Expected unacknowledged message to be returned again, got %+v", resp)
+	}
+	if _, resp := poll("/mcp/poll?cursor=1", "*/*"); len(resp.Messages) != 0 || resp.Cursor != 1 {
+		t.Errorf("Expected acknowledged message to be dropped, got %+v", resp)
+	}
+
+	if rec, _ := poll("/mcp/poll?cursor=abc", "*/*"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid cursor, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	if _, err := NewHTTP(WithLongPollTimeout(0)); err == nil {
+		t.Error("Expected error for zero long-poll timeout")
+	}
+}
+
+func TestBrowserTokens(t *testing.T) {
+	if _, err := NewHTTP(WithBrowserTokens(BrowserTokens{})); err == nil {
+		t.Error("Expected error for browser tokens without origins")
+	}
+
+	const origin = "https://playground.example.com"
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithBrowserTokens(BrowserTokens{Origins: []string{origin}}), WithLongPollTimeout(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+
+	handshake := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/token", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := handshake("https://evil.example.com"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for disallowed origin, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	rec := handshake(origin)
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+		t.Fatalf("Expected token in handshake response, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	expired := transport.browserTokens.issue(origin, time.Now().Add(-time.Second))
+	session, err := transport.createSession(srv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tests := []struct {
+		name       string
+		origin     string
+		target     string
+		auth       string
+		wantStatus int
+	}{
+		{"non-browser client", "", "/mcp/poll", "", http.StatusOK},
+		{"missing token", origin, "/mcp/poll", "", http.StatusUnauthorized},
+		{"bearer token", origin, "/mcp/poll", "Bearer " + resp.Token, http.StatusOK},
+		{"query token", origin, "/mcp/poll?token=" + resp.Token, "", http.StatusOK},
+		{"other origin", "https://evil.example.com", "/mcp/poll", "Bearer " + resp.Token, http.StatusUnauthorized},
+		{"tampered token", origin, "/mcp/poll", "Bearer " + resp.Token + "x", http.StatusUnauthorized},
+		{"expired token", origin, "/mcp/poll", "Bearer " + expired, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set(headerMCPSessionID, session.ID)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+
+	do := func(method, sessionID, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if method != http.MethodPost {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		if sessionID != "" {
+			req.Header.Set(headerMCPSessionID, sessionID)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	const ping = `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+
+	rec := do(http.MethodPost, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+	sessionID := rec.Header().Get(headerMCPSessionID)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(sessionID, sessionIDPrefix) {
+		t.Fatalf("Expected initialize to issue a session, got %d with session %q", rec.Code, sessionID)
+	}
+
+	if rec := do(http.MethodPost, "", ping); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without session, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "session_unknown", ping); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown session, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, sessionID, ping); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 within the session, got %d", rec.Code)
+	}
+
+	// Deleting the session ends its event stream
+	streamDone := make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		do(http.MethodGet, sessionID, "")
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		transport.mu.RLock()
+		open := len(transport.mcpSessions[sessionID].streams)
+		transport.mu.RUnlock()
+		if open == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Event stream was not opened")
+		}
+	}
+
+	if rec := do(http.MethodDelete, sessionID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 for DELETE, got %d", rec.Code)
+	}
+	select {
+	case <-streamDone:
+	case <-time.After(time.Second):
+		t.Fatal("Expected DELETE to close the event stream")
+	}
+
+	if rec := do(http.MethodPost, sessionID, ping); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after DELETE, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, sessionID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for deleting twice, got %d", rec.Code)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithSessionIdleTimeout(time.Minute), WithSessionLimit(2), WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+
+	create := func() *httpSession {
+		t.Helper()
+		session, err := transport.createSession(srv)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return session
+	}
+	openStream := func(session *httpSession) {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		session.streams = append(session.streams, sessionStream{sse: &SSESession{closed: true}, end: func() {}})
+	}
+	exists := func(session *httpSession) bool {
+		transport.mu.RLock()
+		defer transport.mu.RUnlock()
+		return transport.mcpSessions[session.ID] == session
+	}
+
+	// At the limit, the least recently active session makes room
+	oldest, streaming := create(), create()
+	oldest.lastActive.Store(time.Now().Add(-time.Second).UnixNano())
+	openStream(streaming)
+	idle := create()
+	if exists(oldest) || !exists(streaming) || !exists(idle) {
+		t.Error("Expected the least recently active session to be evicted")
+	}
+
+	// Sessions with an open event stream neither expire nor are evicted
+	transport.expireIdleSessions(srv, time.Now().Add(2*time.Minute))
+	if exists(idle) || !exists(streaming) {
+		t.Error("Expected only the idle session to expire")
+	}
+	openStream(create())
+	if _, err := transport.createSession(srv); !errors.Is(err, ErrSessionLimit) {
+		t.Errorf("Expected ErrSessionLimit, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsOps)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil))
+	var stats SessionStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode session stats %q: %v", rec.Body.String(), err)
+	}
+	want := SessionStats{Active: 2, Streaming: 2, Expired: 1, Evicted: 1}
+	if stats != want {
+		t.Errorf("Expected session stats %+v, got %+v", want, stats)
+	}
+
+	if _, err := NewHTTP(WithSessionIdleTimeout(-time.Second)); err == nil {
+		t.Error("Expected error for negative session idle timeout")
+	}
+}
+
+func TestSessionLimitConcurrentCreates(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	const limit = 4
+	transport, err := NewHTTP(WithSessionLimit(limit))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+
+	for range limit {
+		if _, err := transport.createSession(srv); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	// Creates at the limit race to evict, but never exceed it
+	const creates = 200
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range creates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := transport.createSession(srv); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			transport.mu.RLock()
+			defer transport.mu.RUnlock()
+			if n := len(transport.mcpSessions); n > limit {
+				t.Errorf("Expected at most %d sessions, got %d", limit, n)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	transport.mu.RLock()
+	defer transport.mu.RUnlock()
+	if n := len(transport.mcpSessions); n != limit {
+		t.Errorf("Expected %d sessions, got %d", limit, n)
+	}
+	if evicted := transport.sessionsEvicted.Load(); evicted != creates {
+		t.Errorf("Expected %d evictions, got %d", creates, evicted)
+	}
+}
+
+func TestEventStoreReplay(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	sessionID := rec.Header().Get(headerMCPSessionID)
+
+	// The response to a request is sent on its own stream, after the connected event
+	req = httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":"ping-1","method":"ping"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(headerMCPSessionID, sessionID)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "id: 1\nevent: connected\n") || !strings.Contains(rec.Body.String(), "id: 2\ndata: ") {
+		t.Fatalf("Expected connected event 1 and response event 2, got %q", rec.Body.String())
+	}
+
+	resume := func(lastEventID string) string {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/mcp", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set(headerMCPSessionID, sessionID)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		rec := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			mux.ServeHTTP(rec, req)
+		}()
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			transport.mu.RLock()
+			open := len(transport.mcpSessions[sessionID].streams)
+			transport.mu.RUnlock()
+			if open == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Event stream was not opened")
+			}
+		}
+		cancel()
+		<-done
+		return rec.Body.String()
+	}
+
+	// Reconnecting after the connected event replays the missed response
+	if body := resume("1"); !strings.HasPrefix(body, "id: 2\ndata: ") || !strings.Contains(body, `"id":"ping-1"`) {
+		t.Errorf("Expected missed response to be replayed, got %q", body)
+	}
+	if body := resume("999"); strings.Contains(body, "ping-1") {
+		t.Errorf("Expected no replay for unknown event ID, got %q", body)
+	}
+
+	store := NewMemoryEventStore(2)
+	for range 3 {
+		if _, err := store.StoreEvent("session", "stream", Event{Data: []byte("{}")}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if _, _, err := store.EventsAfter("session", "1"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound for discarded event, got %v", err)
+	}
+	if stream, events, err := store.EventsAfter("session", "2"); err != nil || stream != "stream" || len(events) != 1 || events[0].ID != "3" {
+		t.Errorf("Expected event 3 on stream, got %q %+v %v", stream, events, err)
+	}
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	if _, err := NewHTTP(WithSSEKeepAlive(-time.Second)); err == nil {
+		t.Error("Expected error for negative keepalive interval")
+	}
+
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithSSEKeepAlive(10 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+	session, err := transport.createSession(srv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(headerMCPSessionID, session.ID)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "\n\n: ping\n\n") {
+		t.Errorf("Expected keepalive comments on the idle stream, got %q", rec.Body.String())
+	}
+}
+
+func TestStandaloneStream(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewHTTP(WithSSEKeepAlive(0))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer transport.Stop()
+	mux := transport.newMux(context.Background(), srv, EndpointsAll)
+	session, err := transport.createSession(srv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	open := func(streams int) (*httptest.ResponseRecorder, func()) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/mcp", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set(headerMCPSessionID, session.ID)
+		rec := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			mux.ServeHTTP(rec, req)
+		}()
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			transport.mu.RLock()
+			n := len(session.streams)
+			transport.mu.RUnlock()
+			if n == streams {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Event stream was not opened")
+			}
+		}
+		return rec, func() {
+			cancel()
+			<-done
+		}
+	}
+	notify := func(text string) error {
+		return srv.NotifySession(session.ID, mcp.NotificationMessage, map[string]any{"level": "info", "data": text})
+	}
+
+	older, closeOlder := open(1)
+	newer, closeNewer := open(2)
+
+	// Server-initiated messages go to the newest stream
+	if err := notify("first"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	closeNewer()
+	if err := notify("second"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	closeOlder()
+
+	if body := newer.Body.String(); !strings.Contains(body, `"first"`) || strings.Contains(body, `"second"`) {
+		t.Errorf("Expected only the first message on the newer stream, got %q", body)
+	}
+	if body := older.Body.String(); !strings.Contains(body, `"second"`) || strings.Contains(body, `"first"`) {
+		t.Errorf("Expected the second message on the remaining stream, got %q", body)
+	}
+
+	if err := notify("third"); !errors.Is(err, mcp.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound without open streams, got %v", err)
+	}
+}
+
+func TestOriginValidation(t *testing.T) {
+	if _, err := NewHTTP(WithAllowedOrigins("app.example.com")); err == nil {
+		t.Error("Expected error for origin without scheme")
+	}
+
+	tests := []struct {
+		name       string
+		opts       []HTTPOption
+		path       string
+		origin     string
+		wantStatus int
+	}{
+		{"no origin", nil, "/mcp", "", http.StatusOK},
+		{"localhost", nil, "/mcp", "http://localhost:6274", http.StatusOK},
+		{"loopback IPv4", nil, "/mcp", "http://127.0.0.1:3000", http.StatusOK},
+		{"loopback IPv6", nil, "/mcp", "http://[::1]:3000", http.StatusOK},
+		{"rebound domain", nil, "/mcp", "http://attacker.example:8080", http.StatusForbidden},
+		{"rebound domain on poll", nil, "/mcp/poll", "http://attacker.example:8080", http.StatusForbidden},
+		{"other endpoints", nil, "/health", "http://attacker.example:8080", http.StatusOK},
+		{"listed", []HTTPOption{WithAllowedOrigins("https://app.example.com")}, "/mcp", "https://app.example.com", http.StatusOK},
+		{"listed replaces loopback", []HTTPOption{WithAllowedOrigins("https://app.example.com")}, "/mcp", "http://localhost:6274", http.StatusForbidden},
+		{"any", []HTTPOption{WithAllowedOrigins("*")}, "/mcp", "http://attacker.example:8080", http.StatusOK},
+		{"browser token origin", []HTTPOption{WithBrowserTokens(BrowserTokens{Origins: []string{"https://playground.example.com"}})}, "/mcp", "https://playground.example.com", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewHTTP(tt.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+
+			transport.originValidationMiddleware(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCORSRules(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := NewHTTP(WithCORSRules(CORSRule{PathPrefix: "/mcp", AllowedOrigins: []string{"app.example.com"}})); err == nil {
+		t.Error("Expected error for origin without scheme")
+	}
+
+	rules := DefaultCORSRules()
+	rules[1].AllowedOrigins = []string{"https://app.example.com"}
+	transport, err := NewHTTP(WithCORSRules(rules...), WithAdminToken(testAdminToken))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handlerChain := transport.corsMiddleware(authorizeAdmin(transport.newMux(context.Background(), srv, EndpointsOps)))
+
+	request := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		handlerChain.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The MCP endpoint admits its configured origin only
+	rec := request(http.MethodOptions, "/mcp", "https://app.example.com")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || rec.Header().Get("Access-Control-Max-Age") != "86400" {
+		t.Errorf("Expected cacheable preflight for allowed origin, got %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPost) || rec.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected POST to be allowed per origin, got %v", rec.Header())
+	}
+	if rec := request(http.MethodOptions, "/mcp", "https://evil.example.com"); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected preflight of other origin to be refused, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Health checks stay open to any origin, admin endpoints to none
+	if rec := request(http.MethodGet, "/health", "https://evil.example.com"); rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected health to allow any origin, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := request(http.MethodOptions, "/admin/settings", "https://app.example.com"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected admin preflight to be refused, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/admin/settings", "https://app.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers on admin endpoints, got %v", rec.Header())
+	}
+
+	rec = request(http.MethodGet, "/admin/cors", "")
+	var stats map[string]CORSStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("Expected JSON stats, got %v", err)
+	}
+	expected := map[string]CORSStats{
+		"/":       {Requests: 1},
+		"/mcp":    {Preflights: 1, Denied: 1},
+		"/admin/": {Denied: 2},
+		"/debug/": {},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	// Credentials are allowed for listed origins only
+	if _, err := NewHTTP(WithCORSRules(CORSRule{PathPrefix: "/mcp", AllowedOrigins: []string{"*"}, AllowCredentials: true})); err == nil {
+		t.Error("Expected error for credentials with any origin")
+	}
+	if _, err := NewHTTP(WithCORSRules(CORSRule{PathPrefix: "/mcp", AllowedMethods: []string{"GET POST"}})); err == nil {
+		t.Error("Expected error for invalid method")
+	}
+	transport, err = NewHTTP(WithCORSRules(CORSRule{
+		PathPrefix:       "/mcp",
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodPost},
+		AllowCredentials: true,
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handlerChain = transport.corsMiddleware(transport.newMux(context.Background(), srv, EndpointsAll))
+	rec = request(http.MethodOptions, "/mcp", "https://app.example.com")
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Allow-Methods") != http.MethodPost {
+		t.Errorf("Expected credentials and POST only to be allowed, got %v", rec.Header())
+	}
+}
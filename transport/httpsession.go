@@ -0,0 +1,337 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// httpSession is a client session of the Streamable HTTP transport.
+//
+// A session is issued with the response to initialize, in the Mcp-Session-Id
+// header, and lasts until the client deletes it with DELETE /mcp, it expires
+// after the session idle timeout without requests or open event stream, or
+// the transport stops. Every later request must carry the session's ID.
+//
+// The session is registered with the server while it has an open GET event
+// stream, and delivers server-initiated notifications and requests on the
+// newest of its streams.
+type httpSession struct {
+	ID string
+
+	// mu is HTTPTransport.mu, which guards streams.
+	mu *sync.RWMutex
+
+	// streams are the open GET event streams of the session, oldest first.
+	streams []sessionStream
+
+	// lastActive is the time of the session's last request or closed event
+	// stream, in Unix nanoseconds.
+	lastActive atomic.Int64
+}
+
+func (s *httpSession) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleSince reports whether the session had no activity since t and has no
+// open event stream. The caller must hold HTTPTransport.mu.
+func (s *httpSession) idleSince(t time.Time) bool {
+	return len(s.streams) == 0 && s.lastActive.Load() < t.UnixNano()
+}
+
+// sessionStream is an open GET event stream with the function ending it.
+type sessionStream struct {
+	sse *SSESession
+	end context.CancelFunc
+}
+
+// stream returns the newest open event stream of the session, or nil.
+func (s *httpSession) stream() *SSESession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.streams) == 0 {
+		return nil
+	}
+	return s.streams[len(s.streams)-1].sse
+}
+
+// SendNotification delivers a server-initiated notification on the session's newest event stream.
+func (s *httpSession) SendNotification(notification mcp.Notification) error {
+	stream := s.stream()
+	if stream == nil {
+		return ErrNoEventStream
+	}
+	return stream.SendNotification(notification)
+}
+
+// SendRequest delivers a server-initiated request on the session's newest event stream.
+func (s *httpSession) SendRequest(request mcp.Request) error {
+	stream := s.stream()
+	if stream == nil {
+		return ErrNoEventStream
+	}
+	return stream.SendRequest(request)
+}
+
+// newSessionID returns a cryptographically random session ID, so that
+// sessions cannot be guessed or hijacked by other clients.
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return sessionIDPrefix + hex.EncodeToString(b)
+}
+
+// createSession issues a new session for an initialize request. At the
+// session limit, the least recently active session without an open event
+// stream is evicted to make room, and ErrSessionLimit returned if there is none.
+func (t *HTTPTransport) createSession(srv *server.Server) (*httpSession, error) {
+	session := &httpSession{ID: newSessionID(), mu: &t.mu}
+	session.touch()
+
+	// The victim is removed and the new session added in one critical
+	// section, so concurrent creates cannot exceed the limit
+	t.mu.Lock()
+	var evict *httpSession
+	if t.sessionLimit > 0 && len(t.mcpSessions) >= t.sessionLimit {
+		for _, s := range t.mcpSessions {
+			if len(s.streams) == 0 && (evict == nil || s.lastActive.Load() < evict.lastActive.Load()) {
+				evict = s
+			}
+		}
+		if evict == nil {
+			t.mu.Unlock()
+			return nil, ErrSessionLimit
+		}
+	}
+	var poll *pollSession
+	if evict != nil {
+		_, poll = t.removeSession(evict.ID)
+	}
+	t.mcpSessions[session.ID] = session
+	t.mu.Unlock()
+
+	if evict != nil {
+		t.releaseSession(srv, evict.ID, poll)
+		t.sessionsEvicted.Add(1)
+		log.Printf("Session %s evicted to make room for a new session", evict.ID)
+	}
+	return session, nil
+}
+
+// requireSession returns the session named by the request's Mcp-Session-Id
+// header. Without the header it responds 400 Bad Request, and 404 Not Found
+// for unknown or deleted sessions, telling the client to initialize anew.
+// It returns nil if it responded.
+func (t *HTTPTransport) requireSession(w http.ResponseWriter, r *http.Request) *httpSession {
+	id := r.Header.Get(headerMCPSessionID)
+	if id == "" {
+		http.Error(w, "Missing Mcp-Session-Id header", http.StatusBadRequest)
+		return nil
+	}
+
+	t.mu.RLock()
+	session, ok := t.mcpSessions[id]
+	t.mu.RUnlock()
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil
+	}
+	session.touch()
+	return session
+}
+
+// openStream adds an event stream to the session. It returns false if the
+// session was deleted meanwhile.
+func (t *HTTPTransport) openStream(srv *server.Server, session *httpSession, stream *SSESession, end context.CancelFunc) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.mcpSessions[session.ID] != session {
+		return false
+	}
+	session.streams = append(session.streams, sessionStream{sse: stream, end: end})
+	if len(session.streams) == 1 {
+		// Registered under the transport's lock, so it cannot race with the last stream closing
+		srv.RegisterSession(session.ID, session)
+	}
+	return true
+}
+
+// closeStream removes an event stream from the session. Server-initiated
+// messages move to the session's remaining streams, or to its long-poll
+// session once the last stream is closed.
+func (t *HTTPTransport) closeStream(srv *server.Server, session *httpSession, stream *SSESession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	session.streams = slices.DeleteFunc(session.streams, func(s sessionStream) bool { return s.sse == stream })
+	// The idle timeout starts once the client disconnects
+	session.touch()
+	if len(session.streams) > 0 || t.mcpSessions[session.ID] != session {
+		return
+	}
+	if poll, ok := t.pollSessions[session.ID]; ok {
+		srv.RegisterSession(session.ID, poll)
+	} else {
+		srv.UnregisterSession(session.ID)
+	}
+}
+
+// closeStreams ends all event streams of the session. The caller must hold
+// HTTPTransport.mu.
+func (s *httpSession) closeStreams() {
+	for _, stream := range s.streams {
+		stream.sse.close()
+		stream.end()
+	}
+	s.streams = nil
+}
+
+// handleDelete terminates the session named by the request's Mcp-Session-Id header.
+func (t *HTTPTransport) handleDelete(srv *server.Server, w http.ResponseWriter, r *http.Request) {
+	session := t.requireSession(w, r)
+	if session == nil {
+		return
+	}
+
+	t.deleteSession(srv, session.ID)
+	log.Printf("Session %s terminated by client", session.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSession ends a session: its streams and long-poll session are
+// closed, and further requests carrying its ID are answered with 404.
+func (t *HTTPTransport) deleteSession(srv *server.Server, id string) {
+	t.mu.Lock()
+	ok, poll := t.removeSession(id)
+	t.mu.Unlock()
+
+	if ok {
+		t.releaseSession(srv, id, poll)
+	}
+}
+
+// removeSession removes a session and ends its streams, and returns whether
+// it existed and its long-poll session, if any. The caller must hold
+// HTTPTransport.mu, and call releaseSession once it has released it.
+func (t *HTTPTransport) removeSession(id string) (bool, *pollSession) {
+	session, ok := t.mcpSessions[id]
+	if ok {
+		session.closeStreams()
+	}
+	delete(t.mcpSessions, id)
+	poll := t.pollSessions[id]
+	delete(t.pollSessions, id)
+	return ok, poll
+}
+
+// releaseSession ends a removed session with the server and closes its
+// long-poll session.
+func (t *HTTPTransport) releaseSession(srv *server.Server, id string, poll *pollSession) {
+	srv.EndSession(id)
+	srv.UnregisterSession(id)
+	if t.eventStore != nil {
+		t.eventStore.DeleteSession(id)
+	}
+	if poll != nil {
+		poll.expiry.Stop()
+		poll.close()
+	}
+}
+
+// expireSessions ends idle sessions until ctx is done, see
+// WithSessionIdleTimeout.
+func (t *HTTPTransport) expireSessions(ctx context.Context, srv *server.Server) {
+	ticker := time.NewTicker(t.sessionIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.expireIdleSessions(srv, now)
+		}
+	}
+}
+
+// expireIdleSessions ends the sessions idle for the session idle timeout at now.
+// Clients that crashed or forgot to delete their session never come back,
+// so their sessions would otherwise be kept until the transport stops.
+func (t *HTTPTransport) expireIdleSessions(srv *server.Server, now time.Time) {
+	cutoff := now.Add(-t.sessionIdleTimeout)
+
+	t.mu.RLock()
+	var expired []string
+	for id, session := range t.mcpSessions {
+		if session.idleSince(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	t.mu.RUnlock()
+
+	for _, id := range expired {
+		t.deleteSession(srv, id)
+		log.Printf("Session %s expired after %v without activity", id, t.sessionIdleTimeout)
+	}
+	t.sessionsExpired.Add(uint64(len(expired)))
+}
+
+// SessionStats counts the sessions of the HTTP transport.
+type SessionStats struct {
+	// Active counts the open sessions.
+	Active int `json:"active"`
+
+	// Streaming counts the open sessions with an open event stream.
+	Streaming int `json:"streaming"`
+
+	// Expired counts the sessions ended after the session idle timeout.
+	Expired uint64 `json:"expired"`
+
+	// Evicted counts the sessions ended to make room at the session limit.
+	Evicted uint64 `json:"evicted"`
+}
+
+// SessionStats returns the session counters of the transport.
+func (t *HTTPTransport) SessionStats() SessionStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stats := SessionStats{
+		Active:  len(t.mcpSessions),
+		Expired: t.sessionsExpired.Load(),
+		Evicted: t.sessionsEvicted.Load(),
+	}
+	for _, session := range t.mcpSessions {
+		if len(session.streams) > 0 {
+			stats.Streaming++
+		}
+	}
+	return stats
+}
+
+// handleAdminSessions reports the session counters.
+func handleAdminSessions(w http.ResponseWriter, r *http.Request, t *HTTPTransport) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if err := json.NewEncoder(w).Encode(t.SessionStats()); err != nil {
+		log.Printf("Failed to encode session stats: %v", err)
+	}
+}
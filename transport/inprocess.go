@@ -0,0 +1,333 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+const (
+	DefaultInProcessTimeout = 30 * time.Second
+
+	// inProcessBuffer is the number of messages a connection buffers in each
+	// direction before the sender blocks.
+	inProcessBuffer = 64
+
+	inProcessSessionIDPrefix = "inprocess_"
+)
+
+// InProcess connects clients to a server within the same Go program over
+// channels, without stdio or sockets.
+//
+// It suits embedding the server in another program and fast transport-level
+// tests. Messages are exchanged as JSON, so they are validated and decoded
+// exactly as on the other transports. Every connection is its own session:
+//
+//	t, _ := transport.NewInProcess()
+//	go t.Start(ctx, srv)
+//	conn, _ := t.Connect(ctx)
+//	_ = conn.Send(ctx, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(1), Method: "ping"})
+//	response, _ := conn.Receive(ctx)
+type InProcess struct {
+	requestTimeout time.Duration
+
+	// started is closed once Start has been called, stopped once Stop has.
+	started  chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	mu    sync.Mutex
+	srv   *server.Server
+	ctx   context.Context
+	conns map[*InProcessConn]struct{}
+
+	nextID atomic.Uint64
+	wg     sync.WaitGroup
+}
+
+// NewInProcess creates a new in-process transport configured by the given options.
+//
+// Unset options fall back to defaults, see DefaultInProcessTimeout.
+func NewInProcess(opts ...InProcessOption) (*InProcess, error) {
+	t := &InProcess{
+		requestTimeout: DefaultInProcessTimeout,
+		started:        make(chan struct{}),
+		stopped:        make(chan struct{}),
+		conns:          make(map[*InProcessConn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid in-process transport options: %w", err)
+	}
+
+	return t, nil
+}
+
+// Start accepts connections until ctx is done or Stop is called, then closes
+// all connections and waits for their in-flight requests to finish.
+func (t *InProcess) Start(ctx context.Context, srv *server.Server) error {
+	t.mu.Lock()
+	if t.srv != nil {
+		t.mu.Unlock()
+		return errors.New("in-process transport already started")
+	}
+	t.srv = srv
+	t.ctx = ctx
+	t.mu.Unlock()
+	close(t.started)
+
+	log.Println("Starting in-process transport...")
+
+	if err := srv.AnnounceReady(ctx, "inprocess", 0); err != nil {
+		log.Printf("Failed to announce readiness: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = t.Stop()
+	case <-t.stopped:
+	}
+
+	log.Println("In-process transport shutting down")
+	t.wg.Wait()
+	return nil
+}
+
+// Stop closes all connections and rejects new ones.
+func (t *InProcess) Stop() error {
+	t.mu.Lock()
+	// Closed under the lock, so Connect cannot track a connection Stop misses
+	t.stopOnce.Do(func() { close(t.stopped) })
+	conns := make([]*InProcessConn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.Close()
+	}
+	return nil
+}
+
+// Connect opens a new client connection, which is a session of its own. It
+// waits for Start to be called, and fails with ErrTransportClosed once the
+// transport has stopped.
+func (t *InProcess) Connect(ctx context.Context) (*InProcessConn, error) {
+	select {
+	case <-t.started:
+	case <-t.stopped:
+		return nil, ErrTransportClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c := &InProcessConn{
+		sessionID: fmt.Sprintf("%s%d", inProcessSessionIDPrefix, t.nextID.Add(1)),
+		in:        make(chan []byte, inProcessBuffer),
+		out:       make(chan []byte, inProcessBuffer),
+		done:      make(chan struct{}),
+	}
+
+	t.mu.Lock()
+	select {
+	case <-t.stopped:
+		t.mu.Unlock()
+		return nil, ErrTransportClosed
+	default:
+	}
+	t.conns[c] = struct{}{}
+	srv, srvCtx := t.srv, t.ctx
+	// Added under the lock, so Start cannot return before serve is tracked
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	// Register before returning, so server-initiated messages reach the
+	// client as soon as it can send
+	srv.RegisterSession(c.sessionID, &inProcessSender{conn: c})
+
+	go func() {
+		defer t.wg.Done()
+		defer t.untrack(c)
+		t.serve(srvCtx, srv, c)
+	}()
+
+	return c, nil
+}
+
+func (t *InProcess) untrack(c *InProcessConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, c)
+}
+
+// serve handles the messages of a connection until the client closes it or
+// the transport stops.
+func (t *InProcess) serve(ctx context.Context, srv *server.Server, c *InProcessConn) {
+	defer srv.UnregisterSession(c.sessionID)
+	defer srv.EndSession(c.sessionID)
+
+	// Requests of a closed connection have no one to respond to
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var requests sync.WaitGroup
+	defer requests.Wait()
+
+	sender := &inProcessSender{conn: c}
+	for {
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+			return
+		case <-c.done:
+			return
+		case data := <-c.in:
+			if err := dispatchMessage(connCtx, srv, c.sessionID, sender, &requests, t.requestTimeout, data); err != nil {
+				log.Printf("Error handling message: %v", err)
+			}
+		}
+	}
+}
+
+// InProcessConn is the client side of an in-process connection. It is safe
+// for concurrent use.
+type InProcessConn struct {
+	sessionID string
+
+	// in carries messages to the server, out messages to the client.
+	in  chan []byte
+	out chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// SessionID returns the ID of the connection's session.
+func (c *InProcessConn) SessionID() string {
+	return c.sessionID
+}
+
+// Send sends a JSON-RPC message to the server, e.g. an mcp.Request or
+// mcp.Notification. Raw JSON, given as a string, []byte or json.RawMessage,
+// is sent as is. It blocks while the server is behind on reading.
+func (c *InProcessConn) Send(ctx context.Context, msg any) error {
+	var data []byte
+	switch m := msg.(type) {
+	case string:
+		data = []byte(m)
+	case []byte:
+		data = m
+	case json.RawMessage:
+		data = m
+	default:
+		var err error
+		if data, err = json.Marshal(msg); err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+	}
+
+	select {
+	case <-c.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	select {
+	case c.in <- data:
+		return nil
+	case <-c.done:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive returns the next message the server sent: a response, a
+// notification or a server-initiated request. Once the connection is closed,
+// it returns the messages still buffered, then ErrSessionClosed.
+func (c *InProcessConn) Receive(ctx context.Context) (json.RawMessage, error) {
+	select {
+	case data := <-c.out:
+		return data, nil
+	case <-c.done:
+		select {
+		case data := <-c.out:
+			return data, nil
+		default:
+			return nil, ErrSessionClosed
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close ends the connection and its session.
+func (c *InProcessConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+// inProcessSender sends messages of the server to an in-process client.
+type inProcessSender struct {
+	conn *InProcessConn
+}
+
+func (s *inProcessSender) send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.conn.out <- data:
+		return nil
+	case <-s.conn.done:
+		return ErrSessionClosed
+	}
+}
+
+func (s *inProcessSender) SendResponse(response mcp.Response) error {
+	if err := s.send(response); err != nil {
+		return fmt.Errorf("failed to send response: %w", err)
+	}
+	return nil
+}
+
+func (s *inProcessSender) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return s.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error: &mcp.ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	})
+}
+
+func (s *inProcessSender) SendNotification(notification mcp.Notification) error {
+	if err := s.send(notification); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
+
+func (s *inProcessSender) SendRequest(request mcp.Request) error {
+	if err := s.send(request); err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	return nil
+}
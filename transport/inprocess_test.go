@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestInProcessTransport(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := NewInProcess(WithInProcessRequestTimeout(0)); err == nil {
+		t.Error("Expected error for invalid request timeout, got nil")
+	}
+	transport, err := NewInProcess()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	receive := func(conn *InProcessConn) json.RawMessage {
+		t.Helper()
+		recvCtx, recvCancel := context.WithTimeout(context.Background(), time.Second)
+		defer recvCancel()
+		data, err := conn.Receive(recvCtx)
+		if err != nil {
+			t.Fatalf("Failed to receive: %v", err)
+		}
+		return data
+	}
+	call := func(conn *InProcessConn, msg any) mcp.Response {
+		t.Helper()
+		if err := conn.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		var resp mcp.Response
+		if err := json.Unmarshal(receive(conn), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// Each connection is a separate session
+	conns := make([]*InProcessConn, 2)
+	for i := range conns {
+		if conns[i], err = transport.Connect(ctx); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+	}
+	if conns[0].SessionID() == conns[1].SessionID() {
+		t.Errorf("Expected distinct sessions, got %q twice", conns[0].SessionID())
+	}
+	for i, conn := range conns {
+		resp := call(conn, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+		if resp.Error != nil {
+			t.Fatalf("Client %d: expected successful initialize, got %+v", i, resp.Error)
+		}
+	}
+	for i, conn := range conns {
+		resp := call(conn, mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewStringID("ping"), Method: "ping"})
+		if resp.Error != nil || resp.ID != mcp.NewStringID("ping") {
+			t.Errorf("Client %d: expected ping response, got %+v", i, resp)
+		}
+	}
+
+	resp := call(conns[0], []byte(`{"jsonrpc":"2.0","id":2,`))
+	if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("Expected parse error, got %+v", resp)
+	}
+
+	// Server-initiated messages reach the session's connection only
+	if err := srv.NotifySession(conns[1].SessionID(), "notifications/message", map[string]any{"level": "info", "data": "hello"}); err != nil {
+		t.Fatalf("Expected notification to be sent, got %v", err)
+	}
+	var notification mcp.Notification
+	if err := json.Unmarshal(receive(conns[1]), &notification); err != nil || notification.Method != "notifications/message" {
+		t.Errorf("Expected log notification, got %+v (%v)", notification, err)
+	}
+
+	// A closed connection ends its session
+	_ = conns[0].Close()
+	if err := conns[0].Send(context.Background(), mcp.Request{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(3), Method: "ping"}); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+
+	// Stop closes open connections and rejects new ones
+	if _, err := conns[1].Receive(context.Background()); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+	if _, err := transport.Connect(context.Background()); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Expected ErrTransportClosed, got %v", err)
+	}
+}
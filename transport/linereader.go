@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// DefaultMaxMessageSize is the default limit on the size of a single inbound
+// JSON-RPC message, in bytes, of every transport.
+const DefaultMaxMessageSize = 4 << 20
+
+// lineReader reads newline-delimited messages of bounded length.
+//
+// Unlike bufio.Scanner, it recovers from oversized lines: the line is
+// discarded up to its end and reported as ErrMessageTooLarge, so the client
+// can be told and reading continues with the next message.
+type lineReader struct {
+	r   *bufio.Reader
+	max int
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), max: max}
+}
+
+// ReadLine returns the next line without its line ending. The last line may
+// lack the trailing newline; io.EOF is returned once the input is exhausted.
+func (l *lineReader) ReadLine() (string, error) {
+	var line []byte
+	tooLarge := false
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		if !tooLarge {
+			line = append(line, chunk...)
+			// Leave room for the \r\n ending before giving up on the line
+			if len(line) > l.max+2 {
+				tooLarge, line = true, nil
+			}
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && (!errors.Is(err, io.EOF) || (len(line) == 0 && !tooLarge)) {
+			return "", err
+		}
+		break
+	}
+
+	if tooLarge {
+		return "", ErrMessageTooLarge
+	}
+
+	line = trimLineEnding(line)
+	if len(line) > l.max {
+		return "", ErrMessageTooLarge
+	}
+	return string(line), nil
+}
+
+func trimLineEnding(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line
+}
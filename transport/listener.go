@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Endpoints selects which endpoints a listener serves.
+type Endpoints int
+
+const (
+	// EndpointsAll serves both the MCP endpoint and the operational endpoints.
+	EndpointsAll Endpoints = iota
+
+	// EndpointsMCP serves only the /mcp endpoint.
+	EndpointsMCP
+
+	// EndpointsOps serves only operational endpoints such as /health, /readyz and the
+	// status page, plus the /debug/pprof and /admin handlers which no other listener exposes.
+	EndpointsOps
+)
+
+func (e Endpoints) String() string {
+	switch e {
+	case EndpointsAll:
+		return "all"
+	case EndpointsMCP:
+		return "mcp"
+	case EndpointsOps:
+		return "ops"
+	default:
+		return fmt.Sprintf("Endpoints(%d)", int(e))
+	}
+}
+
+func (e Endpoints) servesMCP() bool { return e == EndpointsAll || e == EndpointsMCP }
+func (e Endpoints) servesOps() bool { return e == EndpointsAll || e == EndpointsOps }
+
+// Listener configures an address served by the HTTP transport.
+//
+// Listeners share the transport's sessions, timeouts, TLS and host
+// validation, but each can serve a different set of endpoints and add its
+// own middleware, e.g. authentication on an internal admin listener.
+type Listener struct {
+	// Addr is the TCP address to listen on, e.g. "127.0.0.1:8080" or "[::1]:8080".
+	// It is ignored if NetListener is set.
+	Addr string
+
+	// NetListener is an already bound listener to serve instead of listening
+	// on Addr, e.g. a socket passed by systemd socket activation, a listener
+	// on an ephemeral port in tests or a tailnet listener. The transport
+	// takes ownership and closes it when stopped.
+	NetListener net.Listener
+
+	// Endpoints selects the endpoints served, EndpointsAll by default.
+	Endpoints Endpoints
+
+	// Middleware wraps this listener's handler, the first entry being outermost.
+	Middleware []func(http.Handler) http.Handler
+}
+
+// WithListener adds an address or a pre-bound listener for the HTTP transport
+// to serve on.
+//
+// Repeat it to listen on several addresses, e.g. for dual-stack setups.
+// Once any listener is configured, the transport no longer listens on the
+// port set by WithPort, which only applies to the default listener.
+func WithListener(l Listener) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.listeners = append(t.listeners, l)
+	}
+}
+
+func (l Listener) validate() error {
+	if l.NetListener == nil {
+		if _, _, err := net.SplitHostPort(l.Addr); err != nil {
+			return fmt.Errorf("invalid listen address %q: %w", l.Addr, err)
+		}
+	}
+	switch l.Endpoints {
+	case EndpointsAll, EndpointsMCP, EndpointsOps:
+	default:
+		return fmt.Errorf("invalid endpoints for listener %q: %v", l.addr(), l.Endpoints)
+	}
+	for i, middleware := range l.Middleware {
+		if middleware == nil {
+			return fmt.Errorf("nil middleware %d for listener %q", i, l.addr())
+		}
+	}
+	return nil
+}
+
+// addr returns the address the listener serves on.
+func (l Listener) addr() string {
+	if l.NetListener != nil {
+		return l.NetListener.Addr().String()
+	}
+	return l.Addr
+}
+
+// listen returns the pre-bound listener, or binds Addr.
+func (l Listener) listen() (net.Listener, error) {
+	if l.NetListener != nil {
+		return l.NetListener, nil
+	}
+	return net.Listen("tcp", l.Addr)
+}
+
+// listenerPort returns the TCP port of a listener, or 0 for other networks
+// such as Unix sockets.
+func listenerPort(l net.Listener) int {
+	if addr, ok := l.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// handler builds the handler chain of a listener around the shared middleware.
+func (l Listener) handler(mux http.Handler, shared func(http.Handler) http.Handler) http.Handler {
+	handler := shared(mux)
+	for i := len(l.Middleware) - 1; i >= 0; i-- {
+		handler = l.Middleware[i](handler)
+	}
+	return handler
+}
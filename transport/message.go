@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// message is the wire envelope of any inbound JSON-RPC message.
+//
+// Clients send requests, notifications and responses to server-initiated
+// requests over the same channel, so transports decode into this envelope
+// first and dispatch based on which fields are present. Params are kept raw
+// so the server can decode them for the specific method. IDs of a type other
+// than string or number fail decoding with mcp.ErrInvalidRequestID.
+type message struct {
+	JSONRPC string             `json:"jsonrpc"`
+	Method  string             `json:"method,omitempty"`
+	ID      mcp.RequestID      `json:"id"`
+	Params  json.RawMessage    `json:"params,omitempty"`
+	Result  any                `json:"result,omitempty"`
+	Error   *mcp.ErrorResponse `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a server-initiated request.
+// Error responses may carry a null ID if the client could not read the
+// request's, which still must not be mistaken for a notification.
+func (m *message) isResponse() bool {
+	return m.Method == "" && (!m.ID.IsZero() || m.Result != nil || m.Error != nil)
+}
+
+func (m *message) request() mcp.Request {
+	return mcp.Request{JSONRPC: m.JSONRPC, Method: m.Method, ID: m.ID, Params: m.Params}
+}
+
+func (m *message) notification() mcp.Notification {
+	var params any
+	if len(m.Params) > 0 {
+		params = m.Params
+	}
+	return mcp.Notification{JSONRPC: m.JSONRPC, Method: m.Method, Params: params}
+}
+
+func (m *message) response() mcp.Response {
+	return mcp.Response{JSONRPC: m.JSONRPC, ID: m.ID, Result: m.Result, Error: m.Error}
+}
+
+// decodeMessage decodes and validates an inbound JSON-RPC message. A rejected
+// message comes with the error to answer it with, addressed to the message's
+// ID where it could be read.
+func decodeMessage(srv *server.Server, data []byte) (message, mcp.RequestID, *mcp.Error) {
+	// encoding/json silently replaces invalid UTF-8, so reject it up front
+	if !utf8.Valid(data) {
+		return message{}, parseErrorID(data), mcp.NewError(mcp.ErrorCodeParseError, "Parse error", ErrInvalidUTF8.Error())
+	}
+
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		if errors.Is(err, mcp.ErrInvalidRequestID) {
+			return message{}, mcp.RequestID{}, mcp.NewError(mcp.ErrorCodeInvalidRequest, "Invalid request", err.Error())
+		}
+		return message{}, parseErrorID(data), mcp.NewError(mcp.ErrorCodeParseError, "Parse error", err.Error())
+	}
+
+	if err := srv.ValidateMessage(data); err != nil {
+		log.Printf("Rejecting invalid message: %v", err)
+		return message{}, msg.ID, err
+	}
+	return msg, msg.ID, nil
+}
+
+// parseErrorID returns the ID of a message that could not be decoded, if it
+// can still be read, and -1 otherwise.
+func parseErrorID(data []byte) mcp.RequestID {
+	var partial struct {
+		ID mcp.RequestID `json:"id"`
+	}
+	if err := json.Unmarshal(data, &partial); err == nil && !partial.ID.IsZero() {
+		return partial.ID
+	}
+	return mcp.NewIntID(-1)
+}
+
+// requestSender is implemented by senders that answer every request through
+// a sender of its own, such as the stdio sender keeping responses in order.
+type requestSender interface {
+	// forRequest returns the sender for a request that was just read and a
+	// function to call once the request has been handled.
+	forRequest() (mcp.ResponseSender, func())
+}
+
+// dispatchMessage decodes an inbound JSON-RPC message of a session and hands
+// it to srv. It is shared by the transports that carry a session's messages
+// over one stream, so they all reject and route messages alike.
+//
+// Messages rejected before dispatch are answered through sender. Requests are
+// tracked by requests and time out after requestTimeout.
+func dispatchMessage(ctx context.Context, srv *server.Server, sessionID string, sender mcp.ResponseSender, requests *sync.WaitGroup, requestTimeout time.Duration, data []byte) error {
+	msg, id, rejected := decodeMessage(srv, data)
+	if rejected != nil {
+		return sender.SendError(id, rejected.Code, rejected.Message, rejected.Data)
+	}
+
+	if msg.JSONRPC != mcp.JSONRPCVersion {
+		log.Printf("Invalid JSON-RPC version: %q", msg.JSONRPC)
+		return nil
+	}
+
+	reqCtx := context.WithValue(ctx, mcp.SessionIDKey, sessionID)
+
+	if msg.isResponse() {
+		return srv.HandleResponse(reqCtx, msg.response())
+	}
+
+	if msg.ID.IsZero() {
+		return srv.HandleNotification(reqCtx, msg.notification())
+	}
+
+	req := msg.request()
+
+	done := func() {}
+	if s, ok := sender.(requestSender); ok {
+		sender, done = s.forRequest()
+	}
+
+	// Requests are handled concurrently so that notifications such as
+	// notifications/cancelled can be processed while a request is running
+	requests.Add(1)
+	go func() {
+		defer requests.Done()
+		defer done()
+
+		reqCtx := context.WithValue(reqCtx, mcp.ResponseSenderKey, sender)
+		reqCtx, cancel := context.WithTimeout(reqCtx, requestTimeout)
+		defer cancel()
+
+		if err := srv.HandleRequest(reqCtx, req); err != nil {
+			log.Printf("Error handling request: %v", err)
+		}
+	}()
+
+	return nil
+}
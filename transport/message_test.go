@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		data   string
+		wantID mcp.RequestID
+		code   int
+	}{
+		{"request", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, mcp.NewIntID(1), 0},
+		{"invalid UTF-8", "{\"jsonrpc\":\"2.0\",\"id\":\"a\xff\",\"method\":\"ping\"}", mcp.NewStringID("a�"), mcp.ErrorCodeParseError},
+		{"malformed JSON", `{"jsonrpc":"2.0",`, mcp.NewIntID(-1), mcp.ErrorCodeParseError},
+		{"readable ID", `{"jsonrpc":"2.0","id":7,"method":5}`, mcp.NewIntID(7), mcp.ErrorCodeParseError},
+		{"invalid ID", `{"jsonrpc":"2.0","id":{},"method":"ping"}`, mcp.RequestID{}, mcp.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, id, rejected := decodeMessage(srv, []byte(tt.data))
+			if tt.code == 0 {
+				if rejected != nil {
+					t.Fatalf("Expected no error, got %v", rejected)
+				}
+			} else if rejected == nil || rejected.Code != tt.code {
+				t.Fatalf("Expected error code %d, got %v", tt.code, rejected)
+			}
+			if id != tt.wantID {
+				t.Errorf("Expected ID %v, got %v", tt.wantID, id)
+			}
+		})
+	}
+}
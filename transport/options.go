@@ -0,0 +1,582 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// Default settings of the HTTP transport.
+const (
+	DefaultHTTPPort            = 8080
+	DefaultHTTPBindAddr        = "127.0.0.1"
+	DefaultHTTPReadTimeout     = 30 * time.Second
+	DefaultHTTPWriteTimeout    = 30 * time.Second
+	DefaultHTTPIdleTimeout     = 120 * time.Second
+	DefaultHTTPShutdownTimeout = 5 * time.Second
+	DefaultHTTPRequestTimeout  = 30 * time.Second
+	DefaultSSEKeepAlive        = 15 * time.Second
+	DefaultSessionIdleTimeout  = 30 * time.Minute
+	DefaultACMEChallengeAddr   = ":80"
+)
+
+// HTTPOption configures the HTTP transport.
+type HTTPOption func(*HTTPTransport)
+
+// WithPort sets the TCP port the HTTP transport listens on.
+// Port 0 picks a free ephemeral port.
+func WithPort(port int) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.port = port
+	}
+}
+
+// WithBindAddr sets the address the port and the admin port bind to, e.g.
+// "0.0.0.0" or "::" to accept connections from other hosts. Empty binds all
+// interfaces. Defaults to DefaultHTTPBindAddr, so a local server is not
+// exposed to the network by accident. Listeners set with WithListener
+// carry their own address.
+func WithBindAddr(addr string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.bindAddr = addr
+	}
+}
+
+// WithBasePath serves all endpoints under a path, e.g. "/api/ai" serves the
+// MCP endpoint at /api/ai/mcp and the health check at /api/ai/health, for
+// servers mounted behind a gateway that forwards the full path. It applies to
+// every listener. CORS rules and listener middleware see paths relative to
+// the base path.
+func WithBasePath(path string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.basePath = strings.TrimSuffix(path, "/")
+	}
+}
+
+// WithStatusPage serves the HTML status page at the root path. Enabled by
+// default; disable it to not reveal server details to visitors.
+func WithStatusPage(enabled bool) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.statusPage = enabled
+	}
+}
+
+// WithAdminPort serves the operational endpoints on a separate port.
+//
+// The status page, /health, /readyz, the /debug/pprof profiling handlers and
+// the /admin endpoints, see WithAdminToken, are then served only on this port,
+// and the default listener serves /mcp alone. This allows exposing the MCP endpoint publicly while keeping
+// operational endpoints internal. Port 0 (the default) disables it.
+func WithAdminPort(port int) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.adminPort = port
+	}
+}
+
+// WithLongPollTimeout sets how long a poll of the long-poll fallback waits for
+// server messages before returning empty. It is capped at half the write
+// timeout so the response can still be written. Defaults to DefaultLongPollTimeout.
+func WithLongPollTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.longPollTimeout = timeout
+	}
+}
+
+// WithSSEKeepAlive sets how long a GET event stream may stay idle before a
+// ": ping" comment is sent, so proxies and load balancers do not close it.
+// Zero disables keepalives. Defaults to DefaultSSEKeepAlive.
+func WithSSEKeepAlive(interval time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.sseKeepAlive = interval
+	}
+}
+
+// WithReadTimeout sets the maximum duration for reading an entire request.
+func WithReadTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of a response.
+func WithWriteTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets how long keep-alive connections may stay idle.
+func WithIdleTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.idleTimeout = timeout
+	}
+}
+
+// WithShutdownTimeout sets how long Stop waits for in-flight requests to
+// finish before cancelling them, see HTTPTransport.Stop.
+func WithShutdownTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.shutdownTimeout = timeout
+	}
+}
+
+// WithRequestTimeout sets the maximum time a single MCP request may take.
+func WithRequestTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.requestTimeout = timeout
+	}
+}
+
+// WithSessionIdleTimeout sets how long a session may go without requests
+// and open event stream before it expires, see DefaultSessionIdleTimeout.
+// Expired sessions are ended as if deleted by the client, which then gets
+// 404 Not Found and has to initialize anew. 0 keeps sessions until they are
+// deleted or the transport stops.
+func WithSessionIdleTimeout(timeout time.Duration) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.sessionIdleTimeout = timeout
+	}
+}
+
+// WithSessionLimit caps the number of sessions the transport keeps, including
+// those without an open event stream. At the limit, a new session evicts the
+// least recently active session without an open event stream, or is rejected
+// with 503 Service Unavailable if every session has one. 0 means unlimited.
+//
+// Unlike SessionAdmission, which limits the open event streams, the limit
+// bounds the memory held for clients that went away without deleting their
+// session.
+func WithSessionLimit(limit int) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.sessionLimit = limit
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a request body in bytes, see
+// DefaultMaxMessageSize. Larger requests are rejected with 413 Request Entity
+// Too Large before they are buffered.
+func WithMaxMessageSize(size int) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.maxMessageSize = size
+	}
+}
+
+// WithACME enables automatic TLS certificate provisioning via ACME (Let's Encrypt).
+//
+// Certificates are issued and renewed for the given domains and persisted in
+// cacheDir so restarts don't trigger new issuance. TLS-ALPN-01 challenges are
+// answered on the transport's TLS listeners, which the CA reaches on port 443.
+// HTTP-01 challenges are answered on a separate plain HTTP listener, see
+// WithACMEChallengeAddr. The email is optional and passed to the CA for
+// expiry notices.
+func WithACME(domains []string, cacheDir, email string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.acmeDomains = domains
+		t.acmeCacheDir = cacheDir
+		t.acmeEmail = email
+	}
+}
+
+// WithACMEChallengeAddr sets the address of the plain HTTP listener that
+// answers ACME HTTP-01 challenges and redirects all other requests to HTTPS.
+// The CA connects to port 80, so a different port needs a forward from it.
+// Empty disables the listener, leaving TLS-ALPN-01 as the only challenge.
+// Defaults to DefaultACMEChallengeAddr; ignored without WithACME.
+func WithACMEChallengeAddr(addr string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.acmeChallengeAddr = addr
+	}
+}
+
+// WithAllowedHosts restricts the Host header values the transport accepts.
+//
+// This protects locally running servers against DNS rebinding attacks, where a
+// malicious website resolves its own domain to 127.0.0.1 to reach the server
+// from the browser. Entries may include a port ("localhost:8080") to match
+// exactly, or omit it to match any port. Requests for other hosts are rejected
+// with 421 Misdirected Request. An empty list disables the check.
+func WithAllowedHosts(hosts ...string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.allowedHosts = hosts
+	}
+}
+
+// WithResponseHeaders adds headers to every HTTP response.
+//
+// Headers are applied after the built-in security headers (X-Content-Type-Options,
+// X-Frame-Options, X-XSS-Protection), so they can override them. An empty value
+// removes the header, which disables a built-in default.
+//
+// Example usage:
+//
+//	WithResponseHeaders(map[string]string{
+//	    "Strict-Transport-Security": "max-age=63072000",
+//	    "X-XSS-Protection":          "",
+//	})
+func WithResponseHeaders(headers map[string]string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.responseHeaders = headers
+	}
+}
+
+func (t *HTTPTransport) validate() error {
+	if t.port < 0 || t.port > 65535 {
+		return fmt.Errorf("invalid port: %d (must be 0-65535)", t.port)
+	}
+
+	if t.maxMessageSize <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", t.maxMessageSize)
+	}
+
+	if t.sessionIdleTimeout < 0 {
+		return fmt.Errorf("invalid session idle timeout: %v (must not be negative)", t.sessionIdleTimeout)
+	}
+	if t.sessionLimit < 0 {
+		return fmt.Errorf("invalid session limit: %d (must not be negative)", t.sessionLimit)
+	}
+
+	if strings.ContainsAny(t.bindAddr, ":[]") && net.ParseIP(t.bindAddr) == nil {
+		return fmt.Errorf("invalid bind address %q (must be a host or IP without port)", t.bindAddr)
+	}
+
+	if t.basePath != "" && (!strings.HasPrefix(t.basePath, "/") || path.Clean(t.basePath) != t.basePath) {
+		return fmt.Errorf("invalid base path %q (must be an absolute, clean path such as /api/ai)", t.basePath)
+	}
+
+	if t.adminPort < 0 || t.adminPort > 65535 {
+		return fmt.Errorf("invalid admin port: %d (must be 0-65535)", t.adminPort)
+	}
+	if t.adminPort != 0 && t.adminPort == t.port && len(t.listeners) == 0 {
+		return fmt.Errorf("admin port must differ from port %d", t.port)
+	}
+	if err := validateAdminToken(t.adminToken); err != nil {
+		return err
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read", t.readTimeout},
+		{"write", t.writeTimeout},
+		{"idle", t.idleTimeout},
+		{"shutdown", t.shutdownTimeout},
+		{"request", t.requestTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("invalid %s timeout: %v (must be positive)", timeout.name, timeout.value)
+		}
+	}
+
+	if t.longPollTimeout <= 0 {
+		return fmt.Errorf("invalid long-poll timeout: %v (must be positive)", t.longPollTimeout)
+	}
+
+	if t.sseKeepAlive < 0 {
+		return fmt.Errorf("invalid SSE keepalive interval: %v (must not be negative)", t.sseKeepAlive)
+	}
+
+	if t.admission != nil {
+		if err := t.admission.cfg.validate(); err != nil {
+			return err
+		}
+	}
+
+	if t.browserTokens != nil {
+		if err := t.browserTokens.validate(); err != nil {
+			return err
+		}
+	}
+
+	if len(t.acmeDomains) > 0 && t.acmeCacheDir == "" {
+		return fmt.Errorf("ACME requires a cache directory")
+	}
+	if len(t.acmeDomains) > 0 && t.acmeChallengeAddr != "" {
+		if _, _, err := net.SplitHostPort(t.acmeChallengeAddr); err != nil {
+			return fmt.Errorf("invalid ACME challenge address %q: %w", t.acmeChallengeAddr, err)
+		}
+	}
+
+	if _, err := parseTrustedProxies(t.trustedProxies); err != nil {
+		return err
+	}
+
+	if err := validateOrigins(t.allowedOrigins); err != nil {
+		return err
+	}
+
+	if err := t.cors.validate(); err != nil {
+		return err
+	}
+
+	for name := range t.responseHeaders {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid response header name: %q", name)
+		}
+	}
+
+	for _, l := range t.listeners {
+		if err := l.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StdioOption configures the stdio transport.
+type StdioOption func(*Stdio)
+
+// WithStdioRequestTimeout sets the maximum time a single MCP request may take.
+func WithStdioRequestTimeout(timeout time.Duration) StdioOption {
+	return func(t *Stdio) {
+		t.requestTimeout = timeout
+	}
+}
+
+// WithStdioMaxMessageSize sets the maximum length of a single message line in
+// bytes, see DefaultMaxMessageSize. Longer lines are discarded and answered
+// with an error.
+func WithStdioMaxMessageSize(size int) StdioOption {
+	return func(t *Stdio) {
+		t.maxMessageSize = size
+	}
+}
+
+// WithOrderedResponses makes the stdio transport write responses in the
+// order the requests were received.
+//
+// Requests are still processed concurrently, but a response is held back
+// until all earlier requests have been answered. This suits clients that
+// assume pipelined responses arrive in order. Notifications are not delayed.
+func WithOrderedResponses(enabled bool) StdioOption {
+	return func(t *Stdio) {
+		if enabled {
+			t.sequencer = newResponseSequencer()
+		} else {
+			t.sequencer = nil
+		}
+	}
+}
+
+func (t *Stdio) validate() error {
+	if t.requestTimeout <= 0 {
+		return fmt.Errorf("invalid request timeout: %v (must be positive)", t.requestTimeout)
+	}
+	if t.maxMessageSize <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", t.maxMessageSize)
+	}
+	return nil
+}
+
+// TCPOption configures the TCP transport.
+type TCPOption func(*TCP)
+
+// WithTCPAddr sets the address the TCP transport listens on, e.g. "127.0.0.1:9090".
+// Port 0 picks a free ephemeral port.
+func WithTCPAddr(addr string) TCPOption {
+	return func(t *TCP) {
+		t.addr = addr
+	}
+}
+
+// WithTCPReadTimeout sets how long a connection may stay silent before it is closed.
+func WithTCPReadTimeout(timeout time.Duration) TCPOption {
+	return func(t *TCP) {
+		t.readTimeout = timeout
+	}
+}
+
+// WithTCPWriteTimeout sets the maximum duration for writing a single message to a connection.
+func WithTCPWriteTimeout(timeout time.Duration) TCPOption {
+	return func(t *TCP) {
+		t.writeTimeout = timeout
+	}
+}
+
+// WithTCPRequestTimeout sets the maximum time a single MCP request may take.
+func WithTCPRequestTimeout(timeout time.Duration) TCPOption {
+	return func(t *TCP) {
+		t.requestTimeout = timeout
+	}
+}
+
+// WithTCPMaxMessageSize sets the maximum length of a single message line in
+// bytes, see DefaultMaxMessageSize. Longer lines are discarded and answered
+// with an error.
+func WithTCPMaxMessageSize(size int) TCPOption {
+	return func(t *TCP) {
+		t.maxMessageSize = size
+	}
+}
+
+func (t *TCP) validate() error {
+	if t.maxMessageSize <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", t.maxMessageSize)
+	}
+
+	if _, _, err := net.SplitHostPort(t.addr); err != nil {
+		return fmt.Errorf("invalid address %q: %w", t.addr, err)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read", t.readTimeout},
+		{"write", t.writeTimeout},
+		{"request", t.requestTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("invalid %s timeout: %v (must be positive)", timeout.name, timeout.value)
+		}
+	}
+	return nil
+}
+
+// InProcessOption configures the in-process transport.
+type InProcessOption func(*InProcess)
+
+// WithInProcessRequestTimeout sets the maximum time a single MCP request may take.
+func WithInProcessRequestTimeout(timeout time.Duration) InProcessOption {
+	return func(t *InProcess) {
+		t.requestTimeout = timeout
+	}
+}
+
+func (t *InProcess) validate() error {
+	if t.requestTimeout <= 0 {
+		return fmt.Errorf("invalid request timeout: %v (must be positive)", t.requestTimeout)
+	}
+	return nil
+}
+
+// GRPCOption configures the gRPC transport.
+type GRPCOption func(*GRPC)
+
+// WithGRPCAddr sets the address the gRPC transport listens on, e.g. "127.0.0.1:50051".
+// Port 0 picks a free ephemeral port.
+func WithGRPCAddr(addr string) GRPCOption {
+	return func(t *GRPC) {
+		t.addr = addr
+	}
+}
+
+// WithGRPCWriteTimeout sets the maximum duration for writing a single message to a stream.
+func WithGRPCWriteTimeout(timeout time.Duration) GRPCOption {
+	return func(t *GRPC) {
+		t.writeTimeout = timeout
+	}
+}
+
+// WithGRPCRequestTimeout sets the maximum time a single MCP request may take.
+func WithGRPCRequestTimeout(timeout time.Duration) GRPCOption {
+	return func(t *GRPC) {
+		t.requestTimeout = timeout
+	}
+}
+
+// WithGRPCMaxMessageSize sets the maximum size of a single JSON-RPC payload
+// in bytes, see DefaultMaxMessageSize. Larger messages are discarded and
+// answered with an error.
+func WithGRPCMaxMessageSize(size int) GRPCOption {
+	return func(t *GRPC) {
+		t.maxMessageSize = size
+	}
+}
+
+func (t *GRPC) validate() error {
+	if _, _, err := net.SplitHostPort(t.addr); err != nil {
+		return fmt.Errorf("invalid address %q: %w", t.addr, err)
+	}
+	if t.maxMessageSize <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", t.maxMessageSize)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"write", t.writeTimeout},
+		{"request", t.requestTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("invalid %s timeout: %v (must be positive)", timeout.name, timeout.value)
+		}
+	}
+	return nil
+}
+
+// PipeOption configures the named pipe transport.
+type PipeOption func(*Pipe)
+
+// WithPipeName sets the name of the pipe the transport listens on, e.g.
+// `\\.\pipe\go-mcp-server`.
+func WithPipeName(name string) PipeOption {
+	return func(p *Pipe) {
+		p.conns.addr = name
+	}
+}
+
+// WithPipeReadTimeout sets how long a connection may stay silent before it is closed.
+func WithPipeReadTimeout(timeout time.Duration) PipeOption {
+	return func(p *Pipe) {
+		p.conns.readTimeout = timeout
+	}
+}
+
+// WithPipeWriteTimeout sets the maximum duration for writing a single message to a connection.
+func WithPipeWriteTimeout(timeout time.Duration) PipeOption {
+	return func(p *Pipe) {
+		p.conns.writeTimeout = timeout
+	}
+}
+
+// WithPipeRequestTimeout sets the maximum time a single MCP request may take.
+func WithPipeRequestTimeout(timeout time.Duration) PipeOption {
+	return func(p *Pipe) {
+		p.conns.requestTimeout = timeout
+	}
+}
+
+// WithPipeMaxMessageSize sets the maximum length of a single message line in
+// bytes, see DefaultMaxMessageSize. Longer lines are discarded and answered
+// with an error.
+func WithPipeMaxMessageSize(size int) PipeOption {
+	return func(p *Pipe) {
+		p.conns.maxMessageSize = size
+	}
+}
+
+func (p *Pipe) validate() error {
+	t := p.conns
+	// Pipe names are at most 256 characters, and case-insensitive
+	if len(t.addr) <= len(pipeNamePrefix) || len(t.addr) > 256 || !strings.EqualFold(t.addr[:len(pipeNamePrefix)], pipeNamePrefix) {
+		return fmt.Errorf(`invalid pipe name %q (must be \\.\pipe\<name> of at most 256 characters)`, t.addr)
+	}
+	if t.maxMessageSize <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", t.maxMessageSize)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read", t.readTimeout},
+		{"write", t.writeTimeout},
+		{"request", t.requestTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			return fmt.Errorf("invalid %s timeout: %v (must be positive)", timeout.name, timeout.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// WithAllowedOrigins sets the origins browsers may send requests to the MCP
+// endpoint from, e.g. "https://app.example.com", or "*" for any origin.
+//
+// Requests to /mcp carrying an Origin header that is not allowed are rejected
+// with 403 Forbidden, as the streamable HTTP specification requires to
+// protect locally running servers from DNS rebinding: a malicious page whose
+// domain is rebound to 127.0.0.1 could otherwise call local tools. Requests
+// without an Origin header, i.e. non-browser clients, are not affected.
+//
+// By default, only loopback origins such as http://localhost:6274 and
+// http://127.0.0.1:3000 are allowed, on any port. Setting origins replaces
+// that default. Origins allowed to obtain browser tokens, see
+// WithBrowserTokens, are always allowed.
+func WithAllowedOrigins(origins ...string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.allowedOrigins = origins
+	}
+}
+
+func validateOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("invalid allowed origin %q (must be * or scheme://host[:port])", origin)
+		}
+	}
+	return nil
+}
+
+// isAllowedOrigin reports whether browsers may call the MCP endpoint from origin.
+func (t *HTTPTransport) isAllowedOrigin(origin string) bool {
+	if t.browserTokens != nil && slices.Contains(t.browserTokens.Origins, origin) {
+		return true
+	}
+	if len(t.allowedOrigins) == 0 {
+		return isLoopbackOrigin(origin)
+	}
+	for _, allowed := range t.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopbackOrigin reports whether origin is served from this host, e.g.
+// http://localhost:6274 or http://[::1]:3000.
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// originValidationMiddleware rejects browser requests to the MCP endpoint
+// from origins that are not allowed, before CORS preflights are answered.
+func (t *HTTPTransport) originValidationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !isMCPPath(r.URL.Path) || t.isAllowedOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("Rejected MCP request from %s with disallowed origin: %q", clientIP(r), origin)
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+	})
+}
+
+// isMCPPath reports whether path belongs to the MCP endpoint.
+func isMCPPath(path string) bool {
+	return path == "/mcp" || strings.HasPrefix(path, "/mcp/")
+}
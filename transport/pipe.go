@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// DefaultPipeName is the default name of the named pipe transport's pipe.
+const DefaultPipeName = `\\.\pipe\go-mcp-server`
+
+// pipeNamePrefix is the namespace of local named pipes.
+const pipeNamePrefix = `\\.\pipe\`
+
+// Pipe serves newline-delimited JSON-RPC over a Windows named pipe, so that
+// clients on the same host, such as editor extensions, can connect without
+// the server opening a TCP port.
+//
+// Every pipe connection is its own MCP session, and messages are framed like
+// those of the TCP transport. The pipe rejects remote clients and may only be
+// opened by the user running the server and by SYSTEM. Named pipes are only
+// supported on Windows; elsewhere, Start fails with ErrListen.
+type Pipe struct {
+	// The TCP transport serves the pipe's connections
+	conns *TCP
+}
+
+// NewPipe creates a new named pipe transport configured by the given options.
+//
+// Unset options fall back to DefaultPipeName and the defaults of the TCP
+// transport, see the DefaultTCP*Timeout constants.
+func NewPipe(opts ...PipeOption) (*Pipe, error) {
+	p := &Pipe{conns: &TCP{
+		addr:           DefaultPipeName,
+		readTimeout:    DefaultTCPReadTimeout,
+		writeTimeout:   DefaultTCPWriteTimeout,
+		requestTimeout: DefaultTCPRequestTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+		network:        "pipe",
+		listen:         listenPipe,
+		conns:          make(map[*tcpConn]struct{}),
+	}}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid named pipe transport options: %w", err)
+	}
+
+	return p, nil
+}
+
+// Addr returns the pipe the transport listens on, or nil before Start has created it.
+func (p *Pipe) Addr() net.Addr {
+	return p.conns.Addr()
+}
+
+func (p *Pipe) Start(ctx context.Context, srv *server.Server) error {
+	return p.conns.Start(ctx, srv)
+}
+
+// Stop closes the pipe and all open connections, then waits for their
+// in-flight requests to finish.
+func (p *Pipe) Stop() error {
+	return p.conns.Stop()
+}
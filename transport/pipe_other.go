@@ -0,0 +1,14 @@
+//go:build !windows
+
+package transport
+
+import (
+	"errors"
+	"net"
+)
+
+var errPipeUnsupported = errors.New("named pipes are only supported on Windows")
+
+func listenPipe(string) (net.Listener, error) {
+	return nil, errPipeUnsupported
+}
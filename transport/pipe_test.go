@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestNewPipeOptions(t *testing.T) {
+	transport, err := NewPipe()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.conns.addr != DefaultPipeName {
+		t.Errorf("Expected default pipe name %q, got %q", DefaultPipeName, transport.conns.addr)
+	}
+
+	if _, err := NewPipe(WithPipeName(`\\.\PIPE\mcp`)); err != nil {
+		t.Errorf("Expected pipe namespace to be case-insensitive, got %v", err)
+	}
+
+	invalid := []PipeOption{
+		WithPipeName("go-mcp-server"),
+		WithPipeName(`\\.\pipe\`),
+		WithPipeName(`\\.\pipe\` + strings.Repeat("x", 256)),
+		WithPipeName(`\\server\pipe\go-mcp-server`),
+		WithPipeReadTimeout(0),
+		WithPipeWriteTimeout(-time.Second),
+		WithPipeRequestTimeout(0),
+		WithPipeMaxMessageSize(0),
+	}
+	for _, opt := range invalid {
+		if _, err := NewPipe(opt); err == nil {
+			t.Error("Expected error for invalid option, got nil")
+		}
+	}
+}
+
+func TestPipeUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes are supported on Windows")
+	}
+
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewPipe()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := transport.Start(context.Background(), srv); !errors.Is(err, ErrListen) {
+		t.Errorf("Expected ErrListen, got %v", err)
+	}
+}
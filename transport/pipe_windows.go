@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
+)
+
+// pipeBufferSize is the size of the pipe's input and output buffers.
+const pipeBufferSize = 64 << 10
+
+func listenPipe(name string) (net.Listener, error) {
+	sddl, err := pipeSecurityDescriptor()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restrict pipe access: %w", err)
+	}
+
+	// Byte stream in both directions, like a TCP connection. Remote clients
+	// are rejected, and creating the pipe fails if another process already
+	// owns the name.
+	listener, err := winio.ListenPipe(name, &winio.PipeConfig{
+		SecurityDescriptor: sddl,
+		InputBufferSize:    pipeBufferSize,
+		OutputBufferSize:   pipeBufferSize,
+	})
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, fmt.Errorf("%w (the pipe is in use by another process, e.g. another instance of this server; stop it or choose another pipe name)", err)
+		}
+		return nil, err
+	}
+	return &pipeListener{Listener: listener}, nil
+}
+
+// pipeSecurityDescriptor returns a security descriptor granting access to
+// the pipe to the current user and SYSTEM only. By default, any local user
+// could read from it.
+func pipeSecurityDescriptor() (string, error) {
+	token := windows.GetCurrentProcessToken()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	return "D:P(A;;GA;;;" + user.User.Sid.String() + ")(A;;GA;;;SY)", nil
+}
+
+// pipeListener accepts clients of a named pipe, each connecting to its own
+// instance of the pipe.
+type pipeListener struct {
+	net.Listener
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &pipeConn{Conn: conn}, nil
+}
+
+// pipeConn is the server end of a named pipe connection. It reports errors
+// like other net.Conn implementations, so that closing connections on Stop
+// is not mistaken for a read error.
+type pipeConn struct {
+	net.Conn
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	return n, mapPipeError(err)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	return n, mapPipeError(err)
+}
+
+// mapPipeError translates the errors of a pipe into those net.Conn users expect.
+func mapPipeError(err error) error {
+	switch {
+	case errors.Is(err, winio.ErrFileClosed):
+		return net.ErrClosed
+	case errors.Is(err, winio.ErrTimeout):
+		return os.ErrDeadlineExceeded
+	}
+	return err
+}
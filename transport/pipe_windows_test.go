@@ -0,0 +1,159 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestPipeTransport(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	name := fmt.Sprintf(`\\.\pipe\go-mcp-server-test-%d`, os.Getpid())
+	transport, err := NewPipe(WithPipeName(name))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	for deadline := time.Now().Add(time.Second); transport.Addr() == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Transport did not start listening")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A second server cannot take over the pipe
+	second, err := NewPipe(WithPipeName(name))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := second.Start(ctx, srv); err == nil {
+		t.Error("Expected error starting a second server on the same pipe, got nil")
+	}
+
+	type client struct {
+		file    *os.File
+		scanner *bufio.Scanner
+	}
+	call := func(c client, line string) mcp.Response {
+		t.Helper()
+		if _, err := c.file.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if !c.scanner.Scan() {
+			t.Fatalf("Failed to read response: %v", c.scanner.Err())
+		}
+		var resp mcp.Response
+		if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// Each connection is a separate session
+	clients := make([]client, 2)
+	for i := range clients {
+		file, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer file.Close()
+		clients[i] = client{file: file, scanner: bufio.NewScanner(file)}
+	}
+	for i, c := range clients {
+		resp := call(c, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+		if resp.Error != nil {
+			t.Fatalf("Client %d: expected successful initialize, got %+v", i, resp.Error)
+		}
+	}
+	for i, c := range clients {
+		resp := call(c, `{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+		if resp.Error != nil || resp.ID != mcp.NewStringID("ping") {
+			t.Errorf("Client %d: expected ping response, got %+v", i, resp)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+
+	// Stop closes open connections
+	if clients[1].scanner.Scan() {
+		t.Errorf("Expected connection to be closed, read %q", clients[1].scanner.Text())
+	}
+}
+
+func TestPipeListener(t *testing.T) {
+	name := fmt.Sprintf(`\\.\pipe\go-mcp-server-listener-test-%d`, os.Getpid())
+	listener, err := listenPipe(name)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer listener.Close()
+
+	// The name is owned by the first listener
+	if second, err := listenPipe(name); err == nil {
+		second.Close()
+		t.Error("Expected error listening on a pipe in use, got nil")
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Errorf("Failed to accept: %v", err)
+		}
+		accepted <- conn
+	}()
+	client, err := winio.DialPipe(name, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	conn := <-accepted
+	if conn == nil {
+		t.FailNow()
+	}
+
+	// Deadlines apply to pending reads
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected os.ErrDeadlineExceeded, got %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	// Reads from a closed connection report net.ErrClosed, like TCP
+	_ = conn.Close()
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected net.ErrClosed, got %v", err)
+	}
+
+	_ = listener.Close()
+	if _, err := listener.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected net.ErrClosed from a closed listener, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// Defaults of the long-poll fallback.
+const (
+	DefaultLongPollTimeout = 20 * time.Second
+	maxPollQueue           = 1000
+)
+
+// pollResponse is the body returned by the long-poll endpoint.
+type pollResponse struct {
+	// Cursor is passed back by the client to acknowledge the returned messages.
+	Cursor   uint64 `json:"cursor"`
+	Messages []any  `json:"messages"`
+}
+
+type queuedMessage struct {
+	seq uint64
+	msg any
+}
+
+// pollSession queues server-initiated messages for a client that cannot
+// receive SSE, until the client fetches them from the long-poll endpoint.
+type pollSession struct {
+	ID string
+
+	mu      sync.Mutex
+	queue   []queuedMessage
+	lastSeq uint64
+	arrived chan struct{}
+	expiry  *time.Timer
+	closed  bool
+}
+
+func newPollSession(id string) *pollSession {
+	return &pollSession{ID: id, arrived: make(chan struct{})}
+}
+
+func (p *pollSession) push(msg any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrSessionClosed
+	}
+
+	p.lastSeq++
+	p.queue = append(p.queue, queuedMessage{seq: p.lastSeq, msg: msg})
+	// Drop the oldest message of clients that stopped polling
+	if len(p.queue) > maxPollQueue {
+		p.queue = p.queue[1:]
+	}
+
+	// Wake up a waiting poll
+	close(p.arrived)
+	p.arrived = make(chan struct{})
+	return nil
+}
+
+// SendNotification queues a notification for the next poll.
+func (p *pollSession) SendNotification(notification mcp.Notification) error {
+	return p.push(notification)
+}
+
+// SendRequest queues a server-initiated request; the client POSTs its response back.
+func (p *pollSession) SendRequest(request mcp.Request) error {
+	return p.push(request)
+}
+
+// poll drops the messages acknowledged by cursor and returns the remaining
+// ones, waiting up to timeout for new messages if there are none.
+func (p *pollSession) poll(ctx context.Context, cursor uint64, timeout time.Duration) pollResponse {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		for len(p.queue) > 0 && p.queue[0].seq <= cursor {
+			p.queue = p.queue[1:]
+		}
+		if len(p.queue) > 0 || p.closed {
+			resp := pollResponse{Cursor: p.lastSeq, Messages: make([]any, 0, len(p.queue))}
+			for _, queued := range p.queue {
+				resp.Messages = append(resp.Messages, queued.msg)
+			}
+			p.mu.Unlock()
+			return resp
+		}
+		arrived := p.arrived
+		p.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return pollResponse{Cursor: cursor, Messages: []any{}}
+		case <-ctx.Done():
+			return pollResponse{Cursor: cursor, Messages: []any{}}
+		}
+	}
+}
+
+func (p *pollSession) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed {
+		p.closed = true
+		close(p.arrived)
+	}
+}
+
+// wantsLongPoll reports whether a GET on /mcp asks for long-polling instead of SSE.
+func wantsLongPoll(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, contentTypeJSON) && !strings.Contains(accept, contentTypeSSE)
+}
+
+// handlePoll serves the long-poll fallback for clients behind proxies that strip SSE.
+//
+// Clients POST requests to /mcp as usual and poll /mcp/poll (or GET /mcp with
+// Accept: application/json) for server-initiated messages. Each poll passes
+// the cursor of the previous response, acknowledging its messages. A session
+// that is not polled for twice the poll timeout is closed.
+func (t *HTTPTransport) handlePoll(ctx context.Context, srv *server.Server, w http.ResponseWriter, r *http.Request) {
+	var cursor uint64
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	mcpSession := t.requireSession(w, r)
+	if mcpSession == nil {
+		return
+	}
+	sessionID := mcpSession.ID
+	session := t.pollSession(srv, sessionID)
+
+	pollCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	timeout := t.pollTimeout()
+	resp := session.poll(pollCtx, cursor, timeout)
+	session.expiry.Reset(2 * timeout)
+
+	w.Header().Set(headerMCPSessionID, sessionID)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to encode poll response: %v", err)
+	}
+}
+
+// pollSession returns the poll session with the given ID, creating it if
+// needed. Server-initiated messages are queued for it while the session has
+// no open event stream, which takes precedence.
+func (t *HTTPTransport) pollSession(srv *server.Server, id string) *pollSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if session, ok := t.pollSessions[id]; ok {
+		session.expiry.Stop()
+		return session
+	}
+
+	session := newPollSession(id)
+	session.expiry = time.AfterFunc(2*t.pollTimeout(), func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.pollSessions, id)
+
+		session.close()
+		if !t.hasStreams(id) {
+			srv.UnregisterSession(id)
+		}
+	})
+	t.pollSessions[id] = session
+	if !t.hasStreams(id) {
+		srv.RegisterSession(id, session)
+	}
+	return session
+}
+
+// hasStreams reports whether the session has an open event stream. The
+// caller must hold t.mu.
+func (t *HTTPTransport) hasStreams(id string) bool {
+	session, ok := t.mcpSessions[id]
+	return ok && len(session.streams) > 0
+}
+
+// pollTimeout returns the long-poll timeout, capped to leave time for writing the response.
+func (t *HTTPTransport) pollTimeout() time.Duration {
+	return min(t.longPollTimeout, t.writeTimeout/2)
+}
@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// WithTrustedProxies sets the reverse proxies and load balancers in front of
+// the transport, as IPs or CIDR ranges such as "10.0.0.0/8".
+//
+// For requests from a trusted proxy, the transport derives the client from
+// the X-Forwarded-* headers instead of the proxy's connection: the client IP
+// from X-Forwarded-For, skipping trusted hops from the right, the scheme from
+// X-Forwarded-Proto and the host from X-Forwarded-Host. Session limits per
+// IP, host validation and logging then apply to the actual client. The
+// headers of requests from other addresses are ignored, since any client
+// can send them.
+func WithTrustedProxies(proxies ...string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.trustedProxies = proxies
+	}
+}
+
+// parseTrustedProxies parses IPs and CIDR ranges into prefixes.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q (must be an IP or CIDR range)", proxy)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func isTrustedProxy(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedMiddleware replaces the remote address, scheme and host of
+// requests from trusted proxies with the forwarded ones.
+func (t *HTTPTransport) forwardedMiddleware(next http.Handler) http.Handler {
+	if len(t.trustedProxies) == 0 {
+		return next
+	}
+	// Validated with the transport's options
+	prefixes, _ := parseTrustedProxies(t.trustedProxies)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, err := netip.ParseAddr(clientIP(r))
+		if err != nil || !isTrustedProxy(prefixes, peer.Unmap()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		peer = peer.Unmap()
+
+		r2 := r.Clone(r.Context())
+		if client := forwardedClient(prefixes, peer, r.Header.Values("X-Forwarded-For")); client != peer {
+			r2.RemoteAddr = net.JoinHostPort(client.String(), "0")
+		}
+		// The proxy facing the client comes first in a chain of proxies
+		if proto := strings.ToLower(firstForwarded(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			r2.URL.Scheme = proto
+		}
+		if host := firstForwarded(r.Header.Get("X-Forwarded-Host")); host != "" && httpguts.ValidHostHeader(host) {
+			r2.Host = host
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// forwardedClient returns the client IP a request from the trusted peer was
+// forwarded for: the rightmost X-Forwarded-For hop that is not a trusted
+// proxy. Hops left of it may be spoofed by the client.
+func forwardedClient(prefixes []netip.Prefix, peer netip.Addr, headers []string) netip.Addr {
+	var hops []string
+	for _, header := range headers {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0 && isTrustedProxy(prefixes, client); i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+	}
+	return client
+}
+
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
@@ -0,0 +1,139 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+)
+
+// responseSequencer writes responses in the order their requests were read.
+//
+// Every request reserves a sequence number when it is read. Its responses are
+// buffered until all earlier requests have completed, then flushed in order.
+// A request that completes without a response must still complete its slot,
+// otherwise all later responses are held back.
+type responseSequencer struct {
+	mu     sync.Mutex
+	issued uint64
+	next   uint64
+	ready  map[uint64][][]byte
+}
+
+func newResponseSequencer() *responseSequencer {
+	return &responseSequencer{ready: make(map[uint64][][]byte)}
+}
+
+// reserve returns the sequence number of the next request.
+func (q *responseSequencer) reserve() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := q.issued
+	q.issued++
+	return n
+}
+
+// complete marks a request as done and writes every response that is now in order.
+func (q *responseSequencer) complete(n uint64, lines [][]byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.ready[n] = lines
+
+	// Everything now in order is flushed as one batch
+	var batch [][]byte
+	for {
+		lines, ok := q.ready[q.next]
+		if !ok {
+			break
+		}
+		delete(q.ready, q.next)
+		q.next++
+		batch = append(batch, lines...)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return stdout.writeLines(batch...)
+}
+
+// sequencedSender answers messages in the order they were read. Errors for
+// rejected messages take a slot of their own, and every request is answered
+// through an orderedSender completing its slot once the request is handled.
+type sequencedSender struct {
+	StdoutSender
+	sequencer *responseSequencer
+}
+
+func (s *sequencedSender) SendResponse(response mcp.Response) error {
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return s.sequencer.complete(s.sequencer.reserve(), [][]byte{jsonBytes})
+}
+
+func (s *sequencedSender) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return s.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error: &mcp.ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	})
+}
+
+func (s *sequencedSender) forRequest() (mcp.ResponseSender, func()) {
+	ordered := &orderedSender{}
+	seq := s.sequencer.reserve()
+	return ordered, func() {
+		if err := s.sequencer.complete(seq, ordered.buffered()); err != nil {
+			log.Printf("Error writing response: %v", err)
+		}
+	}
+}
+
+// orderedSender buffers the responses of one request for the sequencer.
+//
+// Notifications and server-initiated requests are not responses to the
+// request and are written immediately, so progress stays timely.
+type orderedSender struct {
+	StdoutSender
+	mu    sync.Mutex
+	lines [][]byte
+}
+
+func (s *orderedSender) SendResponse(response mcp.Response) error {
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, jsonBytes)
+	return nil
+}
+
+func (s *orderedSender) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return s.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error: &mcp.ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	})
+}
+
+func (s *orderedSender) buffered() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines
+}
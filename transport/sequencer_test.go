@@ -0,0 +1,48 @@
+package transport
+
+import (
+	"bufio"
+	"os"
+	"testing"
+)
+
+func TestResponseSequencer(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	original := stdout
+	stdout = newLineWriter(writer)
+	t.Cleanup(func() { stdout = original })
+
+	q := newResponseSequencer()
+	first, second, third := q.reserve(), q.reserve(), q.reserve()
+
+	// Later requests finishing first are held back until earlier ones complete
+	if err := q.complete(third, [][]byte{[]byte("third")}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := q.complete(second, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := q.complete(first, [][]byte{[]byte("first-a"), []byte("first-b")}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_ = writer.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	want := []string{"first-a", "first-b", "third"}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Expected line %d to be %q, got %q", i, want[i], lines[i])
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/cbrgm/go-mcp-server/mcp"
@@ -15,18 +18,84 @@ import (
 
 const (
 	DefaultStdioTimeout = 30 * time.Second
+
+	// stdioSessionID identifies the single client session of the stdio transport.
+	stdioSessionID = "stdio"
 )
 
-type Stdio struct{}
+type Stdio struct {
+	requestTimeout time.Duration
+	maxMessageSize int
+	sequencer      *responseSequencer
+	wg             sync.WaitGroup
+}
+
+// stdout is shared by all senders of the stdio transport, since requests are
+// handled concurrently and every JSON-RPC message must be written as a single
+// uninterrupted line.
+var stdout = newLineWriter(os.Stdout)
+
+func writeLine(data []byte) error {
+	return stdout.writeLines(data)
+}
+
+// lineWriter serializes newline-delimited messages written by concurrent
+// goroutines. Lines are buffered and flushed once per call, so a batch of
+// lines goes out in as few writes as possible and never interleaves with
+// other messages.
+type lineWriter struct {
+	mu  sync.Mutex
+	buf *bufio.Writer
+}
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{buf: bufio.NewWriter(w)}
+}
+
+// writeLines writes each line followed by a newline and flushes them.
+func (w *lineWriter) writeLines(lines ...[]byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, line := range lines {
+		_, _ = w.buf.Write(line)
+		_ = w.buf.WriteByte('\n')
+	}
+	// A failed write sticks to the buffer, so Flush reports it
+	return w.buf.Flush()
+}
+
+// NewStdio creates a new stdio transport configured by the given options.
+//
+// Unset options fall back to defaults, see DefaultStdioTimeout.
+func NewStdio(opts ...StdioOption) (*Stdio, error) {
+	t := &Stdio{
+		requestTimeout: DefaultStdioTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
 
-func NewStdio() *Stdio {
-	return &Stdio{}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid stdio transport options: %w", err)
+	}
+
+	return t, nil
 }
 
 func (t *Stdio) Start(ctx context.Context, srv *server.Server) error {
 	log.Println("Starting stdio transport...")
 
-	scanner := bufio.NewScanner(os.Stdin)
+	srv.RegisterSession(stdioSessionID, &StdoutSender{})
+	defer srv.UnregisterSession(stdioSessionID)
+	defer srv.EndSession(stdioSessionID)
+
+	// Let in-flight requests finish writing their responses before returning
+	defer t.wg.Wait()
+
+	reader := newLineReader(os.Stdin, t.maxMessageSize)
 
 	lineChan := make(chan string)
 	errChan := make(chan error)
@@ -35,29 +104,50 @@ func (t *Stdio) Start(ctx context.Context, srv *server.Server) error {
 		defer close(lineChan)
 		defer close(errChan)
 
-		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
+		for {
+			line, err := reader.ReadLine()
+			if errors.Is(err, io.EOF) {
 				return
-			case lineChan <- scanner.Text():
 			}
-		}
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case errChan <- err:
+				}
+				// Oversized lines are discarded without ending the input
+				if !errors.Is(err, ErrMessageTooLarge) {
+					return
+				}
+				continue
+			}
 
-		if err := scanner.Err(); err != nil {
 			select {
 			case <-ctx.Done():
 				return
-			case errChan <- err:
+			case lineChan <- line:
 			}
 		}
 	}()
 
+	if err := srv.AnnounceReady(ctx, "stdio", 0); err != nil {
+		log.Printf("Failed to announce readiness: %v", err)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Stdio transport shutting down")
 			return nil
 		case err := <-errChan:
+			if errors.Is(err, ErrMessageTooLarge) {
+				log.Printf("Rejecting message larger than %d bytes", t.maxMessageSize)
+				if err := t.sender().SendError(mcp.NewIntID(-1), mcp.ErrorCodeInvalidRequest, "Request too large",
+					fmt.Sprintf("%v: limit is %d bytes", err, t.maxMessageSize)); err != nil {
+					log.Printf("Error handling message: %v", err)
+				}
+				continue
+			}
 			if err != nil {
 				log.Printf("Error reading input: %v", err)
 			}
@@ -84,54 +174,16 @@ func (t *Stdio) Stop() error {
 }
 
 func (t *Stdio) handleMessage(ctx context.Context, srv *server.Server, line string) error {
-	var req mcp.Request
-	if err := json.Unmarshal([]byte(line), &req); err != nil {
-		return t.sendParseError(line, err)
-	}
-
-	if req.JSONRPC != mcp.JSONRPCVersion {
-		log.Printf("Invalid JSON-RPC version: %s", req.JSONRPC)
-		return nil
-	}
-
-	if req.ID == nil {
-		log.Printf("Received notification: %s", req.Method)
-		return nil
-	}
-
-	reqCtx := context.WithValue(ctx, mcp.ResponseSenderKey, &StdoutSender{})
-	reqCtx, cancel := context.WithTimeout(reqCtx, DefaultStdioTimeout)
-	defer cancel()
-
-	return srv.HandleRequest(reqCtx, req)
+	return dispatchMessage(ctx, srv, stdioSessionID, t.sender(), &t.wg, t.requestTimeout, []byte(line))
 }
 
-func (t *Stdio) sendParseError(line string, err error) error {
-	errorID := any(-1)
-	var partialReq map[string]any
-	if unmarshalErr := json.Unmarshal([]byte(line), &partialReq); unmarshalErr == nil {
-		if id, exists := partialReq["id"]; exists && id != nil {
-			errorID = id
-		}
-	}
-
-	errorResp := mcp.Response{
-		JSONRPC: mcp.JSONRPCVersion,
-		ID:      errorID,
-		Error: &mcp.ErrorResponse{
-			Code:    mcp.ErrorCodeParseError,
-			Message: "Parse error",
-			Data:    err.Error(),
-		},
+// sender returns the sender answering messages read from stdin, which keeps
+// responses in request order if the transport is configured to.
+func (t *Stdio) sender() mcp.ResponseSender {
+	if t.sequencer != nil {
+		return &sequencedSender{sequencer: t.sequencer}
 	}
-
-	respBytes, marshErr := json.Marshal(errorResp)
-	if marshErr != nil {
-		return marshErr
-	}
-
-	fmt.Println(string(respBytes))
-	return nil
+	return &StdoutSender{}
 }
 
 type StdoutSender struct{}
@@ -141,11 +193,10 @@ func (s *StdoutSender) SendResponse(response mcp.Response) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
-	fmt.Println(string(jsonBytes))
-	return nil
+	return writeLine(jsonBytes)
 }
 
-func (s *StdoutSender) SendError(id any, code int, message string, data any) error {
+func (s *StdoutSender) SendError(id mcp.RequestID, code int, message string, data any) error {
 	errorResp := &mcp.ErrorResponse{
 		Code:    code,
 		Message: message,
@@ -158,3 +209,19 @@ func (s *StdoutSender) SendError(id any, code int, message string, data any) err
 	}
 	return s.SendResponse(response)
 }
+
+func (s *StdoutSender) SendNotification(notification mcp.Notification) error {
+	jsonBytes, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return writeLine(jsonBytes)
+}
+
+func (s *StdoutSender) SendRequest(request mcp.Request) error {
+	jsonBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return writeLine(jsonBytes)
+}
@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestConcurrentStdoutWrites(t *testing.T) {
+	var out bytes.Buffer
+	original := stdout
+	stdout = newLineWriter(&out)
+	t.Cleanup(func() { stdout = original })
+
+	// Messages larger than the write buffer are flushed in several writes
+	payload := strings.Repeat("x", 10_000)
+	sender := &StdoutSender{}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				var err error
+				if j%2 == 0 {
+					err = sender.SendResponse(mcp.Response{JSONRPC: mcp.JSONRPCVersion, ID: mcp.NewIntID(int64(i*10 + j)), Result: payload})
+				} else {
+					err = sender.SendNotification(mcp.Notification{JSONRPC: mcp.JSONRPCVersion, Method: "notifications/progress", Params: payload})
+				}
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := 0
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines++
+		if !json.Valid(scanner.Bytes()) {
+			t.Fatalf("Expected every line to be a complete JSON message, got %.80q", scanner.Text())
+		}
+	}
+	if lines != 200 {
+		t.Errorf("Expected 200 messages, got %d", lines)
+	}
+}
+
+func TestServerInitiatedRequests(t *testing.T) {
+	reader, writer := io.Pipe()
+	original := stdout
+	stdout = newLineWriter(writer)
+	t.Cleanup(func() { stdout = original })
+	lines := bufio.NewScanner(reader)
+	readLine := func() []byte {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("Expected a line on stdout: %v", lines.Err())
+		}
+		return lines.Bytes()
+	}
+
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewStdio()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	srv.RegisterSession(stdioSessionID, &StdoutSender{})
+
+	ctx := context.Background()
+	handle := func(line string) {
+		t.Helper()
+		if err := transport.handleMessage(ctx, srv, line); err != nil {
+			t.Fatalf("Failed to handle %s: %v", line, err)
+		}
+	}
+	handle(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	readLine()
+	handle(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	// Requests are written to stdout and the client's answers read from stdin
+	// are routed back to the waiting caller
+	sessionCtx, cancel := context.WithTimeout(context.WithValue(ctx, mcp.SessionIDKey, stdioSessionID), time.Second)
+	defer cancel()
+	tests := []struct {
+		method string
+		call   func() (any, error)
+		answer string
+	}{
+		{mcp.MethodPing, func() (any, error) { return nil, srv.Ping(sessionCtx) }, `"result":{}`},
+		{mcp.MethodElicitationCreate, func() (any, error) {
+			return srv.RequestElicitation(sessionCtx, mcp.ElicitationRequest{Prompt: "Which tea?"})
+		}, `"result":{"data":{"tea":"sencha"}}`},
+		{mcp.MethodRootsList, func() (any, error) { return srv.ListRoots(sessionCtx) }, `"error":{"code":-32601,"message":"Method not found"}`},
+	}
+	for _, tt := range tests {
+		done := make(chan error, 1)
+		var result any
+		go func() {
+			var err error
+			result, err = tt.call()
+			done <- err
+		}()
+
+		var request mcp.Request
+		if err := json.Unmarshal(readLine(), &request); err != nil || request.Method != tt.method {
+			t.Fatalf("Expected %s request on stdout, got %+v (%v)", tt.method, request, err)
+		}
+		id, _ := json.Marshal(request.ID)
+		handle(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,%s}`, id, tt.answer))
+
+		err := <-done
+		switch tt.method {
+		case mcp.MethodElicitationCreate:
+			if response, _ := result.(*mcp.ElicitationResponse); err != nil || response.Data["tea"] != "sencha" {
+				t.Errorf("Expected elicited data, got %+v (%v)", result, err)
+			}
+		case mcp.MethodRootsList:
+			var clientErr *server.ClientError
+			if !errors.As(err, &clientErr) || clientErr.Err.Code != mcp.ErrorCodeMethodNotFound {
+				t.Errorf("Expected the client's error, got %v", err)
+			}
+		default:
+			if err != nil {
+				t.Errorf("Expected %s to succeed, got %v", tt.method, err)
+			}
+		}
+	}
+
+	// Unmatched responses, even with a null ID, are not handled as notifications
+	if err := transport.handleMessage(ctx, srv, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`); err == nil {
+		t.Error("Expected error for response to an unknown request")
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	input := "short\r\n" + strings.Repeat("x", 20) + "\n\n" + strings.Repeat("y", 10) + "\nlast"
+	// A small buffer makes lines span several reads
+	reader := &lineReader{r: bufio.NewReaderSize(strings.NewReader(input), 16), max: 10}
+
+	want := []struct {
+		line string
+		err  error
+	}{
+		{"short", nil},
+		{"", ErrMessageTooLarge},
+		{"", nil},
+		{strings.Repeat("y", 10), nil},
+		{"last", nil},
+		{"", io.EOF},
+	}
+	for i, w := range want {
+		line, err := reader.ReadLine()
+		if line != w.line || !errors.Is(err, w.err) {
+			t.Errorf("Line %d: expected %q (%v), got %q (%v)", i, w.line, w.err, line, err)
+		}
+	}
+}
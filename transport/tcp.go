@@ -0,0 +1,301 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+// Default settings of the TCP transport.
+const (
+	DefaultTCPAddr           = "localhost:9090"
+	DefaultTCPReadTimeout    = 5 * time.Minute
+	DefaultTCPWriteTimeout   = 30 * time.Second
+	DefaultTCPRequestTimeout = 30 * time.Second
+)
+
+// TCP serves newline-delimited JSON-RPC over raw TCP connections.
+//
+// Every connection is its own MCP session, so a long-running daemon can
+// serve many non-HTTP clients at once. Messages are framed exactly like the
+// stdio transport: one JSON-RPC message per line.
+type TCP struct {
+	addr           string
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	requestTimeout time.Duration
+	maxMessageSize int
+
+	// network is "tcp", or "pipe" when the transport serves Windows named
+	// pipes, see Pipe. It names the transport in logs, session IDs and the
+	// readiness event.
+	network string
+	listen  func(addr string) (net.Listener, error)
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*tcpConn]struct{}
+	closed   bool
+
+	nextID atomic.Uint64
+	wg     sync.WaitGroup
+}
+
+// NewTCP creates a new TCP transport configured by the given options.
+//
+// Unset options fall back to defaults, see DefaultTCPAddr and the
+// DefaultTCP*Timeout constants.
+func NewTCP(opts ...TCPOption) (*TCP, error) {
+	t := &TCP{
+		addr:           DefaultTCPAddr,
+		readTimeout:    DefaultTCPReadTimeout,
+		writeTimeout:   DefaultTCPWriteTimeout,
+		requestTimeout: DefaultTCPRequestTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+		network:        "tcp",
+		listen:         func(addr string) (net.Listener, error) { return net.Listen("tcp", addr) },
+		conns:          make(map[*tcpConn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid TCP transport options: %w", err)
+	}
+
+	return t, nil
+}
+
+// Addr returns the address the transport listens on, or nil before Start has bound it.
+func (t *TCP) Addr() net.Addr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
+func (t *TCP) Start(ctx context.Context, srv *server.Server) error {
+	listener, err := t.listen(t.addr)
+	if err != nil {
+		return bindError(t.addr, err)
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	t.listener = listener
+	t.mu.Unlock()
+
+	log.Printf("Starting %s transport on %s...", t.name(), listener.Addr())
+
+	if err := srv.AnnounceReady(ctx, t.network, listenerPort(listener)); err != nil {
+		log.Printf("Failed to announce readiness: %v", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = t.Stop() })
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				log.Printf("Shutting down %s transport", t.name())
+				t.wg.Wait()
+				return nil
+			}
+			log.Printf("Failed to accept %s connection: %v", t.name(), err)
+			continue
+		}
+
+		c := &tcpConn{
+			conn:         conn,
+			sessionID:    fmt.Sprintf("%s_%d", t.network, t.nextID.Add(1)),
+			writeTimeout: t.writeTimeout,
+		}
+		if !t.track(c) {
+			_ = conn.Close()
+			continue
+		}
+
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			defer t.untrack(c)
+			t.serve(ctx, srv, c)
+		}()
+	}
+}
+
+// Stop closes the listener and all open connections, then waits for their
+// in-flight requests to finish.
+func (t *TCP) Stop() error {
+	t.mu.Lock()
+	t.closed = true
+	listener := t.listener
+	for c := range t.conns {
+		_ = c.conn.Close()
+	}
+	t.mu.Unlock()
+
+	if listener != nil {
+		if err := listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// name returns the transport's name for log messages.
+func (t *TCP) name() string {
+	if t.network == "pipe" {
+		return "named pipe"
+	}
+	return "TCP"
+}
+
+func (t *TCP) track(c *tcpConn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.conns[c] = struct{}{}
+	return true
+}
+
+func (t *TCP) untrack(c *tcpConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, c)
+}
+
+// serve reads messages from a connection until the client disconnects, the
+// read deadline expires or the transport stops.
+func (t *TCP) serve(ctx context.Context, srv *server.Server, c *tcpConn) {
+	defer c.conn.Close()
+
+	log.Printf("Accepted %s client %s (session %s)", t.name(), c.conn.RemoteAddr(), c.sessionID)
+
+	srv.RegisterSession(c.sessionID, c)
+	defer srv.UnregisterSession(c.sessionID)
+	defer srv.EndSession(c.sessionID)
+
+	// Requests of a closed connection have no one to respond to
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var requests sync.WaitGroup
+	defer requests.Wait()
+
+	reader := newLineReader(c.conn, t.maxMessageSize)
+
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(t.readTimeout)); err != nil {
+			log.Printf("Failed to set read deadline: %v", err)
+			return
+		}
+		line, err := reader.ReadLine()
+		if errors.Is(err, ErrMessageTooLarge) {
+			log.Printf("Rejecting message larger than %d bytes from %s client %s", t.maxMessageSize, t.name(), c.conn.RemoteAddr())
+			if err := c.SendError(mcp.NewIntID(-1), mcp.ErrorCodeInvalidRequest, "Request too large",
+				fmt.Sprintf("%v: limit is %d bytes", err, t.maxMessageSize)); err != nil {
+				log.Printf("Error handling message: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Error reading from %s client %s: %v", t.name(), c.conn.RemoteAddr(), err)
+			}
+			break
+		}
+
+		if line == "" {
+			continue
+		}
+
+		if err := dispatchMessage(connCtx, srv, c.sessionID, c, &requests, t.requestTimeout, []byte(line)); err != nil {
+			log.Printf("Error handling message: %v", err)
+		}
+	}
+
+	log.Printf("Disconnected %s client %s", t.name(), c.conn.RemoteAddr())
+}
+
+// tcpConn is a client connection of the TCP transport. It sends responses,
+// notifications and server-initiated requests as newline-delimited JSON.
+type tcpConn struct {
+	conn         net.Conn
+	sessionID    string
+	writeTimeout time.Duration
+
+	// mu serializes writes, since requests are handled concurrently and every
+	// JSON-RPC message must be written as a single uninterrupted line.
+	mu sync.Mutex
+}
+
+func (c *tcpConn) writeLine(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(append(data, '\n'))
+	return err
+}
+
+func (c *tcpConn) SendResponse(response mcp.Response) error {
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return c.writeLine(jsonBytes)
+}
+
+func (c *tcpConn) SendError(id mcp.RequestID, code int, message string, data any) error {
+	return c.SendResponse(mcp.Response{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error: &mcp.ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	})
+}
+
+func (c *tcpConn) SendNotification(notification mcp.Notification) error {
+	jsonBytes, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return c.writeLine(jsonBytes)
+}
+
+func (c *tcpConn) SendRequest(request mcp.Request) error {
+	jsonBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.writeLine(jsonBytes)
+}
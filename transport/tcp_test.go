@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbrgm/go-mcp-server/cmd/go-mcp-server/handlers"
+	"github.com/cbrgm/go-mcp-server/mcp"
+	"github.com/cbrgm/go-mcp-server/server"
+)
+
+func TestNewTCPOptions(t *testing.T) {
+	transport, err := NewTCP()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if transport.addr != DefaultTCPAddr {
+		t.Errorf("Expected default address %q, got %q", DefaultTCPAddr, transport.addr)
+	}
+
+	invalid := []TCPOption{
+		WithTCPAddr("no-port"),
+		WithTCPReadTimeout(0),
+		WithTCPWriteTimeout(-time.Second),
+		WithTCPRequestTimeout(0),
+		WithTCPMaxMessageSize(0),
+	}
+	for _, opt := range invalid {
+		if _, err := NewTCP(opt); err == nil {
+			t.Error("Expected error for invalid option, got nil")
+		}
+	}
+}
+
+func TestTCPTransport(t *testing.T) {
+	handler := &handlers.TeaHandler{}
+	srv, err := server.NewMCPServer("Test", "1.0.0", handler, handler, handler)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	transport, err := NewTCP(WithTCPAddr("127.0.0.1:0"), WithTCPMaxMessageSize(1024))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- transport.Start(ctx, srv) }()
+
+	var addr net.Addr
+	for deadline := time.Now().Add(time.Second); addr == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Transport did not start listening")
+		}
+		time.Sleep(time.Millisecond)
+		addr = transport.Addr()
+	}
+
+	type client struct {
+		conn    net.Conn
+		scanner *bufio.Scanner
+	}
+	call := func(c client, line string) mcp.Response {
+		t.Helper()
+		if _, err := c.conn.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+		if !c.scanner.Scan() {
+			t.Fatalf("Failed to read response: %v", c.scanner.Err())
+		}
+		var resp mcp.Response
+		if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// Each connection is a separate session
+	clients := make([]client, 2)
+	for i := range clients {
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		clients[i] = client{conn: conn, scanner: bufio.NewScanner(conn)}
+	}
+	for i, c := range clients {
+		resp := call(c, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+		if resp.Error != nil {
+			t.Fatalf("Client %d: expected successful initialize, got %+v", i, resp.Error)
+		}
+	}
+	for i, c := range clients {
+		resp := call(c, `{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+		if resp.Error != nil || resp.ID != mcp.NewStringID("ping") {
+			t.Errorf("Client %d: expected ping response, got %+v", i, resp)
+		}
+	}
+
+	// Oversized messages are rejected without dropping the connection
+	resp := call(clients[0], `{"jsonrpc":"2.0","id":2,"method":"ping","params":{"padding":"`+strings.Repeat("x", 2048)+`"}}`)
+	if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("Expected request too large error, got %+v", resp)
+	}
+	resp = call(clients[0], `{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+	if resp.Error != nil {
+		t.Errorf("Expected ping response after oversized message, got %+v", resp)
+	}
+
+	resp = call(clients[0], `{"jsonrpc":"2.0","id":2,`)
+	if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("Expected parse error, got %+v", resp)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Transport did not shut down")
+	}
+
+	// Stop closes open connections
+	_ = clients[1].conn.SetReadDeadline(time.Now().Add(time.Second))
+	if clients[1].scanner.Scan() {
+		t.Errorf("Expected connection to be closed, read %q", clients[1].scanner.Text())
+	}
+}
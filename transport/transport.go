@@ -4,6 +4,10 @@
 // for different transport mechanisms supported by the MCP specification:
 //   - Stdio transport for process-based communication
 //   - HTTP transport for network-based communication
+//   - TCP transport for newline-delimited JSON-RPC over raw connections
+//   - Named pipe transport for the same over Windows named pipes
+//   - gRPC transport carrying JSON-RPC messages in a bidirectional stream
+//   - In-process transport for embedding and testing over channels
 //
 // All transports use JSON-RPC 2.0 for message exchange and support the
 // full MCP protocol including initialization, requests, and responses.
@@ -20,6 +24,15 @@ import (
 // Transport implementations handle the low-level communication details
 // while delegating MCP protocol logic to the server. Each transport
 // is responsible for message framing, encoding/decoding, and error handling.
+//
+// Messages flow in both directions. Inbound messages are passed to the
+// server's HandleRequest, HandleNotification and HandleResponse, with the
+// session's ID as mcp.SessionIDKey in the context. For outbound messages,
+// transports register every client session with the server's
+// RegisterSession, passing the session's mcp.SessionSender, and unregister
+// it when the session ends. The server pushes notifications such as list_changed and
+// requests such as sampling and elicitation to a session through that
+// sender, see server.Server.NotifySession and server.Server.RequestSampling.
 type Transport interface {
 	// Start begins listening for requests on this transport.
 	// It blocks until the context is canceled or an error occurs.